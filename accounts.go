@@ -0,0 +1,255 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	sessionCookie = "session"
+	sessionMaxAge = 30 * 24 * time.Hour
+
+	minPasswordLength = 8
+	// bcrypt ignores anything past 72 bytes
+	maxPasswordLength = 72
+)
+
+// Whether visitors can register and log in, set from ACCOUNTS_ENABLED
+var accountsEnabled = false
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,30}$`)
+
+var (
+	errBadUsername   = errors.New("invalid username")
+	errEmptyPassword = errors.New("password is empty")
+	errWeakPassword  = errors.New("password is too weak")
+)
+
+// Compared against when the username is unknown, so a failed login takes
+// as long whether or not the account exists
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+
+// Load the logged-in user from the session cookie, if there is one
+func loadSession(c *gin.Context) {
+	token, err := c.Cookie(sessionCookie)
+	if err != nil || token == "" {
+		c.Next()
+		return
+	}
+
+	user, err := store.GetSessionUser(c.Request.Context(), hashToken(token))
+	switch {
+	case errors.Is(err, database.ErrSessionNotFound):
+		c.SetCookie(sessionCookie, "", -1, "/", "", false, true)
+	case err != nil:
+		log.Println("Error loading session:", err)
+	default:
+		c.Set("user", &user)
+	}
+	c.Next()
+}
+
+// The logged-in user, or nil for anonymous visitors
+func currentUser(c *gin.Context) *database.User {
+	user, _ := c.Get("user")
+	u, _ := user.(*database.User)
+	return u
+}
+
+// Show the registration form
+func showRegister(c *gin.Context) {
+	c.HTML(http.StatusOK, "account.html", registerPage(c, ""))
+}
+
+// Show the login form
+func showLogin(c *gin.Context) {
+	c.HTML(http.StatusOK, "account.html", loginPage(c, ""))
+}
+
+func registerPage(c *gin.Context, message string) gin.H {
+	return gin.H{
+		"Title":      "Create an account",
+		"Action":     "/register",
+		"Submit":     "Register",
+		"SwitchURL":  "/login",
+		"SwitchText": "Already have an account? Log in",
+		"Username":   c.PostForm("username"),
+		"Next":       redirectTarget(c),
+		"Error":      message,
+		"Google":     googleOAuth != nil,
+	}
+}
+
+func loginPage(c *gin.Context, message string) gin.H {
+	return gin.H{
+		"Title":      "Log in",
+		"Action":     "/login",
+		"Submit":     "Log in",
+		"SwitchURL":  "/register",
+		"SwitchText": "No account yet? Register",
+		"Username":   c.PostForm("username"),
+		"Next":       redirectTarget(c),
+		"Error":      message,
+		"Google":     googleOAuth != nil,
+	}
+}
+
+// Create an account and log straight into it
+func register(c *gin.Context) {
+	username := strings.TrimSpace(c.PostForm("username"))
+	password := c.PostForm("password")
+
+	if err := validateCredentials(username, password); err != nil {
+		c.HTML(http.StatusBadRequest, "account.html", registerPage(c, credentialsMessage(err)))
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Println("Error hashing password:", err)
+		c.String(http.StatusInternalServerError, "Failed to create account.")
+		return
+	}
+
+	id, err := store.CreateUser(c.Request.Context(), username, string(hash))
+	if errors.Is(err, database.ErrUsernameTaken) {
+		c.HTML(http.StatusConflict, "account.html", registerPage(c, "That username is already taken."))
+		return
+	}
+	if err != nil {
+		log.Println("Error creating user:", err)
+		c.String(http.StatusInternalServerError, "Failed to create account.")
+		return
+	}
+
+	if err := startSession(c, id); err != nil {
+		log.Println("Error starting session:", err)
+		c.String(http.StatusInternalServerError, "Failed to log in.")
+		return
+	}
+	c.Redirect(http.StatusSeeOther, redirectTarget(c))
+}
+
+// Check the username and password and start a session
+func login(c *gin.Context) {
+	username := strings.TrimSpace(c.PostForm("username"))
+	password := c.PostForm("password")
+	if username == "" || password == "" {
+		c.HTML(http.StatusBadRequest, "account.html", loginPage(c, "Enter your username and password."))
+		return
+	}
+
+	user, err := store.GetUserByUsername(c.Request.Context(), username)
+	if err != nil && !errors.Is(err, database.ErrUserNotFound) {
+		log.Println("Error loading user:", err)
+		c.String(http.StatusInternalServerError, "Failed to log in.")
+		return
+	}
+
+	hash := []byte(user.PasswordHash)
+	if errors.Is(err, database.ErrUserNotFound) {
+		hash = dummyPasswordHash
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil || errors.Is(err, database.ErrUserNotFound) {
+		c.HTML(http.StatusUnauthorized, "account.html", loginPage(c, "Incorrect username or password."))
+		return
+	}
+
+	if err := startSession(c, user.ID); err != nil {
+		log.Println("Error starting session:", err)
+		c.String(http.StatusInternalServerError, "Failed to log in.")
+		return
+	}
+	c.Redirect(http.StatusSeeOther, redirectTarget(c))
+}
+
+// End the session on the server as well as in the browser
+func logout(c *gin.Context) {
+	if token, err := c.Cookie(sessionCookie); err == nil && token != "" {
+		if err := store.DeleteSession(c.Request.Context(), hashToken(token)); err != nil {
+			log.Println("Error deleting session:", err)
+			c.String(http.StatusInternalServerError, "Failed to log out.")
+			return
+		}
+	}
+	c.SetCookie(sessionCookie, "", -1, "/", "", false, true)
+	c.Redirect(http.StatusSeeOther, redirectTarget(c))
+}
+
+// Store a new session for the user and hand its token to the browser
+func startSession(c *gin.Context, userID int64) error {
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	if err := store.CreateSession(c.Request.Context(), hashToken(token), userID, time.Now().Add(sessionMaxAge)); err != nil {
+		return err
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookie, token, int(sessionMaxAge.Seconds()), "/", "", false, true)
+	return nil
+}
+
+func validateCredentials(username, password string) error {
+	if !usernamePattern.MatchString(username) {
+		return errBadUsername
+	}
+	if password == "" {
+		return errEmptyPassword
+	}
+	if utf8.RuneCountInString(password) < minPasswordLength || len(password) > maxPasswordLength ||
+		strings.EqualFold(password, username) {
+		return errWeakPassword
+	}
+	return nil
+}
+
+// User-facing explanation of why validateCredentials rejected a registration
+func credentialsMessage(err error) string {
+	switch {
+	case errors.Is(err, errEmptyPassword):
+		return "Choose a password."
+	case errors.Is(err, errWeakPassword):
+		return "Passwords need 8 to 72 characters and must differ from the username."
+	default:
+		return "Usernames are 3 to 30 letters, digits, dashes or underscores."
+	}
+}
+
+// Local path to go back to after logging in or out, from the "next"
+// parameter. Anything that could lead off-site goes to the home page.
+func redirectTarget(c *gin.Context) string {
+	next := c.DefaultPostForm("next", c.Query("next"))
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+// Generate a random hex token, e.g. for a new session
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Only a hash of the token is stored so a database leak can't be used to log in
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	moderationPageSize = 50
+
+	// Most comments one bulk moderation request can act on
+	maxBulkComments = 200
+
+	// Number of videos listed in the dashboard's most-commented widget
+	dashboardTopVideos = 10
+)
+
+// With accounts enabled only users flagged as admins get through. Otherwise
+// HTTP basic auth with the admin token is required, and without one
+// configured every request is refused.
+func requireAdmin(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if accountsEnabled {
+			if !isAdmin(c) {
+				c.String(http.StatusForbidden, "Only admins can see this page.")
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		_, given, ok := c.Request.BasicAuth()
+		if token == "" || !ok {
+			c.Header("WWW-Authenticate", `Basic realm="Right To Comment admin"`)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.String(http.StatusForbidden, "Only admins can see this page.")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Whether the logged-in user is an admin. Basic auth admins aren't known
+// outside the /admin pages.
+func isAdmin(c *gin.Context) bool {
+	user := currentUser(c)
+	return user != nil && user.IsAdmin
+}
+
+// Start an audit log entry for the admin making the request, with the
+// optional "note" form field
+func adminAudit(c *gin.Context) database.AuditEntry {
+	return database.AuditEntry{Actor: adminName(c), Note: c.PostForm("note")}
+}
+
+// Who to name in the audit log: the username with accounts enabled, or the
+// basic auth username otherwise
+func adminName(c *gin.Context) string {
+	if user := currentUser(c); user != nil {
+		return user.Username
+	}
+	if name, _, ok := c.Request.BasicAuth(); ok && name != "" {
+		return name
+	}
+	return "admin"
+}
+
+// A dashboard figure, or the reason it couldn't be loaded
+type dashboardWidget struct {
+	Value any
+	Err   error
+}
+
+func newWidget(value any, err error) dashboardWidget {
+	if err != nil {
+		log.Println("Error loading dashboard stats:", err)
+	}
+	return dashboardWidget{Value: value, Err: err}
+}
+
+// Show comment activity at a glance. Each figure is loaded on its own so one
+// failing query only blanks its own widget.
+func showDashboard(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	total, err := store.CountComments(ctx)
+	totalWidget := newWidget(total, err)
+	recent, err := store.CountCommentsSince(ctx, time.Now().Add(-24*time.Hour))
+	recentWidget := newWidget(recent, err)
+	videos, err := store.MostCommentedVideos(ctx, dashboardTopVideos)
+	videosWidget := newWidget(videos, err)
+	pending, err := store.CountPendingReports(ctx)
+	pendingWidget := newWidget(pending, err)
+	langs, err := store.CountCommentsByLang(ctx)
+	langsWidget := newWidget(langs, err)
+
+	c.HTML(http.StatusOK, "dashboard.html", gin.H{
+		"Total":   totalWidget,
+		"Recent":  recentWidget,
+		"Videos":  videosWidget,
+		"Pending": pendingWidget,
+		"Langs":   langsWidget,
+	})
+}
+
+// List hidden and reported comments waiting for review
+func showModeration(c *gin.Context) {
+	renderModeration(c, nil)
+}
+
+// List the other comments posted from the IP address with the hash in the URL
+func showIPComments(c *gin.Context) {
+	hash := c.Param("hash")
+	comments, err := store.CommentsByIPHash(c.Request.Context(), hash, moderationPageSize)
+	if err != nil {
+		log.Println("Error loading comments by IP hash:", err)
+		c.String(http.StatusInternalServerError, "Failed to load comments.")
+		return
+	}
+	c.HTML(http.StatusOK, "ip_comments.html", gin.H{
+		"IPHash":   hash,
+		"Comments": comments,
+	})
+}
+
+// Outcome of a bulk action on one comment, shown above the queue
+type bulkResultView struct {
+	ID     int64  `json:"id"`
+	OK     bool   `json:"ok"`
+	Result string `json:"result"`
+}
+
+func renderModeration(c *gin.Context, results []bulkResultView) {
+	cursor := c.Query("cursor")
+	page, err := store.ListReportedComments(c.Request.Context(), moderationPageSize, cursor)
+	if err != nil {
+		log.Println("Error loading reported comments:", err)
+		c.String(http.StatusInternalServerError, "Failed to load moderation queue.")
+		return
+	}
+
+	c.HTML(http.StatusOK, "moderation.html", gin.H{
+		"Comments":   page.Items,
+		"Paged":      cursor != "",
+		"NextCursor": page.NextCursor,
+		"HasMore":    page.HasMore,
+		"Results":    results,
+	})
+}
+
+// Apply the "action" form field to every comment in the "ids" field at once
+func bulkModerate(c *gin.Context) {
+	raw := c.PostFormArray("ids")
+	if len(raw) == 1 {
+		raw = strings.FieldsFunc(raw[0], func(r rune) bool { return r == ',' || r == ' ' })
+	}
+	if len(raw) == 0 {
+		c.String(http.StatusBadRequest, "Select at least one comment.")
+		return
+	}
+	if len(raw) > maxBulkComments {
+		c.String(http.StatusBadRequest, fmt.Sprintf("At most %d comments can be moderated at once.", maxBulkComments))
+		return
+	}
+	ids := make([]int64, 0, len(raw))
+	for _, s := range raw {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid comment ID: "+s)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	ban := database.Ban{
+		Reason:       strings.TrimSpace(c.PostForm("reason")),
+		Shadowbanned: c.PostForm("shadowban") != "",
+	}
+	entry := adminAudit(c)
+	if entry.Note == "" {
+		entry.Note = ban.Reason
+	}
+	results, err := store.BulkModerate(c.Request.Context(), c.PostForm("action"), ids, ban, entry)
+	if errors.Is(err, database.ErrUnknownBulkAction) {
+		c.String(http.StatusBadRequest, "Unknown action, use delete, approve or ban-author.")
+		return
+	}
+	if err != nil {
+		log.Println("Error applying bulk moderation:", err)
+		c.String(http.StatusInternalServerError, "Failed to moderate comments, none were changed.")
+		return
+	}
+
+	views := make([]bulkResultView, 0, len(results))
+	for _, result := range results {
+		view := bulkResultView{ID: result.ID, OK: result.Err == nil, Result: "done"}
+		switch {
+		case errors.Is(result.Err, database.ErrNotFound):
+			view.Result = "not found, it may already be deleted"
+		case result.Err != nil:
+			view.Result = result.Err.Error()
+		}
+		views = append(views, view)
+	}
+	if strings.Contains(c.GetHeader("Accept"), "application/json") {
+		c.JSON(http.StatusOK, gin.H{"results": views})
+		return
+	}
+	renderModeration(c, views)
+}
+
+// Clear the hidden flag and reports on a comment
+func approveComment(c *gin.Context) {
+	moderateComment(c, store.ApproveComment)
+}
+
+// Remove a comment and its thread for good
+func adminDeleteComment(c *gin.Context) {
+	moderateComment(c, store.HardDeleteComment)
+}
+
+// Apply a moderation action to the comment in the URL and return to the queue
+func moderateComment(c *gin.Context, action func(ctx context.Context, id int64, audit database.AuditEntry) error) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusNotFound, "Comment not found.")
+		return
+	}
+
+	err = action(c.Request.Context(), id, adminAudit(c))
+	if errors.Is(err, database.ErrNotFound) {
+		c.String(http.StatusNotFound, "Comment not found.")
+		return
+	}
+	if err != nil {
+		log.Println("Error moderating comment:", err)
+		c.String(http.StatusInternalServerError, "Failed to moderate comment.")
+		return
+	}
+
+	c.Redirect(http.StatusSeeOther, "/admin/moderation")
+}
+
+// List the earlier versions of a comment, oldest first
+func showHistory(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusNotFound, "Comment not found.")
+		return
+	}
+
+	ctx := c.Request.Context()
+	comment, err := store.GetComment(ctx, id)
+	if errors.Is(err, database.ErrNotFound) {
+		c.String(http.StatusNotFound, "Comment not found.")
+		return
+	}
+	if err != nil {
+		log.Println("Error loading comment:", err)
+		c.String(http.StatusInternalServerError, "Failed to load comment history.")
+		return
+	}
+
+	revisions, err := store.ListRevisions(ctx, id)
+	if err != nil {
+		log.Println("Error loading revisions:", err)
+		c.String(http.StatusInternalServerError, "Failed to load comment history.")
+		return
+	}
+
+	c.HTML(http.StatusOK, "history.html", gin.H{
+		"Comment":   comment,
+		"Revisions": revisions,
+	})
+}
+
+// Rebuild the comment search index, in case it drifted from the comments
+func reindexSearch(c *gin.Context) {
+	if err := store.RebuildSearchIndex(c.Request.Context()); err != nil {
+		log.Println("Error rebuilding search index:", err)
+		c.String(http.StatusInternalServerError, "Failed to rebuild search index.")
+		return
+	}
+	audit(c, "reindex-search", "site", "search", c.PostForm("note"))
+	c.String(http.StatusOK, "Search index rebuilt.")
+}
+
+// Re-read the word filter list so changes apply without a restart
+func reloadWordFilter(c *gin.Context) {
+	if err := wordFilter.Reload(); err != nil {
+		log.Println("Error reloading word filter:", err)
+		c.String(http.StatusInternalServerError, "Failed to reload word filter.")
+		return
+	}
+	audit(c, "reload-word-filter", "site", "word-filter", c.PostForm("note"))
+	c.String(http.StatusOK, "Word filter reloaded.")
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Number of entries on each page of /admin/audit
+const auditPageSize = 50
+
+// Record an admin action that has nothing in the database to share a
+// transaction with. The action already happened, so a failure is only logged.
+func audit(c *gin.Context, action, targetType, targetID, note string) {
+	if err := store.Audit(c.Request.Context(), adminName(c), action, targetType, targetID, note); err != nil {
+		log.Println("Error writing audit log:", err)
+	}
+}
+
+// List recorded admin actions, optionally only one action or target
+func showAudit(c *gin.Context) {
+	cursor := c.Query("cursor")
+	action := strings.TrimSpace(c.Query("action"))
+	target := strings.TrimSpace(c.Query("target"))
+
+	ctx := c.Request.Context()
+	page, err := store.ListAuditLog(ctx, action, target, auditPageSize, cursor)
+	if err != nil {
+		log.Println("Error loading audit log:", err)
+		c.String(http.StatusInternalServerError, "Failed to load audit log.")
+		return
+	}
+	actions, err := store.AuditActions(ctx)
+	if err != nil {
+		log.Println("Error loading audit actions:", err)
+		c.String(http.StatusInternalServerError, "Failed to load audit log.")
+		return
+	}
+
+	c.HTML(http.StatusOK, "audit.html", gin.H{
+		"Entries":    page.Items,
+		"Actions":    actions,
+		"Action":     action,
+		"Target":     target,
+		"Paged":      cursor != "",
+		"NextCursor": page.NextCursor,
+		"HasMore":    page.HasMore,
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+	"github.com/TanishkBansode/right-to-comment/identicon"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Width and height of avatars in pixels
+const avatarSize = 64
+
+// Avatar keys are hex digests
+var avatarKey = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// Path of the avatar for a comment's author. The key is derived from the
+// author's identity hash with the secret key, so avatars stay the same
+// across comments without revealing the hash itself.
+func avatarURL(comment database.Comment) string {
+	source := comment.AuthorHash
+	if source == "" {
+		// Comments from before identities were stored each get their own
+		source = "comment:" + strconv.FormatInt(comment.ID, 10)
+	}
+	mac := hmac.New(sha256.New, secretKey)
+	mac.Write([]byte("avatar:" + source))
+	return "/avatar/" + hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// Serve the identicon for an avatar key
+func serveAvatar(c *gin.Context) {
+	key := c.Param("hash")
+	if !avatarKey.MatchString(key) {
+		c.String(http.StatusNotFound, "Avatar not found.")
+		return
+	}
+
+	// The image for a key never changes
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("Content-Type", "image/png")
+	if err := identicon.WritePNG(c.Writer, key, avatarSize); err != nil {
+		log.Println("Error drawing avatar:", err)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"testing"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+)
+
+func TestAvatarURLStablePerAuthor(t *testing.T) {
+	first := avatarURL(database.Comment{ID: 1, AuthorHash: "alice"})
+	if again := avatarURL(database.Comment{ID: 2, AuthorHash: "alice"}); again != first {
+		t.Errorf("same author got %q and %q", first, again)
+	}
+	if other := avatarURL(database.Comment{ID: 1, AuthorHash: "bob"}); other == first {
+		t.Error("different authors got the same avatar")
+	}
+	if avatarURL(database.Comment{ID: 1}) == avatarURL(database.Comment{ID: 2}) {
+		t.Error("comments without an author share an avatar")
+	}
+	if !avatarKey.MatchString(first[len("/avatar/"):]) {
+		t.Errorf("%q isn't a valid avatar path", first)
+	}
+}
+
+func TestServeAvatar(t *testing.T) {
+	router := testRouter(http.MethodGet, "/avatar/:hash", serveAvatar)
+	if w := serve(router, requestAs(http.MethodGet, "/avatar/not-a-key", "")); w.Code != http.StatusNotFound {
+		t.Errorf("bad key: status = %d, want 404", w.Code)
+	}
+
+	w := serve(router, requestAs(http.MethodGet, avatarURL(database.Comment{AuthorHash: "alice"}), ""))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=31536000, immutable" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	img, err := png.Decode(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := img.Bounds().Size(); size.X != avatarSize || size.Y != avatarSize {
+		t.Errorf("avatar is %v, want %dpx square", size, avatarSize)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+const backupPrefix, backupSuffix = "comments-", ".db"
+
+// UTC time a snapshot was taken, between the prefix and suffix of its name
+const backupTimeFormat = "20060102-150405.000"
+
+var (
+	// Where snapshots are written, set by BACKUP_DIR
+	backupDir = "./backups"
+	// Snapshots kept, the oldest beyond this are deleted after each backup
+	backupsKept = 7
+)
+
+var errBackupRunning = errors.New("a backup or database maintenance is already running")
+
+// Held while a snapshot is being written or the database optimized, which
+// would only slow each other down
+var databaseJobMu sync.Mutex
+
+// Snapshot the database, answering with the path and size of the file
+func createBackup(c *gin.Context) {
+	path, size, err := takeBackup(c.Request.Context())
+	if errors.Is(err, errBackupRunning) {
+		c.String(http.StatusConflict, "A backup or database maintenance is already running, try again shortly.")
+		return
+	}
+	if errors.Is(err, database.ErrBackupUnsupported) {
+		c.String(http.StatusNotImplemented, "Backups of Postgres databases are taken with pg_dump.")
+		return
+	}
+	if err != nil {
+		log.Println("Error backing up database:", err)
+		c.String(http.StatusInternalServerError, "Failed to back up the database.")
+		return
+	}
+	audit(c, "backup", "site", filepath.Base(path), c.PostForm("note"))
+	c.JSON(http.StatusOK, gin.H{"path": path, "size": size})
+}
+
+// Write a timestamped snapshot to backupDir and prune old ones. The file only
+// gets its final name once complete, so a failed backup never looks like
+// one worth restoring.
+func takeBackup(ctx context.Context) (string, int64, error) {
+	if !databaseJobMu.TryLock() {
+		return "", 0, errBackupRunning
+	}
+	defer databaseJobMu.Unlock()
+
+	if err := os.MkdirAll(backupDir, 0o700); err != nil {
+		return "", 0, err
+	}
+	path := filepath.Join(backupDir, backupPrefix+time.Now().UTC().Format(backupTimeFormat)+backupSuffix)
+	if _, err := os.Stat(path); err == nil {
+		return "", 0, fmt.Errorf("%s already exists", path)
+	}
+	partial := path + ".partial"
+	os.Remove(partial)
+	if err := store.Backup(ctx, partial); err != nil {
+		os.Remove(partial)
+		return "", 0, err
+	}
+	if err := os.Rename(partial, path); err != nil {
+		return "", 0, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := pruneBackups(); err != nil {
+		log.Println("Error pruning old backups:", err)
+	}
+	return path, info.Size(), nil
+}
+
+// Names of the snapshots in backupDir, oldest first as they sort by the
+// time they were taken
+func backupNames() ([]string, error) {
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if name := entry.Name(); strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, backupSuffix) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// When the newest snapshot was taken, false when there is none
+func latestBackup() (time.Time, bool) {
+	names, err := backupNames()
+	if err != nil || len(names) == 0 {
+		return time.Time{}, false
+	}
+	stamp := strings.TrimSuffix(strings.TrimPrefix(names[len(names)-1], backupPrefix), backupSuffix)
+	taken, err := time.Parse(backupTimeFormat, stamp)
+	return taken, err == nil
+}
+
+// Delete all but the newest backupsKept snapshots
+func pruneBackups() error {
+	names, err := backupNames()
+	if err != nil {
+		return err
+	}
+	for len(names) > backupsKept {
+		if err := os.Remove(filepath.Join(backupDir, names[0])); err != nil {
+			return err
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+// Take a snapshot every interval until stop is called, which waits for one
+// in progress
+func startBackups(interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if path, size, err := takeBackup(ctx); err != nil {
+					log.Println("Error taking scheduled backup:", err)
+				} else {
+					log.Printf("Backed up database to %s (%d bytes)", path, size)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-stopped
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+)
+
+// Write backups to a directory of the test's own
+func useBackupDir(t *testing.T) string {
+	t.Helper()
+	previous := backupDir
+	backupDir = t.TempDir()
+	t.Cleanup(func() { backupDir = previous })
+	return backupDir
+}
+
+func TestCreateBackup(t *testing.T) {
+	useTestStore(t)
+	dir := useBackupDir(t)
+	for _, text := range []string{"Keep me", "Me too"} {
+		postTestComment(t, "author", database.NewComment{Text: text})
+	}
+
+	router := testRouter(http.MethodPost, "/admin/backup", createBackup)
+	w := serve(router, requestAs(http.MethodPost, "/admin/backup", ""))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var created struct {
+		Path string
+		Size int64
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(created.Path) != dir {
+		t.Errorf("backup written to %s, want %s", created.Path, dir)
+	}
+	if info, err := os.Stat(created.Path); err != nil || info.Size() != created.Size {
+		t.Errorf("answered size %d for %s, stat says %v, %v", created.Size, created.Path, info, err)
+	}
+
+	backup, err := database.Open(created.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+	if n, err := backup.CountComments(context.Background()); err != nil || n != 2 {
+		t.Errorf("backup has %d comments, %v; want 2", n, err)
+	}
+}
+
+func TestBackupAlreadyRunning(t *testing.T) {
+	useTestStore(t)
+	useBackupDir(t)
+	databaseJobMu.Lock()
+	defer databaseJobMu.Unlock()
+
+	router := testRouter(http.MethodPost, "/admin/backup", createBackup)
+	if w := serve(router, requestAs(http.MethodPost, "/admin/backup", "")); w.Code != http.StatusConflict {
+		t.Errorf("status = %d, want 409", w.Code)
+	}
+}
+
+func TestPruneBackups(t *testing.T) {
+	dir := useBackupDir(t)
+	previous := backupsKept
+	backupsKept = 2
+	t.Cleanup(func() { backupsKept = previous })
+
+	names := []string{
+		"comments-20240101-000000.000.db",
+		"comments-20240102-000000.000.db",
+		"comments-20240103-000000.000.db",
+		// Not a snapshot, so left alone
+		"notes.txt",
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pruneBackups(); err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range names {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if kept := err == nil; kept != (i > 0) {
+			t.Errorf("%s kept = %v", name, kept)
+		}
+	}
+	if taken, ok := latestBackup(); !ok || taken.Day() != 3 {
+		t.Errorf("latest backup = %v, %v; want the 3rd", taken, ok)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+	"github.com/TanishkBansode/right-to-comment/identity"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Refuse requests from banned identities or IP addresses
+func rejectBanned(c *gin.Context) {
+	banned, err := store.IsBanned(c.Request.Context(), identity.Hash(identity.FromContext(c)), ipHash(c))
+	if err != nil {
+		log.Println("Error checking bans:", err)
+		c.String(http.StatusInternalServerError, "Something went wrong, please try again.")
+		c.Abort()
+		return
+	}
+	if banned {
+		c.String(http.StatusForbidden, "You have been banned from commenting.")
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// Comments and bans keep IP addresses as keyed hashes, never as they are
+func ipHash(c *gin.Context) string {
+	return identity.HashIP(secretKey, clientIP(c))
+}
+
+// List the bans currently in force
+func showBans(c *gin.Context) {
+	bans, err := store.ListBans(c.Request.Context())
+	if err != nil {
+		log.Println("Error loading bans:", err)
+		c.String(http.StatusInternalServerError, "Failed to load bans.")
+		return
+	}
+	c.HTML(http.StatusOK, "bans.html", gin.H{"Bans": bans})
+}
+
+// Ban an identity hash, IP address or IP hash given in the form
+func addBan(c *gin.Context) {
+	ban := database.Ban{
+		IdentityHash: strings.TrimSpace(c.PostForm("identity_hash")),
+		IPHash:       strings.TrimSpace(c.PostForm("ip_hash")),
+		Reason:       strings.TrimSpace(c.PostForm("reason")),
+		Shadowbanned: c.PostForm("shadowban") != "",
+	}
+	if ip := strings.TrimSpace(c.PostForm("ip")); ip != "" {
+		ban.IPHash = identity.HashIP(secretKey, ip)
+	}
+	if ban.IdentityHash == "" && ban.IPHash == "" {
+		c.String(http.StatusBadRequest, "Give an identity hash or an IP address to ban.")
+		return
+	}
+	saveBan(c, ban, adminAudit(c))
+}
+
+// Ban or, with the "shadowban" form field, shadowban whoever posted the
+// comment in the URL
+func banCommentAuthor(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusNotFound, "Comment not found.")
+		return
+	}
+
+	comment, err := store.GetComment(c.Request.Context(), id)
+	if errors.Is(err, database.ErrNotFound) {
+		c.String(http.StatusNotFound, "Comment not found.")
+		return
+	}
+	if err != nil {
+		log.Println("Error loading comment:", err)
+		c.String(http.StatusInternalServerError, "Failed to ban author.")
+		return
+	}
+	if comment.AuthorHash == "" {
+		c.String(http.StatusConflict, "This comment predates author tracking, so its author can't be banned.")
+		return
+	}
+
+	entry := adminAudit(c)
+	entry.TargetType, entry.TargetID = "comment", strconv.FormatInt(id, 10)
+	saveBan(c, database.Ban{
+		IdentityHash: comment.AuthorHash,
+		Reason:       strings.TrimSpace(c.PostForm("reason")),
+		Shadowbanned: c.PostForm("shadowban") != "",
+	}, entry)
+}
+
+// Store a ban, expiring after the optional "duration" form field such as "72h"
+func saveBan(c *gin.Context, ban database.Ban, audit database.AuditEntry) {
+	if duration := c.PostForm("duration"); duration != "" {
+		d, err := time.ParseDuration(duration)
+		if err != nil || d <= 0 {
+			c.String(http.StatusBadRequest, "Invalid ban duration, use a value like 24h.")
+			return
+		}
+		expires := time.Now().Add(d)
+		ban.ExpiresAt = &expires
+	}
+
+	if audit.Note == "" {
+		audit.Note = ban.Reason
+	}
+	if _, err := store.AddBan(c.Request.Context(), ban, audit); err != nil {
+		log.Println("Error adding ban:", err)
+		c.String(http.StatusInternalServerError, "Failed to add ban.")
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/bans")
+}
+
+// Lift a ban
+func removeBan(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusNotFound, "Ban not found.")
+		return
+	}
+
+	err = store.RemoveBan(c.Request.Context(), id, adminAudit(c))
+	if errors.Is(err, database.ErrBanNotFound) {
+		c.String(http.StatusNotFound, "Ban not found.")
+		return
+	}
+	if err != nil {
+		log.Println("Error removing ban:", err)
+		c.String(http.StatusInternalServerError, "Failed to remove ban.")
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/admin/bans")
+}
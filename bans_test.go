@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+	"github.com/TanishkBansode/right-to-comment/identity"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRejectBanned(t *testing.T) {
+	useTestStore(t)
+	router := testRouter(http.MethodPost, "/video/:id/comments", identity.Middleware(secretKey), rejectBanned, func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+	if _, err := store.AddBan(context.Background(), database.Ban{IdentityHash: identity.Hash("troll")}, database.AuditEntry{Actor: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := serve(router, requestAs(http.MethodPost, "/video/dQw4w9WgXcQ/comments", "troll")); w.Code != http.StatusForbidden {
+		t.Errorf("banned visitor: status = %d, want 403", w.Code)
+	}
+	if w := serve(router, requestAs(http.MethodPost, "/video/dQw4w9WgXcQ/comments", "someone")); w.Code != http.StatusCreated {
+		t.Errorf("other visitor: status = %d, want 201", w.Code)
+	}
+}
+
+func TestRejectBannedByIP(t *testing.T) {
+	useTestStore(t)
+	router := testRouter(http.MethodPost, "/comments/:id/upvote", identity.Middleware(secretKey), rejectBanned, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	if _, err := store.AddBan(context.Background(), database.Ban{IPHash: identity.HashIP(secretKey, "192.0.2.9")}, database.AuditEntry{Actor: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh identity doesn't get around an IP ban
+	r := requestAs(http.MethodPost, "/comments/1/upvote", "")
+	r.RemoteAddr = "192.0.2.9:1234"
+	if w := serve(router, r); w.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", w.Code)
+	}
+}
+
+func TestCommentIPHash(t *testing.T) {
+	useTestStore(t)
+	router := testRouter(http.MethodPost, "/video/:id/comments", identity.Middleware(secretKey), addComment)
+	if err := router.SetTrustedProxies([]string{"10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	post := func(visitor, text string) {
+		t.Helper()
+		r := formAs("/video/dQw4w9WgXcQ/comments", visitor, commentForm(text, time.Minute))
+		r.RemoteAddr = "10.0.0.1:1234"
+		r.Header.Set("X-Forwarded-For", "198.51.100.7")
+		if w := serve(router, r); w.Code != http.StatusOK {
+			t.Fatalf("status = %d", w.Code)
+		}
+	}
+	// Two identities from one address behind the proxy
+	post("first", "From one address")
+	post("second", "From the same address")
+
+	want := identity.HashIP(secretKey, "198.51.100.7")
+	comments, err := store.CommentsByIPHash(context.Background(), want, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 2 {
+		t.Fatalf("%d comments under the client's IP hash, want 2", len(comments))
+	}
+	for _, comment := range comments {
+		if comment.IPHash != want {
+			t.Errorf("IP hash = %q, want %q", comment.IPHash, want)
+		}
+		if strings.Contains(fmt.Sprintf("%+v", comment), "198.51.100.7") {
+			t.Errorf("raw IP stored: %+v", comment)
+		}
+	}
+}
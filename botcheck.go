@@ -0,0 +1,49 @@
+package main
+
+import (
+	"expvar"
+	"strconv"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/identity"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Hidden field that people never see, so only bots fill it in
+	honeypotField = "website"
+	// Signed time the form was served at
+	formStampField = "form_ts"
+	// Submissions quicker than this after the form was served come from bots
+	minFillTime = 2 * time.Second
+)
+
+// Comments rejected as coming from bots, by reason, published on /admin/metrics
+var botRejections = expvar.NewMap("comment_bot_rejections")
+
+// Signed timestamp for the comment forms, so bots can't claim the form was
+// served earlier than it was
+func formStamp() string {
+	return identity.Sign(secretKey, formStampField, strconv.FormatInt(time.Now().Unix(), 10))
+}
+
+// Return why a comment submission looks automated, or "" if it doesn't
+func botCheck(c *gin.Context) string {
+	if c.PostForm(honeypotField) != "" {
+		return "honeypot"
+	}
+
+	value, ok := identity.Verify(secretKey, formStampField, c.PostForm(formStampField))
+	if !ok {
+		return "bad timestamp"
+	}
+	served, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return "bad timestamp"
+	}
+	if time.Since(time.Unix(served, 0)) < minFillTime {
+		return "too fast"
+	}
+	return ""
+}
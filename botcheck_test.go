@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/identity"
+)
+
+// A comment form for the text as the page would have served it the given
+// time ago
+func commentForm(text string, age time.Duration) url.Values {
+	token, err := formToken()
+	if err != nil {
+		panic(err)
+	}
+	return url.Values{
+		"comment":      {text},
+		formStampField: {identity.Sign(secretKey, formStampField, strconv.FormatInt(time.Now().Add(-age).Unix(), 10))},
+		formTokenField: {token},
+	}
+}
+
+// Post the form as the visitor, returning the status
+func postComment(t *testing.T, visitor string, form url.Values) int {
+	t.Helper()
+	router := testRouter(http.MethodPost, "/video/:id/comments", identity.Middleware(secretKey), addComment)
+	return serve(router, formAs("/video/dQw4w9WgXcQ/comments", visitor, form)).Code
+}
+
+func countComments(t *testing.T) int {
+	t.Helper()
+	n, err := store.CountComments(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestBotCheck(t *testing.T) {
+	useTestStore(t)
+	honeypot := commentForm("Filled in by a bot", time.Minute)
+	honeypot.Set(honeypotField, "https://spam.example")
+	forged := commentForm("Forged timestamp", time.Minute)
+	forged.Set(formStampField, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)+".0000")
+
+	tests := []struct {
+		name   string
+		form   url.Values
+		reason string
+	}{
+		{"honeypot", honeypot, "honeypot"},
+		{"too fast", commentForm("Posted instantly", 0), "too fast"},
+		{"forged timestamp", forged, "bad timestamp"},
+	}
+	for _, test := range tests {
+		before := botRejections.Get(test.reason)
+		if code := postComment(t, "bot", test.form); code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", test.name, code)
+		}
+		if after := botRejections.Get(test.reason); after == nil || before != nil && after.String() == before.String() {
+			t.Errorf("%s: rejection not counted", test.name)
+		}
+	}
+	if n := countComments(t); n != 0 {
+		t.Errorf("%d comments posted by bots", n)
+	}
+}
+
+func TestBotCheckAllowsSlowPeople(t *testing.T) {
+	useTestStore(t)
+	if code := postComment(t, "person", commentForm("Took my time over this one", 5*time.Hour)); code != http.StatusOK {
+		t.Errorf("status = %d, want 200", code)
+	}
+	if n := countComments(t); n != 1 {
+		t.Errorf("%d comments posted, want 1", n)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/captcha"
+
+	"github.com/gin-gonic/gin"
+)
+
+// How long a commenter has to answer a challenge
+const captchaTTL = 10 * time.Minute
+
+// Asks commenters to prove they're human, nil unless CAPTCHA_ENABLED is set
+var challenger captcha.Challenger
+
+// Attach a fresh challenge to the comment form data when CAPTCHA is on
+func addChallenge(form gin.H) error {
+	if challenger == nil {
+		return nil
+	}
+	challenge, err := challenger.New()
+	if err != nil {
+		return err
+	}
+	form["Captcha"] = challenge
+	return nil
+}
+
+// Check the answer posted with a comment. Always passes when CAPTCHA is off.
+func verifyChallenge(c *gin.Context) (bool, error) {
+	if challenger == nil {
+		return true, nil
+	}
+	return challenger.Verify(c.Request.Context(), c.PostForm("captcha_id"), c.PostForm("captcha_answer"))
+}
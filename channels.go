@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/youtube/v3"
+)
+
+var errChannelNotFound = errors.New("channel not found")
+
+// YouTube channel IDs are UC followed by 22 URL-safe base64 characters
+var channelIDPattern = regexp.MustCompile(`^UC[A-Za-z0-9_-]{22}$`)
+
+// Show a channel and a page of its uploads, newest first. The "pageToken"
+// and "page" query parameters come from the next and previous links.
+func showChannel(keys *keyring) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		channelID := c.Param("id")
+		if !channelIDPattern.MatchString(channelID) {
+			renderError(c, http.StatusNotFound, "Channel not found.")
+			return
+		}
+		token := c.Query("pageToken")
+		page, err := strconv.Atoi(c.Query("page"))
+		if err != nil || page < 1 || token == "" {
+			page = 1
+		}
+
+		channel, uploads, err := channelUploads(c.Request.Context(), keys, channelID, token)
+		if errors.Is(err, errYouTubePageToken) {
+			// A stale or mangled link, start over
+			page = 1
+			channel, uploads, err = channelUploads(c.Request.Context(), keys, channelID, "")
+		}
+		if errors.Is(err, errChannelNotFound) {
+			renderError(c, http.StatusNotFound, "Channel not found.")
+			return
+		}
+		if err != nil {
+			renderYouTubeError(c, "Error loading channel", err)
+			return
+		}
+		addCommentCounts(c.Request.Context(), uploads.Videos)
+
+		data := gin.H{
+			"Channel": channel,
+			"Videos":  uploads.Videos,
+			"Page":    page,
+		}
+		if uploads.PrevToken != "" {
+			data["PrevURL"] = channelPageURL(channelID, uploads.PrevToken, max(page-1, 1))
+		}
+		if uploads.NextToken != "" {
+			data["NextURL"] = channelPageURL(channelID, uploads.NextToken, page+1)
+		}
+		c.HTML(http.StatusOK, "channel.html", data)
+	}
+}
+
+func channelPageURL(channelID, pageToken string, page int) string {
+	return "/channel/" + channelID + "?" + url.Values{
+		"pageToken": {pageToken},
+		"page":      {strconv.Itoa(page)},
+	}.Encode()
+}
+
+// Look up a channel and the page of its uploads playlist the token points
+// to, or the first when it's empty. Errors are as for searchYouTube, plus
+// errChannelNotFound.
+func channelUploads(ctx context.Context, keys *keyring, channelID, pageToken string) (map[string]string, searchPage, error) {
+	ctx, cancel := context.WithTimeout(ctx, detailsTimeout)
+	defer cancel()
+
+	var channel map[string]string
+	var uploads searchPage
+	err := withYouTube(ctx, keys, func(service *youtube.Service) (err error) {
+		channel, uploads, err = channelUploadsWith(ctx, service, channelID, pageToken)
+		return err
+	})
+	return channel, uploads, err
+}
+
+// channelUploads through the service
+func channelUploadsWith(ctx context.Context, service *youtube.Service, channelID, pageToken string) (map[string]string, searchPage, error) {
+	response, err := callYouTube(ctx, "channels.list", service.Channels.List([]string{"snippet", "contentDetails", "statistics"}).Id(channelID).Context(ctx).Do)
+	if err != nil {
+		return nil, searchPage{}, fmt.Errorf("fetching channel: %w", classifyYouTubeError(err))
+	}
+	if len(response.Items) == 0 {
+		return nil, searchPage{}, errChannelNotFound
+	}
+	item := response.Items[0]
+	channel := channelCard(item)
+
+	// Every upload is in a playlist of its own, which new or emptied
+	// channels may not have
+	var playlistID string
+	if item.ContentDetails != nil && item.ContentDetails.RelatedPlaylists != nil {
+		playlistID = item.ContentDetails.RelatedPlaylists.Uploads
+	}
+	if playlistID == "" {
+		return channel, searchPage{}, nil
+	}
+	call := service.PlaylistItems.List([]string{"contentDetails"}).PlaylistId(playlistID).MaxResults(10)
+	if pageToken != "" {
+		call = call.PageToken(pageToken)
+	}
+	playlist, err := callYouTube(ctx, "playlistItems.list", call.Context(ctx).Do)
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+		return channel, searchPage{}, nil
+	}
+	if err != nil {
+		return nil, searchPage{}, fmt.Errorf("fetching uploads: %w", classifyYouTubeError(err))
+	}
+
+	uploads := searchPage{NextToken: playlist.NextPageToken, PrevToken: playlist.PrevPageToken}
+	var videoIDs []string
+	for _, entry := range playlist.Items {
+		if entry.ContentDetails != nil && entry.ContentDetails.VideoId != "" {
+			videoIDs = append(videoIDs, entry.ContentDetails.VideoId)
+		}
+	}
+	if len(videoIDs) == 0 {
+		return channel, uploads, nil
+	}
+	uploads.Videos, err = videoDetails(ctx, service, videoIDs)
+	if err != nil {
+		return nil, searchPage{}, err
+	}
+	return channel, uploads, nil
+}
+
+// Fetch what the result lists show about each channel, in the order YouTube
+// returns them
+func channelDetails(ctx context.Context, service *youtube.Service, channelIDs []string) ([]map[string]string, error) {
+	response, err := callYouTube(ctx, "channels.list", service.Channels.List([]string{"snippet", "statistics"}).Id(channelIDs...).Context(ctx).Do)
+	if err != nil {
+		return nil, fmt.Errorf("fetching channel details: %w", classifyYouTubeError(err))
+	}
+	channels := make([]map[string]string, 0, len(response.Items))
+	for _, item := range response.Items {
+		channels = append(channels, channelCard(item))
+	}
+	return channels, nil
+}
+
+// A channel as the templates show it. Hidden or missing counts are
+// missingStat.
+func channelCard(item *youtube.Channel) map[string]string {
+	channel := map[string]string{
+		"id":          item.Id,
+		"title":       item.Id,
+		"subscribers": missingStat,
+		"videos":      missingStat,
+	}
+	if snippet := item.Snippet; snippet != nil {
+		channel["title"] = snippet.Title
+		if thumbnail := pickThumbnail(snippet.Thumbnails); thumbnail != nil {
+			channel["thumbnail"] = thumbnail.Url
+			if thumbnail.Width > 0 && thumbnail.Height > 0 {
+				channel["thumbnail_width"] = strconv.FormatInt(thumbnail.Width, 10)
+				channel["thumbnail_height"] = strconv.FormatInt(thumbnail.Height, 10)
+			}
+		}
+	}
+	if stats := item.Statistics; stats != nil {
+		if !stats.HiddenSubscriberCount {
+			channel["subscribers"] = compactCount(stats.SubscriberCount)
+		}
+		channel["videos"] = compactCount(stats.VideoCount)
+	}
+	return channel
+}
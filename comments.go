@@ -0,0 +1,896 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+	"github.com/TanishkBansode/right-to-comment/identity"
+	"github.com/TanishkBansode/right-to-comment/spam"
+	"github.com/TanishkBansode/right-to-comment/textnorm"
+	"github.com/TanishkBansode/right-to-comment/timestamp"
+	"github.com/TanishkBansode/right-to-comment/wordfilter"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultCommentLimit = 20
+	maxCommentLimit     = 100
+
+	// How long the browser remembers values such as the display name
+	cookieMaxAge = 365 * 24 * 60 * 60
+
+	// Longest display name accepted, in characters
+	maxAuthorLength = 40
+
+	// Remembers the last display name used in this browser
+	authorCookie = "author_name"
+)
+
+// How long after posting the author may still edit a comment, set from COMMENT_EDIT_WINDOW
+var editWindow = 15 * time.Minute
+
+// Longest comment accepted, in characters, set from COMMENT_MAX_LENGTH
+var maxCommentLength = 2000
+
+// Combining marks kept on each character of a comment, set from
+// COMMENT_MAX_COMBINING_MARKS
+var maxCombiningMarks = textnorm.DefaultMaxMarks
+
+// Posting the same text on the same video again within this long is taken
+// as a repeated submission rather than a new comment
+const duplicateWindow = 5 * time.Minute
+
+var (
+	errEmptyComment   = errors.New("comment cannot be empty")
+	errCommentTooLong = errors.New("comment is too long")
+	errBadVideoTime   = errors.New("video time is not a valid position")
+	errBadClip        = errors.New("clip is not a valid range of the video")
+	errAuthorTooLong  = errors.New("display name is too long")
+	errNotOwner       = errors.New("comment belongs to someone else")
+)
+
+// Runs of blank lines are collapsed to a single empty line
+var excessNewlines = regexp.MustCompile(`\n{3,}`)
+
+// Anything that looks like an HTML tag is dropped from display names
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// Blocks or masks unwanted words in new comments, configured from WORD_FILTER*
+var wordFilter *wordfilter.Filter
+
+// Spam heuristics applied to new comments, configured from SPAM_*
+var spamConfig = spam.Config{
+	Links:      true,
+	MaxLinks:   2,
+	Duplicates: true,
+	Shouting:   true,
+	Repetitive: true,
+}
+
+// A comment along with what the current viewer is allowed to do with it
+type commentView struct {
+	database.Comment
+	Owned    bool
+	Editable bool
+	// Viewer is an admin and gets the pin controls
+	Moderator bool
+	// Comment a permalink points at
+	Highlighted bool
+	// Links for the names that can be @mentioned on this video
+	Mentions map[string]string
+	// The video is locked, so there is no replying
+	Locked    bool
+	AvatarURL string
+	// Viewer's time zone, for the absolute times shown on hover
+	Zone *time.Location
+	// Length of the video, 0 when unknown or -1 for live and upcoming videos,
+	// whose timestamps aren't linked
+	VideoSeconds int
+	Reactions    []reactionCount
+	// Cards for the first links in the text, once they have been fetched
+	Previews []database.LinkPreview
+	Replies  []commentView
+}
+
+// Store a new comment for the video and return the updated comment list
+func addComment(c *gin.Context) {
+	videoID := c.Param("id")
+	// Anything else would start a thread no video page shows
+	if !videoIDPattern.MatchString(videoID) {
+		c.String(http.StatusBadRequest, "There's no YouTube video with that ID.")
+		return
+	}
+	if reason := botCheck(c); reason != "" {
+		log.Printf("Rejected comment on %s from IP hash %.12s as automated: %s", videoID, ipHash(c), reason)
+		botRejections.Add(reason, 1)
+		renderCommentFormError(c, videoID, "Your comment could not be posted, please try again.")
+		return
+	}
+	if ok, err := verifyChallenge(c); err != nil {
+		log.Println("Error verifying CAPTCHA:", err)
+		c.String(http.StatusInternalServerError, "Failed to add comment.")
+		return
+	} else if !ok {
+		renderCommentFormError(c, videoID, "Please answer the question below to show you're not a bot.")
+		return
+	}
+	commentText, err := validateComment(c.PostForm("comment"))
+	if err != nil {
+		renderCommentFormError(c, videoID, validationMessage(err))
+		return
+	}
+	videoTime, err := parseVideoTime(videoID, c.PostForm("t"))
+	if err != nil {
+		renderCommentFormError(c, videoID, validationMessage(err))
+		return
+	}
+	// A clip takes the place of a single position, and sorts by its start
+	clipStart, clipEnd, err := parseClip(videoID, c.PostForm("start"), c.PostForm("end"))
+	if err != nil {
+		renderCommentFormError(c, videoID, validationMessage(err))
+		return
+	}
+	if clipStart != nil {
+		videoTime = clipStart
+	}
+
+	// Logged-in users post under their username. Otherwise forms without a
+	// name field, like replies, use the remembered name.
+	user := currentUser(c)
+	name, posted := c.GetPostForm("author")
+	if user != nil {
+		name, posted = user.Username, false
+	} else if !posted {
+		name, _ = c.Cookie(authorCookie)
+	}
+	author, err := validateAuthor(name)
+	if err != nil {
+		renderCommentFormError(c, videoID, validationMessage(err))
+		return
+	}
+
+	notifyEmail, err := validateEmail(c.PostForm("notify_email"))
+	if err != nil {
+		renderCommentFormError(c, videoID, validationMessage(err))
+		return
+	}
+	if mailer == nil {
+		notifyEmail = ""
+	}
+
+	comment := database.NewComment{
+		VideoID:     videoID,
+		Text:        commentText,
+		Author:      author,
+		AuthorHash:  identity.Hash(identity.FromContext(c)),
+		IPHash:      ipHash(c),
+		UserID:      userID(user),
+		VideoTime:   videoTime,
+		VideoEnd:    clipEnd,
+		NotifyEmail: notifyEmail,
+		Lang:        langDetector.Detect(commentText),
+	}
+
+	// Shadowbanned authors aren't told, their comments just stay out of
+	// everyone else's listings
+	shadowbanned, err := store.IsShadowbanned(c.Request.Context(), comment.AuthorHash, comment.IPHash)
+	if err != nil {
+		log.Println("Error checking bans:", err)
+		c.String(http.StatusInternalServerError, "Failed to add comment.")
+		return
+	}
+	comment.VisibleToAuthorOnly = shadowbanned
+
+	// Resubmitted forms and double clicks get the same answer as the first
+	// submission without posting again
+	switch claimFormToken(c) {
+	case formTokenInvalid:
+		renderCommentFormError(c, videoID, "This form has expired, please try again.")
+		return
+	case formTokenUsed:
+		commentPosted(c, videoID, author)
+		return
+	}
+	var parentID *int64
+	if parent := c.PostForm("parent_id"); parent != "" {
+		id, err := strconv.ParseInt(parent, 10, 64)
+		if err != nil {
+			c.String(http.StatusNotFound, "The comment you replied to no longer exists.")
+			return
+		}
+		parentID = &id
+	}
+	duplicate, err := store.FindRecentDuplicate(c.Request.Context(), comment, parentID, time.Now().Add(-duplicateWindow))
+	if err != nil {
+		log.Println("Error checking for duplicate comments:", err)
+		c.String(http.StatusInternalServerError, "Failed to add comment.")
+		return
+	}
+	if duplicate {
+		commentPosted(c, videoID, author)
+		return
+	}
+
+	if rule, err := checkSpam(c.Request.Context(), comment); err != nil {
+		log.Println("Error checking comment for spam:", err)
+		c.String(http.StatusInternalServerError, "Failed to add comment.")
+		return
+	} else if rule != "" {
+		log.Printf("Rejected comment on %s as spam: %s", videoID, rule)
+		renderCommentFormError(c, videoID, "Your comment looks like spam and was not posted.")
+		return
+	}
+
+	var id int64
+	if parentID != nil {
+		id, err = store.AddReply(c.Request.Context(), *parentID, comment)
+		if err == nil && !comment.VisibleToAuthorOnly {
+			notifyReply(c, id)
+		}
+	} else {
+		id, err = store.AddComment(c.Request.Context(), comment)
+	}
+	if errors.Is(err, database.ErrNotFound) {
+		c.String(http.StatusNotFound, "The comment you replied to no longer exists.")
+		return
+	}
+	if err != nil {
+		log.Println("Error adding comment:", err)
+		c.String(http.StatusInternalServerError, "Failed to add comment.")
+		return
+	}
+	notifyWebhooks(c, id, comment)
+	publishComment(id, comment, c.PostForm("parent_id"))
+	queuePreviews(comment.Text)
+	// Drafts are only kept for the top-level form
+	if c.PostForm("parent_id") == "" {
+		if err := store.DeleteDraft(c.Request.Context(), comment.AuthorHash, videoID); err != nil {
+			log.Println("Error deleting draft:", err)
+		}
+	}
+
+	if posted {
+		if author != "" {
+			c.SetCookie(authorCookie, author, cookieMaxAge, "/", "", false, true)
+		} else {
+			c.SetCookie(authorCookie, "", -1, "/", "", false, true)
+		}
+	}
+
+	commentPosted(c, videoID, author)
+}
+
+// Send the visitor back to the updated comments. The form is replaced too,
+// since its one-time token and any challenge on it are used up.
+func commentPosted(c *gin.Context, videoID, author string) {
+	if c.GetHeader("HX-Request") == "" {
+		c.Redirect(http.StatusSeeOther, "/video/"+videoID)
+		return
+	}
+
+	page, err := commentPage(c, videoID, database.SortNewest, "", defaultCommentLimit, database.CommentFilter{})
+	if err == nil {
+		err = addChallenge(page)
+	}
+	if err != nil {
+		log.Println("Error loading comments:", err)
+		c.String(http.StatusInternalServerError, "Failed to load comments.")
+		return
+	}
+	page["FormAuthor"] = author
+	page["RefreshForm"] = true
+	c.HTML(http.StatusOK, "comments.html", page)
+}
+
+// Show the comment form again with the submitted text and what was wrong with it
+func renderCommentFormError(c *gin.Context, videoID, message string) {
+	form := gin.H{
+		"VideoID":    videoID,
+		"FormText":   c.PostForm("comment"),
+		"FormTime":   c.PostForm("t"),
+		"FormStart":  c.PostForm("start"),
+		"FormEnd":    c.PostForm("end"),
+		"FormAuthor": c.PostForm("author"),
+		"FormEmail":  c.PostForm("notify_email"),
+		"Notify":     mailer != nil,
+		"FormError":  message,
+		"ParentID":   c.PostForm("parent_id"),
+		"User":       currentUser(c),
+		"Live":       videoIsLive(videoID),
+	}
+	if err := addChallenge(form); err != nil {
+		log.Println("Error creating CAPTCHA:", err)
+		c.String(http.StatusInternalServerError, "Failed to load the comment form.")
+		return
+	}
+
+	if c.GetHeader("HX-Request") != "" {
+		c.Header("HX-Retarget", "#comment-form")
+		c.HTML(http.StatusBadRequest, "comment_form.html", form)
+		return
+	}
+
+	page, err := commentPage(c, videoID, database.SortNewest, "", defaultCommentLimit, database.CommentFilter{})
+	if err != nil {
+		log.Println("Error loading comments:", err)
+		c.String(http.StatusInternalServerError, "Failed to load comments.")
+		return
+	}
+	for key, value := range form {
+		page[key] = value
+	}
+	page["EmbedURL"] = fmt.Sprintf("https://www.youtube.com/embed/%s", videoID)
+	c.HTML(http.StatusBadRequest, "embed.html", page)
+}
+
+// A comment as listed in JSON
+type commentJSON struct {
+	ID               int64         `json:"id"`
+	ParentID         *int64        `json:"parent_id,omitempty"`
+	Author           string        `json:"author,omitempty"`
+	AvatarURL        string        `json:"avatar_url,omitempty"`
+	Text             string        `json:"text"`
+	Score            int           `json:"score"`
+	VideoTimeSeconds *int          `json:"video_time_seconds"`
+	VideoEndSeconds  *int          `json:"video_end_seconds,omitempty"`
+	CreatedAt        time.Time     `json:"created_at"`
+	EditedAt         *time.Time    `json:"edited_at,omitempty"`
+	Lang             string        `json:"lang"`
+	Deleted          bool          `json:"deleted,omitempty"`
+	Replies          []commentJSON `json:"replies,omitempty"`
+}
+
+func newCommentJSON(view commentView) commentJSON {
+	comment := commentJSON{
+		ID:               view.ID,
+		ParentID:         view.ParentID,
+		Author:           view.Author,
+		AvatarURL:        view.AvatarURL,
+		Text:             view.Text,
+		Score:            view.Score,
+		VideoTimeSeconds: view.VideoTime,
+		VideoEndSeconds:  view.VideoEnd,
+		CreatedAt:        view.CreatedAt,
+		EditedAt:         view.EditedAt,
+		Lang:             view.Lang,
+	}
+	if view.DeletedAt != nil {
+		comment.Text, comment.Author, comment.AvatarURL, comment.Deleted = "", "", "", true
+	}
+	for _, reply := range view.Replies {
+		comment.Replies = append(comment.Replies, newCommentJSON(reply))
+	}
+	return comment
+}
+
+// Number of comments on a video, for badges on other sites
+func commentCount(c *gin.Context) {
+	videoID := c.Param("id")
+	counts, err := store.CountCommentsByVideoIDs(c.Request.Context(), []string{videoID})
+	if err != nil {
+		log.Println("Error counting comments:", err)
+		c.String(http.StatusInternalServerError, "Failed to count comments.")
+		return
+	}
+	// Badges are fetched from other sites
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.JSON(http.StatusOK, gin.H{"count": counts[videoID]})
+}
+
+// Render a page of the comment list for a video, as an HTML fragment or as
+// JSON when the client asks for it
+func getComments(c *gin.Context) {
+	videoID := c.Param("id")
+	if !videoIDPattern.MatchString(videoID) {
+		c.String(http.StatusNotFound, "There's no YouTube video with that ID.")
+		return
+	}
+
+	limit := defaultCommentLimit
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 {
+		limit = min(n, maxCommentLimit)
+	}
+
+	sort := database.ParseSortOrder(c.Query("sort"))
+	page, err := commentPage(c, videoID, sort, c.Query("before"), limit, listingFilter(c))
+	if err != nil {
+		log.Println("Error loading comments:", err)
+		c.String(http.StatusInternalServerError, "Failed to load comments.")
+		return
+	}
+
+	if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON {
+		views := page["Comments"].([]commentView)
+		comments := make([]commentJSON, 0, len(views))
+		for _, view := range views {
+			comments = append(comments, newCommentJSON(view))
+		}
+		c.JSON(http.StatusOK, gin.H{"comments": comments, "next_before": page["NextBefore"]})
+		return
+	}
+	c.HTML(http.StatusOK, "comments.html", page)
+}
+
+// Filters given in the query string. Unknown language codes are ignored.
+func listingFilter(c *gin.Context) database.CommentFilter {
+	filter := database.CommentFilter{Query: strings.TrimSpace(c.Query("q"))}
+	if lang := c.Query("lang"); langCode.MatchString(lang) {
+		filter.Lang = lang
+	}
+	return filter
+}
+
+// Fetch one page of comments along with the cursor for the next page. A
+// cursor that can't be read starts from the first page.
+func commentPage(c *gin.Context, videoID string, sort database.SortOrder, cursor string, limit int, filter database.CommentFilter) (gin.H, error) {
+	viewer := identity.Hash(identity.FromContext(c))
+	comments, err := store.GetCommentsPage(c.Request.Context(), videoID, sort, limit, cursor, viewer, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	views, err := commentViews(c, comments.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	locked, err := store.IsVideoLocked(c.Request.Context(), videoID)
+	if err != nil {
+		return nil, err
+	}
+	var slowMode string
+	if interval, err := store.GetSlowMode(c.Request.Context(), videoID); err != nil {
+		return nil, err
+	} else if interval > 0 {
+		slowMode = slowModeLabel(interval)
+	}
+
+	author, _ := c.Cookie(authorCookie)
+	return gin.H{
+		"VideoID":    videoID,
+		"User":       currentUser(c),
+		"Accounts":   accountsEnabled,
+		"FormAuthor": author,
+		"Notify":     mailer != nil,
+		"Locked":     locked,
+		"SlowMode":   slowMode,
+		"Moderator":  isAdmin(c),
+		"Comments":   views,
+		"NextBefore": comments.NextCursor,
+		"Limit":      limit,
+		"Sort":       sort.String(),
+		"Sorts":      []string{"newest", "oldest", "top", "timestamp"},
+		"Paged":      cursor != "",
+		"Query":      filter.Query,
+		"Lang":       filter.Lang,
+		// Hides the form's timestamp and clip fields
+		"Live": videoIsLive(videoID),
+	}, nil
+}
+
+// Find the comment a permalink points at and the cursor of the page showing
+// it. Replies are shown under their parent, so the parent decides the page.
+func permalinkCursor(c *gin.Context, videoID, param string, sort database.SortOrder) (target int64, cursor string, err error) {
+	target, err = strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return 0, "", database.ErrNotFound
+	}
+
+	ctx := c.Request.Context()
+	comment, err := store.GetComment(ctx, target)
+	if err != nil {
+		return 0, "", err
+	}
+	if comment.VideoID != videoID {
+		return 0, "", database.ErrNotFound
+	}
+
+	anchor := comment.ID
+	if comment.ParentID != nil {
+		if comment.Hidden || comment.DeletedAt != nil {
+			return 0, "", database.ErrNotFound
+		}
+		anchor = *comment.ParentID
+	}
+
+	viewer := identity.Hash(identity.FromContext(c))
+	cursor, err = store.PageCursor(ctx, videoID, sort, anchor, defaultCommentLimit, viewer)
+	return target, cursor, err
+}
+
+// Mark the comment with the given ID, searching replies too
+func highlight(views []commentView, id int64) {
+	for i := range views {
+		if views[i].ID == id {
+			views[i].Highlighted = true
+			return
+		}
+		highlight(views[i].Replies, id)
+	}
+}
+
+// Build views for top-level comments, loading replies and reactions for the
+// whole batch with one query each
+func commentViews(c *gin.Context, comments []database.Comment) ([]commentView, error) {
+	ctx := c.Request.Context()
+
+	ids := make([]int64, 0, len(comments))
+	for _, comment := range comments {
+		ids = append(ids, comment.ID)
+	}
+	replies, err := store.GetReplies(ctx, ids, identity.Hash(identity.FromContext(c)))
+	if err != nil {
+		return nil, err
+	}
+
+	all := append([]database.Comment(nil), comments...)
+	for _, thread := range replies {
+		for _, reply := range thread {
+			ids = append(ids, reply.ID)
+			all = append(all, reply)
+		}
+	}
+	reactions, err := store.GetReactionCounts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	mentions, err := mentionLinks(ctx, comments)
+	if err != nil {
+		return nil, err
+	}
+	previews, err := commentPreviews(ctx, all)
+	if err != nil {
+		return nil, err
+	}
+	var locked bool
+	if len(comments) > 0 {
+		if locked, err = store.IsVideoLocked(ctx, comments[0].VideoID); err != nil {
+			return nil, err
+		}
+	}
+
+	views := make([]commentView, 0, len(comments))
+	for _, comment := range comments {
+		view := newCommentView(c, comment, reactions[comment.ID])
+		view.Mentions, view.Locked, view.Previews = mentions, locked, previews[comment.ID]
+		for _, reply := range replies[comment.ID] {
+			replyView := newCommentView(c, reply, reactions[reply.ID])
+			replyView.Mentions, replyView.Locked, replyView.Previews = mentions, locked, previews[reply.ID]
+			view.Replies = append(view.Replies, replyView)
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// Map the names that can be mentioned on the comments' video to the
+// permalink of each author's latest comment. Comments listed together are
+// all on one video, so one lookup covers them.
+func mentionLinks(ctx context.Context, comments []database.Comment) (map[string]string, error) {
+	if len(comments) == 0 {
+		return nil, nil
+	}
+	videoID := comments[0].VideoID
+	targets, err := store.MentionTargets(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+	links := make(map[string]string, len(targets))
+	for name, id := range targets {
+		links[name] = permalinkPath(videoID, id)
+	}
+	return links, nil
+}
+
+// Load a single comment's view, e.g. to swap it back in after a change
+func loadCommentView(c *gin.Context, id int64) (commentView, error) {
+	comment, err := store.GetComment(c.Request.Context(), id)
+	if err != nil {
+		return commentView{}, err
+	}
+	views, err := commentViews(c, []database.Comment{comment})
+	if err != nil {
+		return commentView{}, err
+	}
+	return views[0], nil
+}
+
+// Comments posted from this browser can be managed by the viewer
+func newCommentView(c *gin.Context, comment database.Comment, reactionCounts map[string]int) commentView {
+	owned := ownsComment(c, comment)
+
+	reactions := make([]reactionCount, len(reactionEmoji))
+	for i, emoji := range reactionEmoji {
+		reactions[i] = reactionCount{Emoji: emoji, Count: reactionCounts[emoji]}
+	}
+
+	var videoSeconds int
+	if video, ok := cachedVideo(comment.VideoID); ok {
+		videoSeconds = int(video.Duration.Seconds())
+		if video.Live != "" {
+			videoSeconds = -1
+		}
+	}
+
+	return commentView{
+		Comment:      comment,
+		Owned:        owned,
+		Editable:     owned && time.Since(comment.CreatedAt) <= editWindow,
+		Moderator:    isAdmin(c),
+		AvatarURL:    avatarURL(comment),
+		Zone:         viewerZone(c),
+		VideoSeconds: videoSeconds,
+		Reactions:    reactions,
+	}
+}
+
+// Normalize comment text, reject it when it is empty or too long and run it
+// through the word filter. Comments are stored normalized, so duplicate
+// checks compare the canonical text too.
+func validateComment(text string) (string, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = textnorm.Normalize(text, maxCombiningMarks)
+	text = excessNewlines.ReplaceAllString(strings.TrimSpace(text), "\n\n")
+	if text == "" {
+		return "", errEmptyComment
+	}
+	// Count characters rather than bytes so emoji and CJK text aren't penalized
+	if utf8.RuneCountInString(text) > maxCommentLength {
+		return "", errCommentTooLong
+	}
+	if wordFilter != nil {
+		return wordFilter.Apply(text)
+	}
+	return text, nil
+}
+
+// Clean up an optional display name, dropping control characters and markup.
+// A name that is only whitespace counts as no name.
+func validateAuthor(name string) (string, error) {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(htmlTag.ReplaceAllString(name, ""))
+	if utf8.RuneCountInString(name) > maxAuthorLength {
+		return "", errAuthorTooLong
+	}
+	return name, nil
+}
+
+// Parse the optional position a comment is anchored to, given as seconds or
+// as a timestamp like "1:23". It must fall within the video when its length
+// is known.
+func parseVideoTime(videoID, value string) (*int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	seconds, ok := timestamp.ParseOffset(value)
+	if !ok || seconds < 0 {
+		return nil, errBadVideoTime
+	}
+	if video, ok := cachedVideo(videoID); ok && video.Duration > 0 && time.Duration(seconds)*time.Second > video.Duration {
+		return nil, errBadVideoTime
+	}
+	return &seconds, nil
+}
+
+// Parse the optional "start" and "end" fields of a clip. Both or neither must
+// be given, and the clip must end after it starts and within the video.
+func parseClip(videoID, start, end string) (*int, *int, error) {
+	start, end = strings.TrimSpace(start), strings.TrimSpace(end)
+	if start == "" && end == "" {
+		return nil, nil, nil
+	}
+	from, okFrom := timestamp.ParseOffset(start)
+	to, okTo := timestamp.ParseOffset(end)
+	if !okFrom || !okTo || from < 0 || to <= from {
+		return nil, nil, errBadClip
+	}
+	if video, ok := cachedVideo(videoID); ok && video.Duration > 0 && time.Duration(to)*time.Second > video.Duration {
+		return nil, nil, errBadClip
+	}
+	return &from, &to, nil
+}
+
+// User-facing explanation of why validateComment rejected a comment
+func validationMessage(err error) string {
+	switch {
+	case errors.Is(err, wordfilter.ErrBlocked):
+		return "Your comment contains words that aren't allowed."
+	case errors.Is(err, errBadVideoTime):
+		return "The time must be a position within the video, like 1:23."
+	case errors.Is(err, errBadClip):
+		return "The clip must end after it starts and within the video, like 1:10 to 1:45."
+	case errors.Is(err, errBadEmail):
+		return "Please enter a valid email address, or leave it empty."
+	case errors.Is(err, errAuthorTooLong):
+		return fmt.Sprintf("Names can be at most %d characters long.", maxAuthorLength)
+	case errors.Is(err, errCommentTooLong):
+		return fmt.Sprintf("Comments can be at most %d characters long.", maxCommentLength)
+	default:
+		return "Comment cannot be empty."
+	}
+}
+
+// Delete a comment if the request carries the token issued to its author
+func deleteComment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusNotFound, "Comment not found.")
+		return
+	}
+
+	// The ownership check and the delete see the same comment
+	ctx := c.Request.Context()
+	var comment database.Comment
+	err = store.WithTx(ctx, func(tx *database.Tx) error {
+		var err error
+		if comment, err = tx.GetComment(ctx, id); err != nil {
+			return err
+		}
+		if comment.DeletedAt != nil {
+			return database.ErrNotFound
+		}
+		if !ownsComment(c, comment) {
+			return errNotOwner
+		}
+		return tx.DeleteComment(ctx, id)
+	})
+	if errors.Is(err, database.ErrNotFound) {
+		c.String(http.StatusNotFound, "Comment not found.")
+		return
+	}
+	if errors.Is(err, errNotOwner) {
+		c.String(http.StatusForbidden, "You can only delete your own comments.")
+		return
+	}
+	if err != nil {
+		log.Println("Error deleting comment:", err)
+		c.String(http.StatusInternalServerError, "Failed to delete comment.")
+		return
+	}
+	publishChange(eventCommentDeleted, comment)
+
+	// Plain HTML forms go back to the video. htmx swaps the comment out, or
+	// for a comment with replies swaps in its placeholder.
+	if c.Request.Method == http.MethodPost && c.GetHeader("HX-Request") == "" {
+		c.Redirect(http.StatusSeeOther, "/video/"+comment.VideoID)
+		return
+	}
+	view, err := loadCommentView(c, id)
+	if err != nil {
+		log.Println("Error loading comment:", err)
+		c.String(http.StatusInternalServerError, "Failed to load comment.")
+		return
+	}
+	if len(view.Replies) == 0 {
+		c.Status(http.StatusOK)
+		return
+	}
+	c.HTML(http.StatusOK, "comment", view)
+}
+
+// Replace the text of a comment if its author asks within the edit window
+func editComment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusNotFound, "Comment not found.")
+		return
+	}
+
+	ctx := c.Request.Context()
+	comment, err := store.GetComment(ctx, id)
+	if errors.Is(err, database.ErrNotFound) {
+		c.String(http.StatusNotFound, "Comment not found.")
+		return
+	}
+	if err != nil {
+		log.Println("Error loading comment:", err)
+		c.String(http.StatusInternalServerError, "Failed to edit comment.")
+		return
+	}
+	if comment.DeletedAt != nil {
+		c.String(http.StatusNotFound, "Comment not found.")
+		return
+	}
+
+	if !ownsComment(c, comment) {
+		c.String(http.StatusForbidden, "You can only edit your own comments.")
+		return
+	}
+	if time.Since(comment.CreatedAt) > editWindow {
+		c.String(http.StatusForbidden, "This comment can no longer be edited.")
+		return
+	}
+
+	commentText, err := validateComment(c.PostForm("comment"))
+	if err != nil {
+		c.String(http.StatusBadRequest, validationMessage(err))
+		return
+	}
+
+	err = store.UpdateComment(ctx, id, commentText, langDetector.Detect(commentText))
+	if errors.Is(err, database.ErrNotFound) {
+		c.String(http.StatusNotFound, "Comment not found.")
+		return
+	}
+	if err != nil {
+		log.Println("Error editing comment:", err)
+		c.String(http.StatusInternalServerError, "Failed to edit comment.")
+		return
+	}
+	queuePreviews(commentText)
+
+	if c.GetHeader("HX-Request") == "" {
+		c.Redirect(http.StatusSeeOther, "/video/"+comment.VideoID)
+		return
+	}
+
+	view, err := loadCommentView(c, id)
+	if err != nil {
+		log.Println("Error loading comment:", err)
+		c.String(http.StatusInternalServerError, "Failed to load comment.")
+		return
+	}
+	c.HTML(http.StatusOK, "comment", view)
+}
+
+// A comment belongs to the logged-in user who posted it, or else to the
+// browser it was posted from
+func ownsComment(c *gin.Context, comment database.Comment) bool {
+	if user := currentUser(c); user != nil && comment.UserID != nil && *comment.UserID == user.ID {
+		return true
+	}
+	hash := identity.Hash(identity.FromContext(c))
+	return comment.AuthorHash != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(comment.AuthorHash)) == 1
+}
+
+// Return the spam rule a new comment breaks, or "" if it looks fine
+func checkSpam(ctx context.Context, comment database.NewComment) (spam.Rule, error) {
+	if rule := spamConfig.Check(comment.Text); rule != "" {
+		return rule, nil
+	}
+	if spamConfig.Duplicates {
+		duplicate, err := store.HasRecentDuplicate(ctx, comment.AuthorHash, comment.Text, time.Now().Add(-time.Hour))
+		if err != nil {
+			return "", err
+		}
+		if duplicate {
+			return spam.Duplicate, nil
+		}
+	}
+	return "", nil
+}
+
+// Link timestamps in a rendered comment so they seek the video's player
+func linkTimestamps(comment template.HTML, videoID string, videoSeconds int) template.HTML {
+	if videoSeconds < 0 {
+		return comment
+	}
+	return timestamp.Link(comment, "/video/"+url.PathEscape(videoID), videoSeconds)
+}
+
+func userID(user *database.User) *int64 {
+	if user == nil {
+		return nil
+	}
+	return &user.ID
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+	"github.com/TanishkBansode/right-to-comment/identity"
+)
+
+// A request from the browser whose identity is id, or from a new visitor
+// when it's empty
+func requestAs(method, target, id string) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	if id != "" {
+		r.AddCookie(&http.Cookie{Name: "voter", Value: identity.Sign(secretKey, "voter", id)})
+	}
+	return r
+}
+
+func serve(handler http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+// Post a comment as the browser whose identity is id
+func postTestComment(t *testing.T, id string, comment database.NewComment) int64 {
+	t.Helper()
+	if comment.VideoID == "" {
+		comment.VideoID = "dQw4w9WgXcQ"
+	}
+	comment.AuthorHash = identity.Hash(id)
+	commentID, err := store.AddComment(context.Background(), comment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return commentID
+}
+
+func TestDeleteOwnComment(t *testing.T) {
+	useTestStore(t)
+	router := testRouter(http.MethodDelete, "/comments/:id", identity.Middleware(secretKey), deleteComment)
+	id := postTestComment(t, "author", database.NewComment{Text: "Regrettable"})
+	target := "/comments/" + strconv.FormatInt(id, 10)
+
+	for _, visitor := range []string{"", "someone else"} {
+		if w := serve(router, requestAs(http.MethodDelete, target, visitor)); w.Code != http.StatusForbidden {
+			t.Errorf("delete as %q: status = %d, want 403", visitor, w.Code)
+		}
+	}
+	if w := serve(router, requestAs(http.MethodDelete, target, "author")); w.Code != http.StatusOK {
+		t.Fatalf("delete as author: status = %d, want 200", w.Code)
+	}
+	comment, err := store.GetComment(context.Background(), id)
+	if err == nil && comment.DeletedAt == nil {
+		t.Error("comment still there")
+	}
+	if w := serve(router, requestAs(http.MethodDelete, target, "author")); w.Code != http.StatusNotFound {
+		t.Errorf("second delete: status = %d, want 404", w.Code)
+	}
+}
+
+// The texts of the comments the visitor is shown on a video, from the JSON
+// listing, with replies after their parent
+func listedTexts(t *testing.T, visitor string) []string {
+	t.Helper()
+	router := testRouter(http.MethodGet, "/video/:id/comments", identity.Middleware(secretKey), getComments)
+	r := requestAs(http.MethodGet, "/video/dQw4w9WgXcQ/comments", visitor)
+	r.Header.Set("Accept", "application/json")
+	w := serve(router, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	var listing struct{ Comments []commentJSON }
+	if err := json.Unmarshal(w.Body.Bytes(), &listing); err != nil {
+		t.Fatal(err)
+	}
+	var texts []string
+	var add func([]commentJSON)
+	add = func(comments []commentJSON) {
+		for _, comment := range comments {
+			texts = append(texts, comment.Text)
+			add(comment.Replies)
+		}
+	}
+	add(listing.Comments)
+	return texts
+}
+
+func TestShadowbannedCommentsOnlyShownToAuthor(t *testing.T) {
+	useTestStore(t)
+	postTestComment(t, "regular", database.NewComment{Text: "Visible"})
+	postTestComment(t, "spammer", database.NewComment{Text: "Shadowbanned", VisibleToAuthorOnly: true})
+
+	if got := listedTexts(t, "spammer"); !slices.Equal(got, []string{"Shadowbanned", "Visible"}) && !slices.Equal(got, []string{"Visible", "Shadowbanned"}) {
+		t.Errorf("author sees %q, want both comments", got)
+	}
+	if got := listedTexts(t, "someone else"); !slices.Equal(got, []string{"Visible"}) {
+		t.Errorf("other visitor sees %q, want only the visible comment", got)
+	}
+	if got := listedTexts(t, ""); !slices.Equal(got, []string{"Visible"}) {
+		t.Errorf("new visitor sees %q, want only the visible comment", got)
+	}
+}
+
+func TestDeletedParentShownAsPlaceholder(t *testing.T) {
+	useTestStore(t)
+	ctx := context.Background()
+	parent := postTestComment(t, "author", database.NewComment{Text: "Original thought"})
+	if _, err := store.AddReply(ctx, parent, database.NewComment{VideoID: "dQw4w9WgXcQ", Text: "A reply", AuthorHash: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	leaf := postTestComment(t, "author", database.NewComment{Text: "Nobody answered"})
+	for _, id := range []int64{parent, leaf} {
+		if err := store.DeleteComment(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	router := testRouter(http.MethodGet, "/video/:id/comments", identity.Middleware(secretKey), getComments)
+	body := serve(router, requestAs(http.MethodGet, "/video/dQw4w9WgXcQ/comments", "reader")).Body.String()
+	for _, want := range []string{"[deleted]", "A reply"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("listing doesn't contain %q", want)
+		}
+	}
+	for _, gone := range []string{"Original thought", "Nobody answered"} {
+		if strings.Contains(body, gone) {
+			t.Errorf("listing still contains %q", gone)
+		}
+	}
+}
+
+func TestReplyToDeletedComment(t *testing.T) {
+	useTestStore(t)
+	ctx := context.Background()
+	parent := postTestComment(t, "author", database.NewComment{Text: "Changed my mind"})
+	if err := store.DeleteComment(ctx, parent); err != nil {
+		t.Fatal(err)
+	}
+
+	form := commentForm("Too late", time.Minute)
+	form.Set("parent_id", strconv.FormatInt(parent, 10))
+	if status := postComment(t, "replier", form); status != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", status)
+	}
+	replies, err := store.GetReplies(ctx, []int64{parent}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies[parent]) != 0 {
+		t.Errorf("replies = %+v, want none", replies[parent])
+	}
+	if n := countComments(t); n != 0 {
+		t.Errorf("%d comments, want none", n)
+	}
+}
+
+func TestSameReplyInTwoThreads(t *testing.T) {
+	useTestStore(t)
+	// Only the double submit check, not the spam rule against repeated text
+	previous := spamConfig.Duplicates
+	spamConfig.Duplicates = false
+	t.Cleanup(func() { spamConfig.Duplicates = previous })
+	ctx := context.Background()
+	first := postTestComment(t, "author", database.NewComment{Text: "First thread"})
+	second := postTestComment(t, "author", database.NewComment{Text: "Second thread"})
+
+	for _, parent := range []int64{first, second} {
+		form := commentForm("+1", time.Minute)
+		form.Set("parent_id", strconv.FormatInt(parent, 10))
+		if status := postComment(t, "replier", form); status != http.StatusOK {
+			t.Fatalf("reply to %d: status = %d", parent, status)
+		}
+	}
+	replies, err := store.GetReplies(ctx, []int64{first, second}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies[first]) != 1 || len(replies[second]) != 1 {
+		t.Errorf("replies = %+v, want one in each thread", replies)
+	}
+}
+
+func TestGetCommentsUnknownVideo(t *testing.T) {
+	useTestStore(t)
+	router := testRouter(http.MethodGet, "/video/:id/comments", identity.Middleware(secretKey), getComments)
+	for _, id := range []string{"short", "dQw4w9WgXcQQ", "dQw4w9WgX%20Q", "%27%3B--%20drop"} {
+		if w := serve(router, requestAs(http.MethodGet, "/video/"+id+"/comments", "reader")); w.Code != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want 404", id, w.Code)
+		}
+	}
+	if w := serve(router, requestAs(http.MethodGet, "/video/dQw4w9WgXcQ/comments", "reader")); w.Code != http.StatusOK {
+		t.Errorf("valid ID: status = %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// An admin action, recorded in the same transaction as the change it
+// describes where the change allows
+type AuditEntry struct {
+	ID     int64
+	Actor  string
+	Action string
+	// "comment", "video", "ban" or "site"
+	TargetType string
+	TargetID   string
+	Note       string
+	CreatedAt  time.Time
+}
+
+// Either the database or a transaction
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func createAuditLogTable(ctx context.Context, tx *sql.Tx, d dialect) error {
+	_, err := tx.ExecContext(
+		ctx,
+		d.ddl(`CREATE TABLE IF NOT EXISTS audit_log (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            actor TEXT NOT NULL,
+            action TEXT NOT NULL,
+            target_type TEXT NOT NULL,
+            target_id TEXT NOT NULL,
+            note TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        )`),
+	)
+	return err
+}
+
+// Record an admin action that doesn't change the database itself
+func (s *Store) Audit(ctx context.Context, actor, action, targetType, targetID, note string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return AuditEntry{Actor: actor, Action: action, TargetType: targetType, TargetID: targetID, Note: note}.write(ctx, s.db)
+}
+
+func (e AuditEntry) write(ctx context.Context, exec execer) error {
+	_, err := exec.ExecContext(
+		ctx,
+		"INSERT INTO audit_log (actor, action, target_type, target_id, note, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		e.Actor, e.Action, e.TargetType, e.TargetID, strings.TrimSpace(e.Note), now(),
+	)
+	return err
+}
+
+// A page of recorded actions, newest first, continuing from the cursor when
+// it is valid. Empty filters match everything.
+func (s *Store) ListAuditLog(ctx context.Context, action, targetID string, limit int, cursor string) (Page[AuditEntry], error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var beforeID int64
+	if key, ok := decodeCursor(cursor, 1); ok {
+		beforeID = key[0]
+	}
+	// One extra row tells whether there is another page
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, actor, action, target_type, target_id, note, created_at FROM audit_log
+		WHERE (? = '' OR action = ?) AND (? = '' OR target_id = ?) AND (? = 0 OR id < ?)
+		ORDER BY id DESC LIMIT ?`,
+		action, action, targetID, targetID, beforeID, beforeID, limit+1,
+	)
+	if err != nil {
+		return Page[AuditEntry]{}, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.Actor, &e.Action, &e.TargetType, &e.TargetID, &e.Note, &e.CreatedAt); err != nil {
+			return Page[AuditEntry]{}, err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return Page[AuditEntry]{}, err
+	}
+	return newPage(entries, limit, func(e AuditEntry) []int64 { return []int64{e.ID} }), nil
+}
+
+// Every action name in the log, for the filter on the audit page
+func (s *Store) AuditActions(ctx context.Context) ([]string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT DISTINCT action FROM audit_log ORDER BY action")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var actions []string
+	for rows.Next() {
+		var action string
+		if err := rows.Scan(&action); err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+	}
+	return actions, rows.Err()
+}
@@ -0,0 +1,18 @@
+package database
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrBackupUnsupported = errors.New("backups of Postgres databases are taken with pg_dump")
+
+// Write a consistent copy of the database to path, which must not exist yet,
+// while it stays open for reads and writes
+func (s *Store) Backup(ctx context.Context, path string) error {
+	if s.dialect != sqlite {
+		return ErrBackupUnsupported
+	}
+	_, err := s.db.ExecContext(ctx, "VACUUM INTO ?", path)
+	return err
+}
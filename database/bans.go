@@ -0,0 +1,177 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var ErrBanNotFound = errors.New("ban not found")
+
+// A blocked commenter, identified by the hash of their browser identity, the
+// hash of their IP address, or both
+type Ban struct {
+	ID           int64
+	IdentityHash string
+	IPHash       string
+	Reason       string
+	// Shadowbanned actors can keep posting, but only they see their comments
+	Shadowbanned bool
+	// Nil for bans that never expire
+	ExpiresAt *time.Time
+	CreatedAt time.Time
+}
+
+func createBansTable(ctx context.Context, tx *sql.Tx, d dialect) error {
+	_, err := tx.ExecContext(
+		ctx,
+		d.ddl(`CREATE TABLE IF NOT EXISTS bans (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            identity_hash TEXT,
+            ip_hash TEXT,
+            reason TEXT NOT NULL DEFAULT '',
+            expires_at TIMESTAMP,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        )`),
+	)
+	if err != nil {
+		return err
+	}
+	return addColumn(ctx, tx, d, "bans", "shadowbanned", "INTEGER NOT NULL DEFAULT 0")
+}
+
+// Store a ban. Unless the audit entry already names a target, such as the
+// comment whose author is banned, it is recorded against the new ban.
+func (s *Store) AddBan(ctx context.Context, ban Ban, audit AuditEntry) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := addBan(ctx, tx, ban, audit)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func addBan(ctx context.Context, tx queryer, ban Ban, audit AuditEntry) (int64, error) {
+	var expires any
+	if ban.ExpiresAt != nil {
+		expires = formatTime(*ban.ExpiresAt)
+	}
+	var id int64
+	err := tx.QueryRowContext(
+		ctx,
+		"INSERT INTO bans (identity_hash, ip_hash, reason, shadowbanned, expires_at, created_at) VALUES (NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?) RETURNING id",
+		ban.IdentityHash, ban.IPHash, ban.Reason, ban.Shadowbanned, expires, now(),
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	audit.Action = "ban"
+	if ban.Shadowbanned {
+		audit.Action = "shadowban"
+	}
+	if audit.TargetType == "" {
+		audit.TargetType, audit.TargetID = "ban", strconv.FormatInt(id, 10)
+	}
+	return id, audit.write(ctx, tx)
+}
+
+func (s *Store) RemoveBan(ctx context.Context, id int64, audit AuditEntry) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM bans WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrBanNotFound
+	}
+	audit.Action, audit.TargetType, audit.TargetID = "unban", "ban", strconv.FormatInt(id, 10)
+	if err := audit.write(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Bans that are still in force, newest first
+func (s *Store) ListBans(ctx context.Context) ([]Ban, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, COALESCE(identity_hash, ''), COALESCE(ip_hash, ''), reason, shadowbanned, expires_at, created_at
+		FROM bans WHERE expires_at IS NULL OR expires_at > ?
+		ORDER BY id DESC`,
+		now(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bans []Ban
+	for rows.Next() {
+		var ban Ban
+		if err := rows.Scan(&ban.ID, &ban.IdentityHash, &ban.IPHash, &ban.Reason, &ban.Shadowbanned, &ban.ExpiresAt, &ban.CreatedAt); err != nil {
+			return nil, err
+		}
+		bans = append(bans, ban)
+	}
+	return bans, rows.Err()
+}
+
+// Report whether an unexpired outright ban matches the identity hash or the
+// IP hash. A ban stops applying the moment its expiry passes.
+func (s *Store) IsBanned(ctx context.Context, identityHash, ipHash string) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return s.hasActiveBan(ctx, identityHash, ipHash, false)
+}
+
+// Like IsBanned, for shadowbans
+func (s *Store) IsShadowbanned(ctx context.Context, identityHash, ipHash string) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return s.hasActiveBan(ctx, identityHash, ipHash, true)
+}
+
+func (s *Store) hasActiveBan(ctx context.Context, identityHash, ipHash string, shadowbanned bool) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var banned bool
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (
+			SELECT 1 FROM bans
+			WHERE (identity_hash = NULLIF(?, '') OR ip_hash = NULLIF(?, ''))
+			AND shadowbanned = ? AND (expires_at IS NULL OR expires_at > ?)
+		)`,
+		identityHash, ipHash, shadowbanned, now(),
+	).Scan(&banned)
+	return banned, err
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIsBanned(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+	ban := func(b Ban) int64 {
+		t.Helper()
+		id, err := s.AddBan(ctx, b, AuditEntry{Actor: "admin"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	at := func(d time.Duration) *time.Time {
+		t := time.Now().Add(d)
+		return &t
+	}
+	ban(Ban{IdentityHash: "forever"})
+	ban(Ban{IPHash: "ip-forever"})
+	ban(Ban{IdentityHash: "expired", ExpiresAt: at(-2 * time.Second)})
+	ban(Ban{IdentityHash: "expiring", ExpiresAt: at(time.Hour)})
+	ban(Ban{IdentityHash: "expired-now", ExpiresAt: at(0)})
+	ban(Ban{IdentityHash: "shadow", Shadowbanned: true})
+	removed := ban(Ban{IdentityHash: "removed"})
+	if err := s.RemoveBan(ctx, removed, AuditEntry{Actor: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		identity, ip   string
+		banned, shadow bool
+	}{
+		{"never expires", "forever", "", true, false},
+		{"by IP", "someone", "ip-forever", true, false},
+		{"expired", "expired", "", false, false},
+		{"expiring later", "expiring", "", true, false},
+		{"expiring this second", "expired-now", "", false, false},
+		{"shadowbanned", "shadow", "", false, true},
+		{"removed", "removed", "", false, false},
+		{"not banned", "someone", "ip-other", false, false},
+		// A ban on only an IP mustn't match visitors with no identity
+		{"empty identity", "", "", false, false},
+	}
+	for _, test := range tests {
+		banned, err := s.IsBanned(ctx, test.identity, test.ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shadow, err := s.IsShadowbanned(ctx, test.identity, test.ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if banned != test.banned || shadow != test.shadow {
+			t.Errorf("%s: banned = %v, shadowbanned = %v, want %v, %v", test.name, banned, shadow, test.banned, test.shadow)
+		}
+	}
+}
+
+func TestListBansLeavesOutExpired(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+	past := time.Now().Add(-time.Minute)
+	if _, err := s.AddBan(ctx, Ban{IdentityHash: "old", ExpiresAt: &past}, AuditEntry{Actor: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddBan(ctx, Ban{IdentityHash: "current", Reason: "spam"}, AuditEntry{Actor: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+	bans, err := s.ListBans(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bans) != 1 || bans[0].IdentityHash != "current" || bans[0].Reason != "spam" {
+		t.Errorf("bans = %+v, want only the current one", bans)
+	}
+	if err := s.RemoveBan(ctx, 999, AuditEntry{Actor: "admin"}); !errors.Is(err, ErrBanNotFound) {
+		t.Errorf("removing a missing ban: err = %v, want ErrBanNotFound", err)
+	}
+}
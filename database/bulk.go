@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+)
+
+var (
+	ErrUnknownBulkAction = errors.New("unknown bulk action")
+	ErrNoAuthor          = errors.New("comment predates author tracking")
+	ErrAlreadyBanned     = errors.New("author already banned in this batch")
+)
+
+// Bulk moderation actions
+const (
+	BulkDelete    = "delete"
+	BulkApprove   = "approve"
+	BulkBanAuthor = "ban-author"
+)
+
+// What happened to one comment in a bulk action. Err is nil when the action
+// was applied.
+type BulkResult struct {
+	ID  int64
+	Err error
+}
+
+// Apply one moderation action to each comment in a single transaction,
+// writing an audit entry per comment affected. Comments that are gone or
+// can't take the action get an error in their result and are skipped; any
+// other failure rolls the whole batch back.
+func (s *Store) BulkModerate(ctx context.Context, action string, ids []int64, ban Ban, audit AuditEntry) ([]BulkResult, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if action != BulkDelete && action != BulkApprove && action != BulkBanAuthor {
+		return nil, ErrUnknownBulkAction
+	}
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	banned := make(map[string]bool)
+	results := make([]BulkResult, 0, len(ids))
+	for _, id := range ids {
+		var err error
+		switch action {
+		case BulkDelete:
+			err = hardDeleteComment(ctx, tx, id, audit)
+		case BulkApprove:
+			err = approveComment(ctx, tx, id, audit)
+		case BulkBanAuthor:
+			err = banCommentAuthor(ctx, tx, id, ban, audit, banned)
+		}
+		if err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrNoAuthor) && !errors.Is(err, ErrAlreadyBanned) {
+			return nil, err
+		}
+		results = append(results, BulkResult{ID: id, Err: err})
+	}
+	return results, tx.Commit()
+}
+
+// Ban whoever wrote the comment, once per author in the batch
+func banCommentAuthor(ctx context.Context, tx queryer, id int64, ban Ban, audit AuditEntry, banned map[string]bool) error {
+	comment, err := scanComment(tx.QueryRowContext(ctx, "SELECT "+commentColumns+" FROM comments WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if comment.AuthorHash == "" {
+		return ErrNoAuthor
+	}
+	if banned[comment.AuthorHash] {
+		return ErrAlreadyBanned
+	}
+	banned[comment.AuthorHash] = true
+
+	ban.IdentityHash = comment.AuthorHash
+	audit.TargetType, audit.TargetID = "comment", strconv.FormatInt(id, 10)
+	_, err = addBan(ctx, tx, ban, audit)
+	return err
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCommentFields(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+	start := time.Now().Add(-time.Second)
+	at, end := 62, 75
+
+	id, err := s.AddComment(ctx, NewComment{
+		VideoID:    "dQw4w9WgXcQ",
+		Text:       "At the chorus",
+		Author:     "Rick",
+		AuthorHash: "author",
+		IPHash:     "ip",
+		VideoTime:  &at,
+		VideoEnd:   &end,
+		Lang:       "en",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateComment(ctx, id, "At the chorus, edited", "en"); err != nil {
+		t.Fatal(err)
+	}
+	replyID, err := s.AddReply(ctx, id, NewComment{VideoID: "dQw4w9WgXcQ", Text: "Agreed", AuthorHash: "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comment, err := s.GetComment(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment.ID != id || comment.VideoID != "dQw4w9WgXcQ" || comment.ParentID != nil ||
+		comment.Text != "At the chorus, edited" || comment.Author != "Rick" || comment.AuthorHash != "author" ||
+		comment.IPHash != "ip" || comment.Lang != "en" || comment.EditCount != 1 || comment.DeletedAt != nil ||
+		comment.VideoTime == nil || *comment.VideoTime != at || comment.VideoEnd == nil || *comment.VideoEnd != end {
+		t.Errorf("comment = %+v", comment)
+	}
+	for name, tm := range map[string]*time.Time{"created": &comment.CreatedAt, "edited": comment.EditedAt} {
+		if tm == nil || tm.Location() != time.UTC || tm.Before(start.Truncate(time.Second)) || tm.After(time.Now()) {
+			t.Errorf("%s at %v, want a UTC time from the test", name, tm)
+		}
+	}
+
+	// Every listing scans the same fields
+	page, err := s.GetCommentsPage(ctx, "dQw4w9WgXcQ", SortNewest, 10, "", "", CommentFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := s.LatestComments(ctx, "dQw4w9WgXcQ", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || !reflect.DeepEqual(page.Items[0], comment) {
+		t.Errorf("page = %+v, want %+v", page.Items, comment)
+	}
+	if len(latest) != 2 || !reflect.DeepEqual(latest[1], comment) {
+		t.Errorf("latest = %+v, want the reply then %+v", latest, comment)
+	}
+
+	replies, err := s.GetReplies(ctx, []int64{id}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := replies[id]; len(r) != 1 || r[0].ID != replyID || r[0].ParentID == nil || *r[0].ParentID != id {
+		t.Errorf("replies = %+v", r)
+	}
+}
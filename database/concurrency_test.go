@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// A store on an SQLite file, which unlike ":memory:" has a pool of
+// connections that really run at once
+func fileStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "comments.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestConnectionSettings(t *testing.T) {
+	t.Parallel()
+	s := fileStore(t)
+	ctx := context.Background()
+	for pragma, want := range map[string]string{
+		"journal_mode": "wal",
+		"busy_timeout": "5000",
+		"foreign_keys": "1",
+		// NORMAL
+		"synchronous": "1",
+	} {
+		var got string
+		if err := s.db.QueryRowContext(ctx, "PRAGMA "+pragma).Scan(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s = %s, want %s", pragma, got, want)
+		}
+	}
+
+	// Foreign keys are enforced
+	if _, err := s.AddReply(ctx, 12345, NewComment{VideoID: "dQw4w9WgXcQ", Text: "Orphan", AuthorHash: "a"}); err == nil {
+		t.Error("reply to a missing comment posted")
+	}
+}
+
+func TestConcurrentWritesAndReads(t *testing.T) {
+	t.Parallel()
+	s := fileStore(t)
+	ctx := context.Background()
+	const workers, each = 16, 25
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*workers*each)
+	for range workers {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range each {
+				id, err := s.AddComment(ctx, NewComment{VideoID: "dQw4w9WgXcQ", Text: "Hammering", AuthorHash: "a"})
+				if err == nil {
+					err = s.Vote(ctx, id, "voter", 1)
+				}
+				if err != nil {
+					errs <- err
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range each {
+				if _, err := s.GetCommentsPage(ctx, "dQw4w9WgXcQ", SortTop, 20, "", "", CommentFilter{}); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	count, err := s.CountComments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != workers*each {
+		t.Errorf("count = %d, want %d", count, workers*each)
+	}
+}
+
+func TestCloseCheckpointsOnce(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "comments.db")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddComment(context.Background(), NewComment{VideoID: "dQw4w9WgXcQ", Text: "Last words", AuthorHash: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The write-ahead log was folded into the database file
+	if info, err := os.Stat(path + "-wal"); err == nil && info.Size() > 0 {
+		t.Errorf("write-ahead log still holds %d bytes", info.Size())
+	}
+}
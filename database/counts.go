@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// A video whose stored comment count didn't match its comments
+type CountDrift struct {
+	VideoID string
+	Stored  int
+	Actual  int
+}
+
+// Condition for a comment row, as old or new in a trigger, that the counts
+// include
+func countedComment(row string) string {
+	return row + ".hidden = 0 AND " + row + ".deleted_at IS NULL AND " + row + ".visible_to_author_only = 0"
+}
+
+// video_comment_counts keeps the number of visible comments on each video,
+// replies included, so badges don't count them on every request. Triggers
+// keep it up to date in the same transaction as every insert, delete and
+// change of visibility, whichever code makes it.
+func createVideoCommentCounts(ctx context.Context, tx *sql.Tx, d dialect) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS video_comment_counts (
+            video_id TEXT PRIMARY KEY,
+            count INTEGER NOT NULL
+        )`,
+	}
+	if d == postgres {
+		statements = append(statements,
+			`CREATE OR REPLACE FUNCTION count_video_comments() RETURNS trigger LANGUAGE plpgsql AS $$
+            DECLARE
+                delta integer := 0;
+            BEGIN
+                IF TG_OP IN ('UPDATE', 'DELETE') AND `+countedComment("OLD")+` THEN
+                    delta := delta - 1;
+                END IF;
+                IF TG_OP IN ('INSERT', 'UPDATE') AND `+countedComment("NEW")+` THEN
+                    delta := delta + 1;
+                END IF;
+                IF delta <> 0 THEN
+                    INSERT INTO video_comment_counts AS counts (video_id, count)
+                    VALUES (COALESCE(NEW.video_id, OLD.video_id), delta)
+                    ON CONFLICT (video_id) DO UPDATE SET count = counts.count + excluded.count;
+                END IF;
+                RETURN NULL;
+            END
+            $$`,
+			`CREATE TRIGGER video_comment_counts AFTER INSERT OR DELETE OR UPDATE OF hidden, deleted_at, visible_to_author_only
+            ON comments FOR EACH ROW EXECUTE FUNCTION count_video_comments()`,
+		)
+	} else {
+		statements = append(statements,
+			`CREATE TRIGGER IF NOT EXISTS video_comment_counts_insert AFTER INSERT ON comments
+            WHEN `+countedComment("new")+` BEGIN
+                INSERT INTO video_comment_counts (video_id, count) VALUES (new.video_id, 1)
+                ON CONFLICT (video_id) DO UPDATE SET count = count + 1;
+            END`,
+			`CREATE TRIGGER IF NOT EXISTS video_comment_counts_delete AFTER DELETE ON comments
+            WHEN `+countedComment("old")+` BEGIN
+                UPDATE video_comment_counts SET count = count - 1 WHERE video_id = old.video_id;
+            END`,
+			`CREATE TRIGGER IF NOT EXISTS video_comment_counts_update AFTER UPDATE OF hidden, deleted_at, visible_to_author_only ON comments
+            WHEN (`+countedComment("old")+`) <> (`+countedComment("new")+`) BEGIN
+                INSERT INTO video_comment_counts (video_id, count)
+                VALUES (new.video_id, CASE WHEN `+countedComment("new")+` THEN 1 ELSE -1 END)
+                ON CONFLICT (video_id) DO UPDATE SET count = count + excluded.count;
+            END`,
+		)
+	}
+	statements = append(statements,
+		`INSERT INTO video_comment_counts (video_id, count)
+		SELECT video_id, COUNT(*) FROM comments WHERE `+countedComment("comments")+` GROUP BY video_id`,
+	)
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Recount the comments on every video, correcting the stored counts that
+// had drifted, e.g. after rows were changed by hand with the triggers off.
+// Returns the counts that were wrong, as they were.
+func (s *Store) ReconcileCommentCounts(ctx context.Context) ([]CountDrift, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT video_id, SUM(stored), SUM(actual) FROM (
+			SELECT video_id, count AS stored, 0 AS actual FROM video_comment_counts
+			UNION ALL
+			SELECT video_id, 0, COUNT(*) FROM comments WHERE `+countedComment("comments")+` GROUP BY video_id
+		) AS counts
+		GROUP BY video_id HAVING SUM(stored) <> SUM(actual)`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var drifts []CountDrift
+	for rows.Next() {
+		var d CountDrift
+		if err := rows.Scan(&d.VideoID, &d.Stored, &d.Actual); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		drifts = append(drifts, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, d := range drifts {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO video_comment_counts (video_id, count) VALUES (?, ?)
+			ON CONFLICT (video_id) DO UPDATE SET count = excluded.count`,
+			d.VideoID, d.Actual,
+		); err != nil {
+			return nil, err
+		}
+	}
+	return drifts, tx.Commit()
+}
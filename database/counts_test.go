@@ -0,0 +1,106 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestVideoCommentCounts(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+	counts := func() map[string]int {
+		t.Helper()
+		counts, err := s.CountCommentsByVideoIDs(ctx, []string{"dQw4w9WgXcQ", "9bZkp7q19f0", "unseen00000"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return counts
+	}
+	add := func(videoID string) int64 {
+		t.Helper()
+		id, err := s.AddComment(ctx, NewComment{VideoID: videoID, Text: "Counted", AuthorHash: "a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	first, second := add("dQw4w9WgXcQ"), add("dQw4w9WgXcQ")
+	add("9bZkp7q19f0")
+	if _, err := s.AddReply(ctx, first, NewComment{VideoID: "dQw4w9WgXcQ", Text: "Replies count too", AuthorHash: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddComment(ctx, NewComment{VideoID: "dQw4w9WgXcQ", Text: "Shadowbanned", AuthorHash: "c", VisibleToAuthorOnly: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := counts(), map[string]int{"dQw4w9WgXcQ": 3, "9bZkp7q19f0": 1, "unseen00000": 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after inserts: counts = %v, want %v", got, want)
+	}
+
+	if err := s.DeleteComment(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	if got := counts()["dQw4w9WgXcQ"]; got != 2 {
+		t.Errorf("after soft delete: count = %d, want 2", got)
+	}
+
+	if err := s.HardDeleteComment(ctx, first, AuditEntry{Actor: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := counts()["dQw4w9WgXcQ"]; got != 0 {
+		t.Errorf("after hard deleting a thread: count = %d, want 0", got)
+	}
+}
+
+func TestReconcileCommentCounts(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+	for _, videoID := range []string{"dQw4w9WgXcQ", "dQw4w9WgXcQ", "9bZkp7q19f0"} {
+		if _, err := s.AddComment(ctx, NewComment{VideoID: videoID, Text: "Counted", AuthorHash: "a"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	drifts, err := s.ReconcileCommentCounts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifts) != 0 {
+		t.Errorf("drift found in correct counts: %+v", drifts)
+	}
+
+	for _, corrupt := range []string{
+		"UPDATE video_comment_counts SET count = 40 WHERE video_id = 'dQw4w9WgXcQ'",
+		"DELETE FROM video_comment_counts WHERE video_id = '9bZkp7q19f0'",
+		"INSERT INTO video_comment_counts (video_id, count) VALUES ('ghost000000', 5)",
+	} {
+		if _, err := s.db.ExecContext(ctx, corrupt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	drifts, err = s.ReconcileCommentCounts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDrifts := []CountDrift{
+		{VideoID: "9bZkp7q19f0", Stored: 0, Actual: 1},
+		{VideoID: "dQw4w9WgXcQ", Stored: 40, Actual: 2},
+		{VideoID: "ghost000000", Stored: 5, Actual: 0},
+	}
+	if !reflect.DeepEqual(drifts, wantDrifts) {
+		t.Errorf("drifts = %+v, want %+v", drifts, wantDrifts)
+	}
+
+	counts, err := s.CountCommentsByVideoIDs(ctx, []string{"dQw4w9WgXcQ", "9bZkp7q19f0", "ghost000000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"dQw4w9WgXcQ": 2, "9bZkp7q19f0": 1, "ghost000000": 0}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("counts = %v after reconciling, want %v", counts, want)
+	}
+	if drifts, err := s.ReconcileCommentCounts(ctx); err != nil || len(drifts) != 0 {
+		t.Errorf("second run found %+v, %v", drifts, err)
+	}
+}
@@ -3,63 +3,691 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
 
-var db *sql.DB
+// Longest a query may run when the caller's context sets no deadline
+const DefaultQueryTimeout = 3 * time.Second
 
-func InitDB(dbPath string) error {
+// Store holds the connection to the comments database. It is safe for
+// concurrent use.
+type Store struct {
+	pool *sql.DB
+	// The pool, or the transaction when the store is a Tx
+	db         queryer
+	tx         *sql.Tx
+	savepoints int
+	dialect    dialect
+	// File of an SQLite database, empty for Postgres and in-memory ones
+	path      string
+	closeOnce sync.Once
+	closeErr  error
+	// Deadline for each call whose context has none, so a stuck query
+	// doesn't hold a connection forever. Set before the store is used.
+	QueryTimeout time.Duration
+}
+
+var ErrNotFound = errors.New("comment not found")
+
+type Comment struct {
+	ID        int64
+	VideoID   string
+	ParentID  *int64
+	Text      string
+	Score     int
+	CreatedAt time.Time
+	EditedAt  *time.Time
+	// Number of earlier versions kept in comment_revisions
+	EditCount int
+	// Set once the author deleted the comment, which is then only shown as a
+	// placeholder above its replies
+	DeletedAt *time.Time
+	Hidden    bool
+	// Pinned comments are listed above the rest in every sort order
+	Pinned bool
+	// Comments of shadowbanned authors, shown only to the author
+	VisibleToAuthorOnly bool
+	// Hash of the identity of the browser that posted the comment
+	AuthorHash string
+	// Keyed hash of the IP address it was posted from, empty for comments
+	// from before they were recorded
+	IPHash string
+	// Display name chosen by the author, empty when anonymous
+	Author string
+	// Account that posted the comment, if the author was logged in
+	UserID *int64
+	// Position in the video the comment is anchored to, if any
+	VideoTime *int
+	// End of the clip starting at VideoTime, for comments about a range
+	VideoEnd *int
+	// ISO 639-1 code of the language the text is in, or "und"
+	Lang string
+}
+
+// Columns selected by every comment query, in the order scanComment expects
+var commentColumns = "id, video_id, parent_id, comment, " + scoreOf("comments") + ", created_at, edited_at, " +
+	"(SELECT COUNT(*) FROM comment_revisions WHERE comment_id = comments.id), deleted_at, hidden, pinned, visible_to_author_only, COALESCE(author_hash, ''), COALESCE(author, ''), user_id, video_time_seconds, video_end_seconds, COALESCE(lang, 'und'), COALESCE(ip_hash, '')"
+
+// Vote total of the comment row known by the given table name or alias
+func scoreOf(table string) string {
+	return "COALESCE((SELECT SUM(value) FROM comment_votes WHERE comment_id = " + table + ".id), 0)"
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// Scan commentColumns into a Comment, followed by any extra selected columns
+func scanComment(row scanner, extra ...any) (Comment, error) {
+	var comment Comment
+	dest := []any{
+		&comment.ID,
+		&comment.VideoID,
+		&comment.ParentID,
+		&comment.Text,
+		&comment.Score,
+		&comment.CreatedAt,
+		&comment.EditedAt,
+		&comment.EditCount,
+		&comment.DeletedAt,
+		&comment.Hidden,
+		&comment.Pinned,
+		&comment.VisibleToAuthorOnly,
+		&comment.AuthorHash,
+		&comment.Author,
+		&comment.UserID,
+		&comment.VideoTime,
+		&comment.VideoEnd,
+		&comment.Lang,
+		&comment.IPHash,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return comment, err
+	}
+	// Times are stored in UTC, but drivers differ in the location they give
+	// times read back
+	comment.CreatedAt = comment.CreatedAt.UTC()
+	for _, t := range []*time.Time{comment.EditedAt, comment.DeletedAt} {
+		if t != nil {
+			*t = t.UTC()
+		}
+	}
+	return comment, nil
+}
+
+func scanComments(rows *sql.Rows) ([]Comment, error) {
+	defer rows.Close()
+
+	var comments []Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+// Settings applied to every connection. Background jobs write alongside
+// requests, so writers wait briefly for a lock instead of failing with
+// SQLITE_BUSY, and transactions take the write lock when they begin, since a
+// read lock can't wait its way up to a write lock. Write-ahead logging lets
+// readers carry on during a write, and with it synchronous=NORMAL stays safe
+// against corruption while only risking the last commits on power loss.
+// Incremental auto-vacuum only takes on databases created with it, and lets
+// Optimize give space back after deletions. The pragmas run in order, so the
+// busy timeout comes first to cover the others.
+var connectionSettings = url.Values{
+	"_pragma": {"busy_timeout(5000)", "auto_vacuum(incremental)", "foreign_keys(1)", "journal_mode(WAL)", "synchronous(NORMAL)"},
+	"_txlock": {"immediate"},
+}
+
+// Open the database, creating or upgrading its tables as needed. The source
+// is a postgres:// URL or the path of an SQLite file.
+func Open(source string) (*Store, error) {
+	d := dialectOf(source)
+	var db *sql.DB
 	var err error
-	db, err = sql.Open("sqlite", dbPath)
+	if d == postgres {
+		db, err = openPostgres(source)
+	} else {
+		db, err = sql.Open("sqlite", source+"?"+connectionSettings.Encode())
+	}
 	if err != nil {
+		return nil, err
+	}
+	s := &Store{pool: db, db: db, dialect: d, QueryTimeout: DefaultQueryTimeout}
+	if d == sqlite && source != ":memory:" {
+		s.path = source
+	}
+	if err := s.configure(source); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := s.migrate(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Size the connection pool and check an SQLite database really is in WAL
+// mode, which some filesystems, like network shares, don't support
+func (s *Store) configure(dbPath string) error {
+	if s.dialect == postgres {
+		s.pool.SetMaxOpenConns(max(4, 2*runtime.NumCPU()))
+		s.pool.SetMaxIdleConns(max(4, 2*runtime.NumCPU()))
+		return nil
+	}
+	// Each connection to ":memory:" gets a database of its own, which goes
+	// when the connection closes, so the store keeps to one that is never
+	// closed while it is open. Calls then take turns, and a call made on the
+	// Store inside its own WithTx waits forever; use the Tx.
+	if dbPath == ":memory:" {
+		s.pool.SetMaxOpenConns(1)
+		s.pool.SetMaxIdleConns(1)
+		s.pool.SetConnMaxLifetime(0)
+		s.pool.SetConnMaxIdleTime(0)
+		return nil
+	}
+	// Only one connection writes at a time however many there are, so a few
+	// readers beside it are all that helps
+	s.pool.SetMaxOpenConns(max(4, runtime.NumCPU()))
+	s.pool.SetMaxIdleConns(max(4, runtime.NumCPU()))
+
+	var mode string
+	if err := s.db.QueryRowContext(context.Background(), "PRAGMA journal_mode").Scan(&mode); err != nil {
 		return err
 	}
-	_, err = db.ExecContext(
-		context.Background(),
-		`CREATE TABLE IF NOT EXISTS comments (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            video_id TEXT NOT NULL,
-            comment TEXT,
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-        )`,
+	if !strings.EqualFold(mode, "wal") {
+		return fmt.Errorf("could not enable WAL mode on %s, journal mode is %s", dbPath, mode)
+	}
+	return nil
+}
+
+// Close the database once the queries already running are done, folding
+// SQLite's write-ahead log back into the database file first. Later calls
+// return the first call's result.
+func (s *Store) Close() error {
+	// A Tx shares the pool of the Store it came from, which closes it
+	if s.tx != nil {
+		return nil
+	}
+	s.closeOnce.Do(func() {
+		var err error
+		if s.dialect == sqlite {
+			// Leaves the database in one file, for backups taken after shutdown
+			_, err = s.db.ExecContext(context.Background(), "PRAGMA wal_checkpoint(TRUNCATE)")
+		}
+		s.closeErr = errors.Join(err, s.pool.Close())
+	})
+	return s.closeErr
+}
+
+// Check the database can still be reached and queried
+func (s *Store) Ping(ctx context.Context) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var one int
+	return s.db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+}
+
+// Give the context the store's query timeout unless it already has a deadline
+func (s *Store) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || s.QueryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.QueryTimeout)
+}
+
+// Fields supplied when posting a comment
+type NewComment struct {
+	VideoID string
+	Text    string
+	// Display name, or empty to post anonymously
+	Author string
+	// Logged-in account posting the comment, if any
+	UserID *int64
+	// Hash of the author's browser identity, which lets them edit or delete
+	// the comment and is used to spot repeated posts
+	AuthorHash string
+	// Keyed hash of the client's IP address, never the address itself
+	IPHash string
+	// Seconds into the video the comment refers to, or nil
+	VideoTime *int
+	// Where the clip starting at VideoTime ends, or nil for a single moment
+	VideoEnd *int
+	// Set for shadowbanned authors, whose comments only they can see
+	VisibleToAuthorOnly bool
+	// Where to send reply notifications, or empty for none
+	NotifyEmail string
+	// Language code of the text
+	Lang string
+}
+
+func (s *Store) AddComment(ctx context.Context, comment NewComment) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return s.insertComment(ctx, comment, nil)
+}
+
+// Replies are attached to the top-level comment of the thread, so nesting
+// never goes deeper than one level. Deleted and hidden comments can't be
+// replied to.
+func (s *Store) AddReply(ctx context.Context, parentID int64, comment NewComment) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	parent, err := s.GetComment(ctx, parentID)
+	if err != nil {
+		return 0, err
+	}
+	if parent.VideoID != comment.VideoID || parent.DeletedAt != nil || parent.Hidden {
+		return 0, ErrNotFound
+	}
+	if parent.ParentID != nil {
+		parentID = *parent.ParentID
+	}
+	return s.insertComment(ctx, comment, &parentID)
+}
+
+func (s *Store) insertComment(ctx context.Context, comment NewComment, parentID *int64) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var id int64
+	err := s.db.QueryRowContext(
+		ctx,
+		`INSERT INTO comments (video_id, comment, author, user_id, author_hash, ip_hash, parent_id, video_time_seconds, video_end_seconds, visible_to_author_only, notify_email, lang, created_at)
+		VALUES (?, ?, NULLIF(?, ''), ?, ?, NULLIF(?, ''), ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?)
+		RETURNING id`,
+		comment.VideoID, comment.Text, comment.Author, comment.UserID, comment.AuthorHash, comment.IPHash, parentID, comment.VideoTime, comment.VideoEnd,
+		comment.VisibleToAuthorOnly, comment.NotifyEmail, comment.Lang, now(),
+	).Scan(&id)
+	return id, err
+}
+
+// Report whether the author already posted this text in the same thread since
+// the given time and hasn't deleted it. parentID is the comment being replied
+// to, or nil for a top-level comment.
+func (s *Store) FindRecentDuplicate(ctx context.Context, comment NewComment, parentID *int64, since time.Time) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	// Replies to replies are stored under the top-level comment, see AddReply,
+	// and 0 stands for no parent
+	var parent int64
+	if parentID != nil {
+		parent = *parentID
+	}
+	var exists bool
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (
+			SELECT 1 FROM comments
+			WHERE video_id = ? AND created_at >= ? AND author_hash = ? AND comment = ? AND deleted_at IS NULL
+			AND COALESCE(parent_id, 0) = COALESCE((SELECT replied.parent_id FROM comments replied WHERE replied.id = ?), ?)
+		)`,
+		comment.VideoID, formatTime(since), comment.AuthorHash, comment.Text, parent, parent,
+	).Scan(&exists)
+	return exists, err
+}
+
+// Report whether the same author already posted this exact text since the given time
+func (s *Store) HasRecentDuplicate(ctx context.Context, authorHash, text string, since time.Time) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var exists bool
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (
+			SELECT 1 FROM comments
+			WHERE author_hash = ? AND comment = ? AND created_at >= ?
+		)`,
+		authorHash, text, formatTime(since),
+	).Scan(&exists)
+	return exists, err
+}
+
+// Comments of shadowbanned authors are only listed for the viewer with the
+// matching identity hash
+const visibleTo = "(visible_to_author_only = 0 OR author_hash = ?)"
+
+// Deleted top-level comments are kept as placeholders while they still have
+// replies to show, and dropped once they have none
+const notDeletedOrHasReplies = `(deleted_at IS NULL OR EXISTS (
+	SELECT 1 FROM comments AS reply WHERE reply.parent_id = comments.id AND reply.deleted_at IS NULL AND reply.hidden = 0
+))`
+
+// Top-level comments only, replies are loaded separately by GetReplies
+func (s *Store) GetComments(ctx context.Context, videoID string, sort SortOrder, viewerHash string) ([]Comment, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT "+commentColumns+" FROM comments WHERE video_id = ? AND parent_id IS NULL AND hidden = 0 AND "+
+			notDeletedOrHasReplies+" AND "+visibleTo+" ORDER BY "+sort.orderBy(),
+		videoID, viewerHash,
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	return scanComments(rows)
 }
 
-func AddComment(videoId, commentText string) error {
-	_, err := db.ExecContext(
-		context.Background(),
-		"INSERT INTO comments (video_id, comment) VALUES (?, ?)",
-		videoId, commentText,
+// Newest visible comments on a video, replies included
+func (s *Store) LatestComments(ctx context.Context, videoID string, limit int) ([]Comment, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT "+commentColumns+" FROM comments WHERE video_id = ? AND hidden = 0 AND deleted_at IS NULL AND visible_to_author_only = 0 ORDER BY created_at DESC, id DESC LIMIT ?",
+		videoID, limit,
 	)
-	return err
+	if err != nil {
+		return nil, err
+	}
+	return scanComments(rows)
 }
 
-func GetComments(videoId string) ([]map[string]string, error) {
-	rows, err := db.QueryContext(
-		context.Background(),
-		"SELECT comment, created_at FROM comments WHERE video_id = ? ORDER BY created_at DESC",
-		videoId,
+// A page of the newest visible comments across all videos, replies
+// included, continuing from the cursor when it is valid
+func (s *Store) RecentComments(ctx context.Context, limit int, cursor string) (Page[Comment], error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	after, args := "", []any{}
+	if key, ok := decodeCursor(cursor, 2); ok {
+		after = " AND (created_at, id) < (?, ?)"
+		args = append(args, storedTime(key[0]), key[1])
+	}
+	// One extra row tells whether there is another page
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT `+commentColumns+` FROM comments
+		WHERE hidden = 0 AND deleted_at IS NULL AND visible_to_author_only = 0`+after+`
+		ORDER BY created_at DESC, id DESC LIMIT ?`,
+		append(args, limit+1)...,
+	)
+	if err != nil {
+		return Page[Comment]{}, err
+	}
+	comments, err := scanComments(rows)
+	if err != nil {
+		return Page[Comment]{}, err
+	}
+	return newPage(comments, limit, func(c Comment) []int64 { return []int64{c.CreatedAt.Unix(), c.ID} }), nil
+}
+
+// Top-level comments listed for a video, binding the video ID and the viewer's hash
+const listedComments = "video_id = ? AND parent_id IS NULL AND hidden = 0 AND " + notDeletedOrHasReplies + " AND " + visibleTo
+
+// Narrows down the comments listed on a video, empty fields match everything
+type CommentFilter struct {
+	// Keep threads where the comment or one of its replies contains every
+	// word of the query
+	Query string
+	// Keep top-level comments in this language
+	Lang string
+}
+
+// A page of top-level comments continuing from the cursor of the previous
+// page in the same sort order, or from the start when it isn't valid for it
+func (s *Store) GetCommentsPage(ctx context.Context, videoID string, sort SortOrder, limit int, cursor string, viewerHash string, only CommentFilter) (Page[Comment], error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	filter := ""
+	args := []any{videoID, viewerHash}
+	if only.Lang != "" {
+		filter += " AND COALESCE(lang, 'und') = ?"
+		args = append(args, only.Lang)
+	}
+	if match, arg := s.dialect.textMatch("matched", only.Query); match != "" {
+		filter += ` AND id IN (
+			SELECT COALESCE(parent_id, id) FROM comments AS matched
+			WHERE matched.hidden = 0 AND matched.deleted_at IS NULL AND ` + match + `
+		)`
+		args = append(args, arg)
+	}
+	if key, ok := decodeCursor(cursor, len(sort.key(Comment{}))); ok {
+		after, keyArgs := sort.afterKey(key)
+		filter += " AND " + after
+		args = append(args, keyArgs...)
+	}
+	// One extra row tells whether there is another page
+	args = append(args, limit+1)
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT `+commentColumns+` FROM comments
+		WHERE `+listedComments+filter+`
+		ORDER BY `+sort.orderBy()+` LIMIT ?`,
+		args...,
+	)
+	if err != nil {
+		return Page[Comment]{}, err
+	}
+	comments, err := scanComments(rows)
+	if err != nil {
+		return Page[Comment]{}, err
+	}
+	return newPage(comments, limit, sort.key), nil
+}
+
+// Cursor for GetCommentsPage that loads the page of limit comments holding
+// the given top-level comment, empty when it is on the first page
+func (s *Store) PageCursor(ctx context.Context, videoID string, sort SortOrder, commentID int64, limit int, viewerHash string) (string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var listed bool
+	if err := s.db.QueryRowContext(
+		ctx,
+		"SELECT EXISTS(SELECT 1 FROM comments WHERE id = ? AND "+listedComments+")",
+		commentID, videoID, viewerHash,
+	).Scan(&listed); err != nil {
+		return "", err
+	}
+	if !listed {
+		return "", ErrNotFound
+	}
+
+	// Everything not after the comment is the comment itself and those before it
+	var position int
+	if err := s.db.QueryRowContext(
+		ctx,
+		"SELECT COUNT(*) - 1 FROM comments WHERE "+listedComments+" AND NOT "+sort.after(),
+		videoID, viewerHash, commentID,
+	).Scan(&position); err != nil {
+		return "", err
+	}
+
+	start := position / limit * limit
+	if start == 0 {
+		return "", nil
+	}
+
+	// The page starts after the last comment of the one before it
+	last, err := scanComment(s.db.QueryRowContext(
+		ctx,
+		"SELECT "+commentColumns+" FROM comments WHERE "+listedComments+" ORDER BY "+sort.orderBy()+" LIMIT 1 OFFSET ?",
+		videoID, viewerHash, start-1,
+	))
+	if err != nil {
+		return "", err
+	}
+	return encodeCursor(sort.key(last)...), nil
+}
+
+// Replies to the given comments, oldest first and grouped by parent
+func (s *Store) GetReplies(ctx context.Context, parentIDs []int64, viewerHash string) (map[int64][]Comment, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	replies := make(map[int64][]Comment)
+	if len(parentIDs) == 0 {
+		return replies, nil
+	}
+
+	args := []any{viewerHash}
+	for _, id := range parentIDs {
+		args = append(args, id)
+	}
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT "+commentColumns+" FROM comments WHERE hidden = 0 AND deleted_at IS NULL AND "+visibleTo+" AND parent_id IN ("+placeholders(len(parentIDs))+") ORDER BY id",
+		args...,
 	)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	comments, err := scanComments(rows)
+	if err != nil {
+		return nil, err
+	}
 
-	var comments []map[string]string
-	for rows.Next() {
-		var comment, createdAt string
-		if err := rows.Scan(&comment, &createdAt); err != nil {
-			return nil, err
+	for _, comment := range comments {
+		replies[*comment.ParentID] = append(replies[*comment.ParentID], comment)
+	}
+	return replies, nil
+}
+
+func (s *Store) GetComment(ctx context.Context, id int64) (Comment, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	comment, err := scanComment(s.db.QueryRowContext(
+		ctx,
+		"SELECT "+commentColumns+" FROM comments WHERE id = ?",
+		id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Comment{}, ErrNotFound
+	}
+	return comment, err
+}
+
+// Mark a comment as deleted, keeping the row so its replies stay attached
+func (s *Store) DeleteComment(ctx context.Context, id int64) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(
+		ctx,
+		"UPDATE comments SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL",
+		now(), id,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Replace the text of a comment, keeping the previous text as a revision
+func (s *Store) UpdateComment(ctx context.Context, id int64, newText, lang string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO comment_revisions (comment_id, comment, created_at) SELECT id, comment, ? FROM comments WHERE id = ?",
+		now(), id,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		"UPDATE comments SET comment = ?, lang = NULLIF(?, ''), edited_at = ? WHERE id = ?",
+		newText, lang, now(), id,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Remove a comment for good together with its replies and everything
+// attached to them, e.g. when the law requires it
+func (s *Store) HardDeleteComment(ctx context.Context, id int64, audit AuditEntry) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := hardDeleteComment(ctx, tx, id, audit); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func hardDeleteComment(ctx context.Context, tx queryer, id int64, audit AuditEntry) error {
+	thread := "SELECT id FROM comments WHERE id = ? OR parent_id = ?"
+	for _, table := range []string{"comment_votes", "comment_reactions", "comment_reports", "comment_revisions"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE comment_id IN ("+thread+")", id, id); err != nil {
+			return err
 		}
-		comments = append(comments, map[string]string{
-			"text":      comment,
-			"createdAt": createdAt,
-		})
 	}
-	return comments, nil
+	if _, err := tx.ExecContext(ctx, "DELETE FROM comments WHERE parent_id = ?", id); err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM comments WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	audit.Action, audit.TargetType, audit.TargetID = "delete", "comment", strconv.FormatInt(id, 10)
+	return audit.write(ctx, tx)
+}
+
+// Comma-separated "?" placeholders for an IN clause
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
 }
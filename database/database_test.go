@@ -0,0 +1,285 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreKeepsOneDatabase(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+
+	id, err := s.AddComment(ctx, NewComment{VideoID: "dQw4w9WgXcQ", Text: "First", AuthorHash: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Later calls see the tables the migrations made and what was written
+	comment, err := s.GetComment(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment.Text != "First" || comment.VideoID != "dQw4w9WgXcQ" {
+		t.Errorf("comment = %+v", comment)
+	}
+	count, err := s.CountComments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("count = %d, want 1", count)
+	}
+}
+
+func TestMemoryStoresAreSeparate(t *testing.T) {
+	t.Parallel()
+	a, b := NewTestStore(t), NewTestStore(t)
+	ctx := context.Background()
+
+	if _, err := a.AddComment(ctx, NewComment{VideoID: "dQw4w9WgXcQ", Text: "Only in a", AuthorHash: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	count, err := b.CountComments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("second store has %d comments, want 0", count)
+	}
+}
+
+func TestMemoryStoreTransactions(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+
+	err := s.WithTx(ctx, func(tx *Tx) error {
+		_, err := tx.AddComment(ctx, NewComment{VideoID: "dQw4w9WgXcQ", Text: "In a transaction", AuthorHash: "a"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := s.CountComments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("count = %d, want 1", count)
+	}
+}
+
+func TestHasRecentDuplicate(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+	if _, err := s.AddComment(ctx, NewComment{VideoID: "dQw4w9WgXcQ", Text: "Buy my course", AuthorHash: "spammer"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		author string
+		text   string
+		since  time.Time
+		want   bool
+	}{
+		{"same author and text", "spammer", "Buy my course", time.Now().Add(-time.Hour), true},
+		{"other author", "someone", "Buy my course", time.Now().Add(-time.Hour), false},
+		{"other text", "spammer", "Buy my book", time.Now().Add(-time.Hour), false},
+		{"too long ago", "spammer", "Buy my course", time.Now().Add(time.Minute), false},
+	}
+	for _, test := range tests {
+		got, err := s.HasRecentDuplicate(ctx, test.author, test.text, test.since)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestFindRecentDuplicate(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+	posted := NewComment{VideoID: "dQw4w9WgXcQ", Text: "First!", AuthorHash: "clicker"}
+	if _, err := s.AddComment(ctx, posted); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := s.AddComment(ctx, NewComment{VideoID: "dQw4w9WgXcQ", Text: "Oops", AuthorHash: "clicker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteComment(ctx, deleted); err != nil {
+		t.Fatal(err)
+	}
+
+	recently := time.Now().Add(-5 * time.Minute)
+	tests := []struct {
+		name    string
+		comment NewComment
+		since   time.Time
+		want    bool
+	}{
+		{"same comment", posted, recently, true},
+		{"other video", NewComment{VideoID: "9bZkp7q19f0", Text: "First!", AuthorHash: "clicker"}, recently, false},
+		{"other author", NewComment{VideoID: "dQw4w9WgXcQ", Text: "First!", AuthorHash: "someone"}, recently, false},
+		{"deleted", NewComment{VideoID: "dQw4w9WgXcQ", Text: "Oops", AuthorHash: "clicker"}, recently, false},
+		{"too long ago", posted, time.Now().Add(time.Minute), false},
+	}
+	for _, test := range tests {
+		got, err := s.FindRecentDuplicate(ctx, test.comment, nil, test.since)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestFindRecentDuplicateReplies(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+	add := func(parent *int64, text string) int64 {
+		t.Helper()
+		comment := NewComment{VideoID: "dQw4w9WgXcQ", Text: text, AuthorHash: "a"}
+		var id int64
+		var err error
+		if parent == nil {
+			id, err = s.AddComment(ctx, comment)
+		} else {
+			id, err = s.AddReply(ctx, *parent, comment)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	first, second := add(nil, "First thread"), add(nil, "Second thread")
+	reply := add(&first, "thanks!")
+
+	recently := time.Now().Add(-5 * time.Minute)
+	thanks := NewComment{VideoID: "dQw4w9WgXcQ", Text: "thanks!", AuthorHash: "a"}
+	tests := []struct {
+		name   string
+		parent *int64
+		want   bool
+	}{
+		{"same thread", &first, true},
+		{"reply to the reply", &reply, true},
+		{"other thread", &second, false},
+		{"top level", nil, false},
+	}
+	for _, test := range tests {
+		got, err := s.FindRecentDuplicate(ctx, thanks, test.parent, recently)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	// The same top-level text doesn't count against replies either
+	add(nil, "+1")
+	if got, err := s.FindRecentDuplicate(ctx, NewComment{VideoID: "dQw4w9WgXcQ", Text: "+1", AuthorHash: "a"}, &second, recently); err != nil || got {
+		t.Errorf("reply after the same top-level text: got %v, %v, want false", got, err)
+	}
+}
+
+func TestSoftDeletedComments(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+	add := func(parent *int64, text string) int64 {
+		t.Helper()
+		comment := NewComment{VideoID: "dQw4w9WgXcQ", Text: text, AuthorHash: "a"}
+		var id int64
+		var err error
+		if parent == nil {
+			id, err = s.AddComment(ctx, comment)
+		} else {
+			id, err = s.AddReply(ctx, *parent, comment)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	parent := add(nil, "Parent")
+	add(&parent, "Live reply")
+	leaf := add(nil, "Leaf")
+	for _, id := range []int64{parent, leaf} {
+		if err := s.DeleteComment(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	comments, err := s.GetComments(ctx, "dQw4w9WgXcQ", SortOldest, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The parent stays as a placeholder for its reply, the leaf goes
+	if len(comments) != 1 || comments[0].ID != parent || comments[0].DeletedAt == nil {
+		t.Fatalf("comments = %+v, want only the deleted parent", comments)
+	}
+	replies, err := s.GetReplies(ctx, []int64{parent}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies[parent]) != 1 || replies[parent][0].Text != "Live reply" {
+		t.Errorf("replies = %+v, want the live reply", replies[parent])
+	}
+
+	// Deleting twice finds nothing
+	if err := s.DeleteComment(ctx, leaf); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestReplyToGoneParent(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+	add := func(text string) int64 {
+		t.Helper()
+		id, err := s.AddComment(ctx, NewComment{VideoID: "dQw4w9WgXcQ", Text: text, AuthorHash: "a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	deleted, hidden, live := add("Deleted"), add("Hidden"), add("Live")
+	if err := s.DeleteComment(ctx, deleted); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ReportComment(ctx, hidden, "reporter", "spam", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	reply := NewComment{VideoID: "dQw4w9WgXcQ", Text: "A reply", AuthorHash: "b"}
+	for name, parent := range map[string]int64{"deleted": deleted, "hidden": hidden, "missing": 12345} {
+		if _, err := s.AddReply(ctx, parent, reply); !errors.Is(err, ErrNotFound) {
+			t.Errorf("reply to %s parent: err = %v, want ErrNotFound", name, err)
+		}
+	}
+	if _, err := s.AddReply(ctx, live, NewComment{VideoID: "9bZkp7q19f0", Text: "A reply", AuthorHash: "b"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("reply from another video: err = %v, want ErrNotFound", err)
+	}
+	var rows int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM comments").Scan(&rows); err != nil {
+		t.Fatal(err)
+	}
+	if rows != 3 {
+		t.Errorf("%d rows, want the 3 parents and no replies", rows)
+	}
+	if _, err := s.AddReply(ctx, live, reply); err != nil {
+		t.Errorf("reply to live parent: %v", err)
+	}
+}
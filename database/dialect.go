@@ -0,0 +1,63 @@
+package database
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// The SQL flavour of the database behind a Store. Queries are written for
+// SQLite and carry over to Postgres unchanged where the two agree; the few
+// places they don't ask the dialect.
+type dialect int
+
+const (
+	sqlite dialect = iota
+	postgres
+)
+
+// Postgres for postgres:// URLs, SQLite for anything else, which is taken
+// as a file path
+func dialectOf(dsn string) dialect {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return postgres
+	}
+	return sqlite
+}
+
+// Adapt a CREATE TABLE statement written for SQLite. Postgres spells
+// AUTOINCREMENT as an identity column.
+func (d dialect) ddl(statement string) string {
+	if d == postgres {
+		return strings.ReplaceAll(statement, "INTEGER PRIMARY KEY AUTOINCREMENT", "BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY")
+	}
+	return statement
+}
+
+// Report whether err is a unique constraint failing, on table.column when
+// column is given
+func isUniqueViolation(err error, column string) bool {
+	if err == nil {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Postgres names single column constraints table_column_key
+		return pgErr.Code == "23505" && (column == "" || pgErr.ConstraintName == strings.ReplaceAll(column, ".", "_")+"_key")
+	}
+	return strings.Contains(err.Error(), "UNIQUE constraint failed") && strings.Contains(err.Error(), column)
+}
+
+// Condition matching the comments, known by the given table name or alias,
+// that contain every word of the query, with the argument it binds. Both
+// are empty when the query has no words.
+func (d dialect) textMatch(table, query string) (string, string) {
+	if len(strings.Fields(query)) == 0 {
+		return "", ""
+	}
+	if d == postgres {
+		return table + ".search @@ plainto_tsquery('simple', ?)", query
+	}
+	return table + ".id IN (SELECT rowid FROM comments_fts WHERE comments_fts MATCH ?)", matchQuery(query)
+}
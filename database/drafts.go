@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var ErrDraftNotFound = errors.New("draft not found")
+
+// Unsent comment text saved for a browser and video
+type Draft struct {
+	VideoID   string
+	Text      string
+	UpdatedAt time.Time
+}
+
+func createDraftsTable(ctx context.Context, tx *sql.Tx, d dialect) error {
+	_, err := tx.ExecContext(
+		ctx,
+		d.ddl(`CREATE TABLE IF NOT EXISTS drafts (
+            identity_hash TEXT NOT NULL,
+            video_id TEXT NOT NULL,
+            comment TEXT NOT NULL,
+            updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (identity_hash, video_id)
+        )`),
+	)
+	return err
+}
+
+// Save the draft, replacing any earlier one for the same browser and video
+func (s *Store) SaveDraft(ctx context.Context, identityHash, videoID, text string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO drafts (identity_hash, video_id, comment, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (identity_hash, video_id) DO UPDATE SET comment = excluded.comment, updated_at = excluded.updated_at`,
+		identityHash, videoID, text, now(),
+	)
+	return err
+}
+
+func (s *Store) GetDraft(ctx context.Context, identityHash, videoID string) (Draft, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var d Draft
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT video_id, comment, updated_at FROM drafts WHERE identity_hash = ? AND video_id = ?",
+		identityHash, videoID,
+	).Scan(&d.VideoID, &d.Text, &d.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Draft{}, ErrDraftNotFound
+	}
+	return d, err
+}
+
+func (s *Store) DeleteDraft(ctx context.Context, identityHash, videoID string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, "DELETE FROM drafts WHERE identity_hash = ? AND video_id = ?", identityHash, videoID)
+	return err
+}
+
+// Remove drafts not touched since the given time, returning how many went
+func (s *Store) PurgeDrafts(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM drafts WHERE updated_at < ?", formatTime(before))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+)
+
+// How much a visitor has contributed, or how much of it was erased
+type Contributions struct {
+	Comments int64
+	// Erased comments kept as "[deleted]" because others replied to them
+	Tombstoned int64
+	Votes      int64
+	Reactions  int64
+	Reports    int64
+	Drafts     int64
+}
+
+// Every comment by the author that hasn't been deleted, newest first
+func (s *Store) GetCommentsByAuthor(ctx context.Context, authorHash string) ([]Comment, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT "+commentColumns+" FROM comments WHERE author_hash = ? AND deleted_at IS NULL ORDER BY created_at DESC, id DESC",
+		authorHash,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+// Count what the visitor has left behind. Votes, reactions and reports are
+// keyed by the identity itself, comments and drafts by its hash.
+func (s *Store) CountContributions(ctx context.Context, id, idHash string) (Contributions, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var n Contributions
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT
+			(SELECT COUNT(*) FROM comments WHERE author_hash = ? AND deleted_at IS NULL),
+			(SELECT COUNT(*) FROM comment_votes WHERE voter_token = ?),
+			(SELECT COUNT(*) FROM comment_reactions WHERE reactor_token = ?),
+			(SELECT COUNT(*) FROM comment_reports WHERE reporter_token = ?),
+			(SELECT COUNT(*) FROM drafts WHERE identity_hash = ?)`,
+		idHash, id, id, id, idHash,
+	).Scan(&n.Comments, &n.Votes, &n.Reactions, &n.Reports, &n.Drafts)
+	return n, err
+}
+
+// Remove everything the visitor has contributed in one transaction. Their
+// comments that others replied to become blank tombstones so the replies
+// keep their place; the rest are deleted outright.
+func (s *Store) EraseContributions(ctx context.Context, id, idHash string) (Contributions, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var n Contributions
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return n, err
+	}
+	defer tx.Rollback()
+
+	exec := func(count *int64, query string, args ...any) error {
+		res, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if count != nil {
+			*count += affected
+		}
+		return nil
+	}
+
+	// The votes, reactions, reports and earlier text of the author's own
+	// comments go along with them
+	for _, table := range commentChildTables {
+		if err := exec(nil, "DELETE FROM "+table+" WHERE comment_id IN (SELECT id FROM comments WHERE author_hash = ?)", idHash); err != nil {
+			return n, err
+		}
+	}
+
+	// Replies go first so their parents can be checked for any left over
+	if err := exec(&n.Comments, "DELETE FROM comments WHERE author_hash = ? AND parent_id IS NOT NULL", idHash); err != nil {
+		return n, err
+	}
+	if err := exec(
+		&n.Tombstoned,
+		`UPDATE comments SET comment = '', author = NULL, author_hash = NULL, ip_hash = NULL, user_id = NULL, notify_email = NULL,
+			pinned = 0, deleted_at = COALESCE(deleted_at, ?)
+		WHERE author_hash = ? AND EXISTS (SELECT 1 FROM comments AS reply WHERE reply.parent_id = comments.id)`,
+		now(), idHash,
+	); err != nil {
+		return n, err
+	}
+	if err := exec(&n.Comments, "DELETE FROM comments WHERE author_hash = ?", idHash); err != nil {
+		return n, err
+	}
+	n.Comments += n.Tombstoned
+
+	if err := exec(&n.Votes, "DELETE FROM comment_votes WHERE voter_token = ?", id); err != nil {
+		return n, err
+	}
+	if err := exec(&n.Reactions, "DELETE FROM comment_reactions WHERE reactor_token = ?", id); err != nil {
+		return n, err
+	}
+	if err := exec(&n.Reports, "DELETE FROM comment_reports WHERE reporter_token = ?", id); err != nil {
+		return n, err
+	}
+	if err := exec(&n.Drafts, "DELETE FROM drafts WHERE identity_hash = ?", idHash); err != nil {
+		return n, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Contributions{}, err
+	}
+	return n, nil
+}
@@ -0,0 +1,29 @@
+package database
+
+import "context"
+
+// Call fn with every visible comment on a video, replies included, oldest
+// first. Rows are read one at a time so large videos don't have to fit in
+// memory.
+func (s *Store) EachComment(ctx context.Context, videoID string, fn func(Comment) error) error {
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT "+commentColumns+" FROM comments WHERE video_id = ? AND hidden = 0 AND deleted_at IS NULL AND visible_to_author_only = 0 ORDER BY id",
+		videoID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(comment); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Comments seeded across seededVideos videos, "video-0000" to "video-0999"
+const (
+	seededComments = 100_000
+	seededVideos   = 1000
+)
+
+// A store holding seededComments comments spread evenly over the videos
+func seededStore(tb testing.TB) *Store {
+	tb.Helper()
+	s := NewTestStore(tb)
+	_, err := s.db.ExecContext(context.Background(), `
+		WITH RECURSIVE n(i) AS (SELECT 0 UNION ALL SELECT i + 1 FROM n WHERE i + 1 < ?)
+		INSERT INTO comments (video_id, comment, author_hash, created_at)
+		SELECT printf('video-%04d', i % ?), 'Comment ' || i, 'author-' || (i % 5000), ?
+		FROM n`,
+		seededComments, seededVideos, now(),
+	)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return s
+}
+
+func TestListingUsesIndex(t *testing.T) {
+	if testing.Short() {
+		t.Skip("seeds 100k comments")
+	}
+	t.Parallel()
+	s := seededStore(t)
+	ctx := context.Background()
+
+	rows, err := s.db.QueryContext(ctx,
+		"EXPLAIN QUERY PLAN SELECT "+commentColumns+" FROM comments WHERE "+listedComments+" ORDER BY "+SortNewest.orderBy()+" LIMIT 20",
+		"video-0042", "",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var plan []string
+	for rows.Next() {
+		var id, parent, unused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+			t.Fatal(err)
+		}
+		plan = append(plan, detail)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(plan[0], "USING INDEX comments_video_id") {
+		t.Errorf("listing doesn't search by video ID:\n%s", strings.Join(plan, "\n"))
+	}
+
+	start := time.Now()
+	page, err := s.GetCommentsPage(ctx, "video-0042", SortNewest, 20, "", "", CommentFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("listing took %s", took)
+	}
+	if len(page.Items) != 20 {
+		t.Errorf("listed %d comments, want 20", len(page.Items))
+	}
+}
+
+func BenchmarkGetCommentsPage(b *testing.B) {
+	s := seededStore(b)
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := range b.N {
+		if _, err := s.GetCommentsPage(ctx, fmt.Sprintf("video-%04d", i%seededVideos), SortNewest, 20, "", "", CommentFilter{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+)
+
+// Number of comments written in a language
+type LanguageCount struct {
+	Lang     string
+	Comments int
+}
+
+// Visible comments per language, most common first. Comments whose language
+// hasn't been detected yet count as "und".
+func (s *Store) CountCommentsByLang(ctx context.Context) ([]LanguageCount, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT COALESCE(lang, 'und') AS code, COUNT(*) AS n FROM comments
+		WHERE hidden = 0 AND deleted_at IS NULL AND visible_to_author_only = 0
+		GROUP BY code ORDER BY n DESC, code`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []LanguageCount
+	for rows.Next() {
+		var count LanguageCount
+		if err := rows.Scan(&count.Lang, &count.Comments); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+// Comments stored before language detection, oldest first
+func (s *Store) CommentsWithoutLang(ctx context.Context, limit int) ([]Comment, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT "+commentColumns+" FROM comments WHERE lang IS NULL ORDER BY id LIMIT ?",
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanComments(rows)
+}
+
+// Store the detected language of each comment, keyed by comment ID
+func (s *Store) SetCommentLangs(ctx context.Context, langs map[int64]string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for id, lang := range langs {
+		if _, err := tx.ExecContext(ctx, "UPDATE comments SET lang = ? WHERE id = ?", lang, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"context"
+	"strings"
+)
+
+// Latest visible comment of each display name used on a video, keyed by the
+// lowercased name. Names shared by more than one author are left out, since
+// there is no telling which of them a mention means.
+func (s *Store) MentionTargets(ctx context.Context, videoID string) (map[string]int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT MIN(author), MAX(id) FROM comments
+		WHERE video_id = ? AND author IS NOT NULL AND hidden = 0 AND deleted_at IS NULL AND visible_to_author_only = 0
+		GROUP BY author COLLATE NOCASE
+		HAVING COUNT(DISTINCT COALESCE(author_hash, '')) = 1`,
+		videoID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	targets := make(map[string]int64)
+	for rows.Next() {
+		var name string
+		var id int64
+		if err := rows.Scan(&name, &id); err != nil {
+			return nil, err
+		}
+		targets[strings.ToLower(name)] = id
+	}
+	return targets, rows.Err()
+}
@@ -0,0 +1,34 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMentionTargets(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+	add := func(author, hash string) int64 {
+		t.Helper()
+		id, err := s.AddComment(ctx, NewComment{VideoID: "dQw4w9WgXcQ", Text: "Hi", Author: author, AuthorHash: hash})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	add("Ada", "ada")
+	latest := add("ada", "ada")
+	// Two authors going by the same name
+	add("Sam", "sam1")
+	add("Sam", "sam2")
+	add("", "anonymous")
+
+	targets, err := s.MentionTargets(ctx, "dQw4w9WgXcQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 1 || targets["ada"] != latest {
+		t.Errorf("targets = %v, want only ada at her latest comment %d", targets, latest)
+	}
+}
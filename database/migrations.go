@@ -0,0 +1,344 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrSchemaTooNew = errors.New("database schema is newer than this version knows")
+
+// A numbered schema change, applied once in its own transaction
+type migration struct {
+	version int
+	name    string
+	up      func(ctx context.Context, tx *sql.Tx, d dialect) error
+}
+
+// Every schema change in order. Append new ones with the next version and
+// never edit or reorder ones that have shipped.
+var migrations = []migration{
+	{1, "baseline", baseline},
+	{2, "comment lookup indexes", commentIndexes},
+	{3, "utc timestamps", utcTimestamps},
+	{4, "video comment counts", createVideoCommentCounts},
+	{5, "comment ip hashes", commentIPHashes},
+	{6, "video cache", createVideoCacheTable},
+	{7, "api quota", createAPIQuotaTable},
+}
+
+// Bring the schema up to date. Databases from before schema_migrations
+// existed count as version 0, which the baseline migration upgrades in place.
+func (s *Store) migrate(ctx context.Context) error {
+	// Migrations run on a connection of their own with SQLite's foreign keys
+	// off, as rebuilding a table drops one that other tables reference.
+	// SQLite ignores the setting inside a transaction, so it's changed
+	// around them.
+	conn, err := s.pool.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if s.dialect == sqlite {
+		if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+			return err
+		}
+		// The connection goes back to the pool afterwards
+		defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+	}
+
+	_, err = conn.ExecContext(
+		ctx,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+            version INTEGER PRIMARY KEY,
+            name TEXT NOT NULL,
+            applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        )`,
+	)
+	if err != nil {
+		return err
+	}
+
+	var current int
+	if err := conn.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current); err != nil {
+		return err
+	}
+	latest := migrations[len(migrations)-1].version
+	if current > latest {
+		return fmt.Errorf("%w: database is at version %d, this build knows up to %d", ErrSchemaTooNew, current, latest)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := apply(ctx, conn, s.dialect, m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+	}
+	return nil
+}
+
+func apply(ctx context.Context, conn *sql.Conn, d dialect, m migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.up(ctx, tx, d); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)", m.version, m.name, now()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// The schema as the old InitDB left it: tables created if missing and
+// columns added to databases from before they existed. Safe to run on a
+// fresh database and on one InitDB already set up.
+func baseline(ctx context.Context, tx *sql.Tx, d dialect) error {
+	if d == postgres {
+		if err := postgresPrelude(ctx, tx); err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.ExecContext(
+		ctx,
+		d.ddl(`CREATE TABLE IF NOT EXISTS comments (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            video_id TEXT NOT NULL,
+            comment TEXT NOT NULL,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        )`),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Columns added after the table was first created
+	columns := []struct{ name, definition string }{
+		{"delete_token_hash", "TEXT"},
+		{"edited_at", "TIMESTAMP"},
+		{"parent_id", "INTEGER REFERENCES comments(id)"},
+		{"hidden", "INTEGER NOT NULL DEFAULT 0"},
+		{"author_hash", "TEXT"},
+		{"video_time_seconds", "INTEGER"},
+		{"author", "TEXT"},
+		{"user_id", "INTEGER REFERENCES users(id)"},
+		{"visible_to_author_only", "INTEGER NOT NULL DEFAULT 0"},
+		{"deleted_at", "TIMESTAMP"},
+		{"pinned", "INTEGER NOT NULL DEFAULT 0"},
+		// Only read by the reply notifier, so it isn't part of Comment
+		{"notify_email", "TEXT"},
+		// NULL until detected, see CommentsWithoutLang
+		{"lang", "TEXT"},
+		{"video_end_seconds", "INTEGER"},
+	}
+	for _, column := range columns {
+		if err := addColumn(ctx, tx, d, "comments", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+
+	// Postgres databases never had the old schema
+	if d == sqlite {
+		if err := requireCommentText(ctx, tx); err != nil {
+			return err
+		}
+	}
+
+	// Serves listings by video and the recent duplicate check
+	_, err = tx.ExecContext(
+		ctx,
+		"CREATE INDEX IF NOT EXISTS comments_video_created ON comments (video_id, created_at)",
+	)
+	if err != nil {
+		return err
+	}
+
+	tables := []func(context.Context, *sql.Tx, dialect) error{
+		createVotesTable,
+		createReactionsTable,
+		createReportsTable,
+		createUsersTable,
+		createSessionsTable,
+		createBansTable,
+		createRevisionsTable,
+		createSearchTable,
+		createDraftsTable,
+		createVideoSettingsTable,
+		createAuditLogTable,
+		createLinkPreviewsTable,
+	}
+	for _, create := range tables {
+		if err := create(ctx, tx, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// What a fresh Postgres database needs before the tables SQLite starts with:
+// a collation matching SQLite's NOCASE for usernames and author names, and
+// the users table, which Postgres wants to exist before anything references it
+func postgresPrelude(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"CREATE COLLATION IF NOT EXISTS nocase (provider = icu, locale = 'und-u-ks-level2', deterministic = false)",
+	)
+	if err != nil {
+		return err
+	}
+	return createUsersTable(ctx, tx, postgres)
+}
+
+// Indexes for the lookups every page makes. Each one costs some disk space
+// and a little time on every insert, a fair trade on a site that lists
+// comments far more often than it takes them. Votes, reactions and reports
+// need none: their primary keys start with comment_id, which already serves
+// the score and count subqueries.
+func commentIndexes(ctx context.Context, tx *sql.Tx, d dialect) error {
+	statements := []string{
+		// Listings of a video in id order and the cursors paging through them
+		"CREATE INDEX IF NOT EXISTS comments_video_id ON comments (video_id, id)",
+		// Loading the replies under a page of comments
+		"CREATE INDEX IF NOT EXISTS comments_parent ON comments (parent_id)",
+		// An author's own comments, slow mode and the duplicate checks
+		"CREATE INDEX IF NOT EXISTS comments_author_created ON comments (author_hash, created_at)",
+		// The edit count selected with every comment
+		"CREATE INDEX IF NOT EXISTS comment_revisions_comment ON comment_revisions (comment_id)",
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Where each comment was posted from, as a keyed hash, so moderators can
+// find the other comments from the same address
+func commentIPHashes(ctx context.Context, tx *sql.Tx, d dialect) error {
+	if err := addColumn(ctx, tx, d, "comments", "ip_hash", "TEXT"); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS comments_ip_hash ON comments (ip_hash)")
+	return err
+}
+
+// Every column holding a time
+var timeColumns = []struct{ table, column string }{
+	{"comments", "created_at"},
+	{"comments", "edited_at"},
+	{"comments", "deleted_at"},
+	{"comment_reports", "created_at"},
+	{"comment_revisions", "created_at"},
+	{"users", "created_at"},
+	{"sessions", "created_at"},
+	{"sessions", "expires_at"},
+	{"bans", "created_at"},
+	{"bans", "expires_at"},
+	{"drafts", "updated_at"},
+	{"audit_log", "created_at"},
+	{"link_previews", "fetched_at"},
+	{"schema_migrations", "applied_at"},
+}
+
+// SQLite keeps times as text, which used to be CURRENT_TIMESTAMP's
+// "2006-01-02 15:04:05" in UTC. Rewrite them as the RFC 3339 that Go now
+// writes, since the two forms don't compare correctly with each other.
+// Postgres parsed both into real timestamps, so it has nothing to do.
+func utcTimestamps(ctx context.Context, tx *sql.Tx, d dialect) error {
+	if d == postgres {
+		return nil
+	}
+	for _, c := range timeColumns {
+		stored := "strftime('%Y-%m-%dT%H:%M:%SZ', " + c.column + ")"
+		_, err := tx.ExecContext(
+			ctx,
+			fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s IS NOT NULL AND %s <> %s", c.table, c.column, stored, stored, c.column, stored),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Databases created before comment text was NOT NULL get the constraint by
+// rebuilding the table, since SQLite can't alter an existing column
+func requireCommentText(ctx context.Context, tx *sql.Tx) error {
+	var notNull bool
+	err := tx.QueryRowContext(
+		ctx,
+		"SELECT \"notnull\" FROM pragma_table_info('comments') WHERE name = 'comment'",
+	).Scan(&notNull)
+	if err != nil || notNull {
+		return err
+	}
+
+	var schema string
+	if err := tx.QueryRowContext(ctx, "SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'comments'").Scan(&schema); err != nil {
+		return err
+	}
+	rebuilt := strings.Replace(schema, "comment TEXT,", "comment TEXT NOT NULL,", 1)
+	rebuilt = strings.Replace(rebuilt, "CREATE TABLE comments", "CREATE TABLE comments_rebuilt", 1)
+	if rebuilt == schema {
+		return fmt.Errorf("unexpected comments schema: %s", schema)
+	}
+
+	statements := []string{
+		rebuilt,
+		"INSERT INTO comments_rebuilt SELECT * FROM comments WHERE comment IS NOT NULL",
+		"DROP TABLE comments",
+		"ALTER TABLE comments_rebuilt RENAME TO comments",
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Add a column to an existing table unless it is already there
+func addColumn(ctx context.Context, tx *sql.Tx, d dialect, table, column, definition string) error {
+	if d == postgres {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s", table, column, definition))
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, colType    string
+			defaultValue     sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition),
+	)
+	return err
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// The migrations a database has had applied, by version
+func appliedMigrations(t *testing.T, s *Store) []int {
+	t.Helper()
+	rows, err := s.db.QueryContext(context.Background(), "SELECT version FROM schema_migrations ORDER BY version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var versions []int
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return versions
+}
+
+func checkFullyMigrated(t *testing.T, s *Store) {
+	t.Helper()
+	versions := appliedMigrations(t, s)
+	if len(versions) != len(migrations) {
+		t.Fatalf("applied %v, want all %d migrations", versions, len(migrations))
+	}
+	for i, m := range migrations {
+		if versions[i] != m.version {
+			t.Errorf("applied %v, want the versions in order", versions)
+			break
+		}
+	}
+}
+
+// Write SQL to a new SQLite file the way an older build would have
+func writeFixture(t *testing.T, path string, statements ...string) {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range statements {
+		if _, err := db.ExecContext(context.Background(), stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMigrateFresh(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "comments.db")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFullyMigrated(t, s)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Opening again applies nothing twice
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	checkFullyMigrated(t, s)
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "comments.db")
+	// The table InitDB made, with a comment posted back then
+	writeFixture(t, path,
+		`CREATE TABLE IF NOT EXISTS comments (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            video_id TEXT NOT NULL,
+            comment TEXT,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        )`,
+		`INSERT INTO comments (video_id, comment, created_at) VALUES ('dQw4w9WgXcQ', 'From before', '2023-01-02 03:04:05')`,
+	)
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	checkFullyMigrated(t, s)
+
+	ctx := context.Background()
+	comments, err := s.GetComments(ctx, "dQw4w9WgXcQ", SortNewest, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 1 || comments[0].Text != "From before" || comments[0].CreatedAt.Year() != 2023 {
+		t.Fatalf("comments = %+v, want the old one", comments)
+	}
+	// And the new columns work on it
+	if _, err := s.AddReply(ctx, comments[0].ID, NewComment{VideoID: "dQw4w9WgXcQ", Text: "Replying to history", AuthorHash: "a"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "comments.db")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := migrations[len(migrations)-1].version + 1
+	if _, err := s.db.ExecContext(context.Background(), "INSERT INTO schema_migrations (version, name) VALUES (?, 'from the future')", next); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if s, err := Open(path); !errors.Is(err, ErrSchemaTooNew) {
+		if err == nil {
+			s.Close()
+		}
+		t.Errorf("err = %v, want ErrSchemaTooNew", err)
+	}
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// Address to tell about replies to a comment, or "" when nobody asked
+func (s *Store) GetNotifyEmail(ctx context.Context, commentID int64) (string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var email sql.NullString
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT notify_email FROM comments WHERE id = ? AND deleted_at IS NULL",
+		commentID,
+	).Scan(&email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return email.String, err
+}
+
+// Stop sending reply notifications for a comment
+func (s *Store) ClearNotifyEmail(ctx context.Context, commentID int64) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, "UPDATE comments SET notify_email = NULL WHERE id = ?", commentID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package database
+
+import "context"
+
+// Keep a long-running database in shape: fold the write-ahead log back into
+// the database file, refresh the statistics the query planner relies on and
+// return free pages to the filesystem. Free pages are only returned for
+// databases created with incremental auto-vacuum, which older ones weren't.
+// Postgres vacuums itself, so there only the statistics are refreshed.
+func (s *Store) Optimize(ctx context.Context) error {
+	statements := []string{"PRAGMA wal_checkpoint(TRUNCATE)", "ANALYZE", "PRAGMA incremental_vacuum"}
+	if s.dialect == postgres {
+		statements = []string{"ANALYZE"}
+	}
+	for _, statement := range statements {
+		if _, err := s.db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
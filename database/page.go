@@ -0,0 +1,69 @@
+package database
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+// One page of a keyset-paginated listing
+type Page[T any] struct {
+	Items []T
+	// Opaque cursor that loads the page after this one, empty on the last
+	NextCursor string
+	HasMore    bool
+}
+
+// Trim rows fetched with one to spare down to limit, keeping the sort key of
+// the last one kept as the cursor for the rest
+func newPage[T any](rows []T, limit int, key func(T) []int64) Page[T] {
+	page := Page[T]{Items: rows}
+	if len(rows) > limit {
+		page.Items = rows[:limit]
+		page.HasMore = true
+		page.NextCursor = encodeCursor(key(rows[limit-1])...)
+	}
+	return page
+}
+
+// Cursors carry the sort key of the last row on a page rather than just its
+// ID, so the next page still starts in the right place when that row has
+// since been deleted, approved or re-scored
+func encodeCursor(key ...int64) string {
+	parts := make([]string, len(key))
+	for i, k := range key {
+		parts[i] = strconv.FormatInt(k, 10)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(parts, ".")))
+}
+
+// The n numbers in a cursor, or false when it is empty or wasn't made by
+// encodeCursor for a key that long, which callers treat as the first page.
+// A plain ID, as older links carry, is accepted for one-number keys.
+func decodeCursor(cursor string, n int) ([]int64, bool) {
+	if cursor == "" {
+		return nil, false
+	}
+	raw := cursor
+	if _, err := strconv.ParseUint(cursor, 10, 63); err != nil || n != 1 {
+		decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, false
+		}
+		raw = string(decoded)
+	}
+
+	parts := strings.Split(raw, ".")
+	if len(parts) != n {
+		return nil, false
+	}
+	key := make([]int64, n)
+	for i, part := range parts {
+		k, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		key[i] = k
+	}
+	return key, true
+}
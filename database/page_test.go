@@ -0,0 +1,154 @@
+package database
+
+import (
+	"context"
+	"encoding/base64"
+	"reflect"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	t.Parallel()
+	for _, key := range [][]int64{{1}, {42, -7}, {1, 1700000000, 99}} {
+		got, ok := decodeCursor(encodeCursor(key...), len(key))
+		if !ok || !reflect.DeepEqual(got, key) {
+			t.Errorf("key %v came back as %v, %v", key, got, ok)
+		}
+	}
+	if got, ok := decodeCursor("17", 1); !ok || got[0] != 17 {
+		t.Errorf("plain ID decoded as %v, %v", got, ok)
+	}
+}
+
+func TestMalformedCursors(t *testing.T) {
+	t.Parallel()
+	for _, cursor := range []string{
+		"",
+		"!!!",
+		"'; DROP TABLE comments; --",
+		base64.RawURLEncoding.EncodeToString([]byte("1.2.3")),
+		base64.RawURLEncoding.EncodeToString([]byte("1.x")),
+		encodeCursor(5),
+	} {
+		if key, ok := decodeCursor(cursor, 2); ok {
+			t.Errorf("decodeCursor(%q) = %v, want the first page", cursor, key)
+		}
+	}
+}
+
+// Seed n top-level comments on one video, returning their IDs
+func seedComments(t *testing.T, s *Store, n int) []int64 {
+	t.Helper()
+	ids := make([]int64, n)
+	for i := range ids {
+		id, err := s.AddComment(context.Background(), NewComment{VideoID: "dQw4w9WgXcQ", Text: "Comment", AuthorHash: "a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = id
+	}
+	return ids
+}
+
+// Page through a video's comments, calling between after each page, and
+// return the IDs in the order they were listed
+func pageThrough(t *testing.T, s *Store, sort SortOrder, between func()) []int64 {
+	t.Helper()
+	var listed []int64
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatal("paging never ended")
+		}
+		page, err := s.GetCommentsPage(context.Background(), "dQw4w9WgXcQ", sort, 10, cursor, "", CommentFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range page.Items {
+			listed = append(listed, c.ID)
+		}
+		if !page.HasMore {
+			return listed
+		}
+		cursor = page.NextCursor
+		between()
+	}
+}
+
+func TestPagingSkipsAndRepeatsNothing(t *testing.T) {
+	t.Parallel()
+	for _, sort := range []SortOrder{SortNewest, SortOldest, SortTop} {
+		s := NewTestStore(t)
+		ctx := context.Background()
+		seeded := seedComments(t, s, 53)
+		for i, id := range seeded[:20] {
+			if err := s.Vote(ctx, id, "voter", 1-2*(i%2)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Comments keep being posted while the reader pages
+		listed := pageThrough(t, s, sort, func() { seedComments(t, s, 2) })
+		seen := make(map[int64]bool)
+		for _, id := range listed {
+			if seen[id] {
+				t.Errorf("sort %d: comment %d listed twice", sort, id)
+			}
+			seen[id] = true
+		}
+		for _, id := range seeded {
+			if !seen[id] {
+				t.Errorf("sort %d: comment %d skipped", sort, id)
+			}
+		}
+	}
+}
+
+func TestMalformedCursorGivesFirstPage(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+	seedComments(t, s, 15)
+	first, err := s.GetCommentsPage(ctx, "dQw4w9WgXcQ", SortNewest, 10, "", "", CommentFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cursor := range []string{"'; DROP TABLE comments; --", "bm90IGEga2V5"} {
+		page, err := s.GetCommentsPage(ctx, "dQw4w9WgXcQ", SortNewest, 10, cursor, "", CommentFilter{})
+		if err != nil {
+			t.Fatalf("cursor %q: %v", cursor, err)
+		}
+		if !reflect.DeepEqual(page, first) {
+			t.Errorf("cursor %q didn't give the first page", cursor)
+		}
+	}
+}
+
+func TestRecentCommentsPaging(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+	seeded := seedComments(t, s, 25)
+
+	seen := make(map[int64]bool)
+	cursor := ""
+	for {
+		page, err := s.RecentComments(ctx, 10, cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range page.Items {
+			if seen[c.ID] {
+				t.Errorf("comment %d listed twice", c.ID)
+			}
+			seen[c.ID] = true
+		}
+		if !page.HasMore {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if len(seen) != len(seeded) {
+		t.Errorf("listed %d comments, want %d", len(seen), len(seeded))
+	}
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+)
+
+var (
+	ErrPinLimit      = errors.New("too many pinned comments on this video")
+	ErrPinNotAllowed = errors.New("only top-level comments can be pinned")
+)
+
+// Pin a top-level comment to the top of its video, allowing at most limit
+// pinned comments per video
+func (s *Store) PinComment(ctx context.Context, id int64, limit int, audit AuditEntry) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	comment, err := scanComment(tx.QueryRowContext(ctx, "SELECT "+commentColumns+" FROM comments WHERE id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if comment.DeletedAt != nil {
+		return ErrNotFound
+	}
+	if comment.ParentID != nil {
+		return ErrPinNotAllowed
+	}
+	if comment.Pinned {
+		return nil
+	}
+
+	var pinned int
+	if err := tx.QueryRowContext(
+		ctx,
+		"SELECT COUNT(*) FROM comments WHERE video_id = ? AND pinned = 1 AND deleted_at IS NULL",
+		comment.VideoID,
+	).Scan(&pinned); err != nil {
+		return err
+	}
+	if pinned >= limit {
+		return ErrPinLimit
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE comments SET pinned = 1 WHERE id = ?", id); err != nil {
+		return err
+	}
+	audit.Action, audit.TargetType, audit.TargetID = "pin", "comment", strconv.FormatInt(id, 10)
+	if err := audit.write(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) UnpinComment(ctx context.Context, id int64, audit AuditEntry) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "UPDATE comments SET pinned = 0 WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	audit.Action, audit.TargetType, audit.TargetID = "unpin", "comment", strconv.FormatInt(id, 10)
+	if err := audit.write(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
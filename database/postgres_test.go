@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRebind(t *testing.T) {
+	t.Parallel()
+	tests := []struct{ query, want string }{
+		{"SELECT 1", "SELECT 1"},
+		{"SELECT * FROM comments WHERE id = ? AND video_id = ?", "SELECT * FROM comments WHERE id = $1 AND video_id = $2"},
+		{"SELECT '?' FROM comments WHERE id = ?", "SELECT '?' FROM comments WHERE id = $1"},
+		{`SELECT "odd?column" FROM t WHERE a = ?`, `SELECT "odd?column" FROM t WHERE a = $1`},
+		{"SELECT a -- why?\nFROM t WHERE a = ?", "SELECT a -- why?\nFROM t WHERE a = $1"},
+		{"SELECT 'unterminated ?", "SELECT 'unterminated ?"},
+	}
+	for _, test := range tests {
+		if got := rebind(test.query); got != test.want {
+			t.Errorf("rebind(%q) = %q, want %q", test.query, got, test.want)
+		}
+	}
+}
+
+func TestIntFlags(t *testing.T) {
+	t.Parallel()
+	args := intFlags([]driver.NamedValue{{Value: true}, {Value: false}, {Value: "true"}, {Value: int64(7)}})
+	want := []driver.Value{int64(1), int64(0), "true", int64(7)}
+	for i := range want {
+		if args[i].Value != want[i] {
+			t.Errorf("arg %d = %#v, want %#v", i, args[i].Value, want[i])
+		}
+	}
+}
+
+func TestDialect(t *testing.T) {
+	t.Parallel()
+	for dsn, want := range map[string]dialect{
+		"postgres://localhost/comments":   postgres,
+		"postgresql://localhost/comments": postgres,
+		"./data":                          sqlite,
+		":memory:":                        sqlite,
+	} {
+		if got := dialectOf(dsn); got != want {
+			t.Errorf("dialectOf(%q) = %v, want %v", dsn, got, want)
+		}
+	}
+
+	table := "CREATE TABLE t (id INTEGER PRIMARY KEY AUTOINCREMENT)"
+	if got := sqlite.ddl(table); got != table {
+		t.Errorf("SQLite DDL changed to %q", got)
+	}
+	if got := postgres.ddl(table); !strings.Contains(got, "BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY") {
+		t.Errorf("Postgres DDL = %q", got)
+	}
+}
+
+// Runs against the database at TEST_POSTGRES_URL, which should be a scratch
+// one as the tables are created in it
+func TestPostgres(t *testing.T) {
+	t.Parallel()
+	dsn := os.Getenv("TEST_POSTGRES_URL")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_URL not set")
+	}
+	s, err := Open(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	// A video of its own, so earlier runs' comments don't show up
+	videoID := fmt.Sprintf("pg%09d", time.Now().UnixNano()%1e9)
+	id, err := s.AddComment(ctx, NewComment{VideoID: videoID, Text: "From Postgres", AuthorHash: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddReply(ctx, id, NewComment{VideoID: videoID, Text: "Replying", AuthorHash: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Vote(ctx, id, "voter", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := s.GetCommentsPage(ctx, videoID, SortTop, 10, "", "", CommentFilter{Query: "postgres"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != id || page.Items[0].Score != 1 {
+		t.Errorf("page = %+v, want the voted comment", page.Items)
+	}
+}
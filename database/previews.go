@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Title and description of a page linked from a comment
+type LinkPreview struct {
+	URL         string
+	Title       string
+	Description string
+}
+
+func createLinkPreviewsTable(ctx context.Context, tx *sql.Tx, d dialect) error {
+	_, err := tx.ExecContext(
+		ctx,
+		d.ddl(`CREATE TABLE IF NOT EXISTS link_previews (
+            url TEXT PRIMARY KEY,
+            title TEXT NOT NULL DEFAULT '',
+            description TEXT NOT NULL DEFAULT '',
+            -- 0 when the page couldn't be previewed, so it isn't fetched again
+            ok INTEGER NOT NULL,
+            fetched_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        )`),
+	)
+	return err
+}
+
+// Report whether the URL was fetched, successfully or not, since the given time
+func (s *Store) LinkPreviewFetchedSince(ctx context.Context, url string, since time.Time) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var fetched bool
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT EXISTS(SELECT 1 FROM link_previews WHERE url = ? AND fetched_at >= ?)",
+		url, formatTime(since),
+	).Scan(&fetched)
+	return fetched, err
+}
+
+// Store the preview of a URL, or with ok false that it has none
+func (s *Store) SaveLinkPreview(ctx context.Context, preview LinkPreview, ok bool) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO link_previews (url, title, description, ok, fetched_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (url) DO UPDATE SET title = excluded.title, description = excluded.description,
+			ok = excluded.ok, fetched_at = excluded.fetched_at`,
+		preview.URL, preview.Title, preview.Description, ok, now(),
+	)
+	return err
+}
+
+// Previews available for the given URLs, keyed by URL. URLs without one
+// are left out.
+func (s *Store) GetLinkPreviews(ctx context.Context, urls []string) (map[string]LinkPreview, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	previews := make(map[string]LinkPreview)
+	if len(urls) == 0 {
+		return previews, nil
+	}
+
+	args := make([]any, 0, len(urls))
+	for _, url := range urls {
+		args = append(args, url)
+	}
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT url, title, description FROM link_previews WHERE ok = 1 AND url IN ("+placeholders(len(urls))+")",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p LinkPreview
+		if err := rows.Scan(&p.URL, &p.Title, &p.Description); err != nil {
+			return nil, err
+		}
+		previews[p.URL] = p
+	}
+	return previews, rows.Err()
+}
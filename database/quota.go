@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// api_quota keeps the estimated YouTube API units spent each day, by the
+// API's own day, so a restart doesn't forget them
+func createAPIQuotaTable(ctx context.Context, tx *sql.Tx, d dialect) error {
+	_, err := tx.ExecContext(
+		ctx,
+		d.ddl(`CREATE TABLE IF NOT EXISTS api_quota (
+            day TEXT PRIMARY KEY,
+            units INTEGER NOT NULL
+        )`),
+	)
+	return err
+}
+
+// Add units to the day's estimate, returning the new total
+func (s *Store) AddQuotaUsage(ctx context.Context, day string, units int) (int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var total int
+	err := s.db.QueryRowContext(
+		ctx,
+		`INSERT INTO api_quota (day, units) VALUES (?, ?)
+		ON CONFLICT (day) DO UPDATE SET units = api_quota.units + excluded.units
+		RETURNING units`,
+		day, units,
+	).Scan(&total)
+	return total, err
+}
+
+// Units estimated to have been spent on the day, 0 when none were
+func (s *Store) QuotaUsage(ctx context.Context, day string) (int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var units int
+	err := s.db.QueryRowContext(ctx, "SELECT units FROM api_quota WHERE day = ?", day).Scan(&units)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return units, err
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+func createReactionsTable(ctx context.Context, tx *sql.Tx, d dialect) error {
+	_, err := tx.ExecContext(
+		ctx,
+		d.ddl(`CREATE TABLE IF NOT EXISTS comment_reactions (
+            comment_id INTEGER NOT NULL REFERENCES comments(id),
+            reactor_token TEXT NOT NULL,
+            emoji TEXT NOT NULL,
+            PRIMARY KEY (comment_id, reactor_token, emoji)
+        )`),
+	)
+	return err
+}
+
+// Add the reaction, or remove it if the reactor already left the same one.
+// Reports whether the reaction is now present.
+func (s *Store) ToggleReaction(ctx context.Context, commentID int64, reactorToken, emoji string) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if comment, err := s.GetComment(ctx, commentID); err != nil {
+		return false, err
+	} else if comment.DeletedAt != nil {
+		return false, ErrNotFound
+	}
+
+	res, err := s.db.ExecContext(
+		ctx,
+		"DELETE FROM comment_reactions WHERE comment_id = ? AND reactor_token = ? AND emoji = ?",
+		commentID, reactorToken, emoji,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n > 0 {
+		return false, nil
+	}
+
+	_, err = s.db.ExecContext(
+		ctx,
+		"INSERT INTO comment_reactions (comment_id, reactor_token, emoji) VALUES (?, ?, ?) ON CONFLICT DO NOTHING",
+		commentID, reactorToken, emoji,
+	)
+	return err == nil, err
+}
+
+// Reaction counts per emoji for each of the given comments
+func (s *Store) GetReactionCounts(ctx context.Context, commentIDs []int64) (map[int64]map[string]int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	counts := make(map[int64]map[string]int)
+	if len(commentIDs) == 0 {
+		return counts, nil
+	}
+
+	args := make([]any, len(commentIDs))
+	for i, id := range commentIDs {
+		args[i] = id
+	}
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT comment_id, emoji, COUNT(*) FROM comment_reactions
+		WHERE comment_id IN (`+placeholders(len(commentIDs))+`)
+		GROUP BY comment_id, emoji`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			commentID int64
+			emoji     string
+			count     int
+		)
+		if err := rows.Scan(&commentID, &emoji, &count); err != nil {
+			return nil, err
+		}
+		if counts[commentID] == nil {
+			counts[commentID] = make(map[string]int)
+		}
+		counts[commentID][emoji] = count
+	}
+	return counts, rows.Err()
+}
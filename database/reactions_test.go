@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestToggleReaction(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+	id, err := s.AddComment(ctx, NewComment{VideoID: "dQw4w9WgXcQ", Text: "Nice", AuthorHash: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []bool{true, false, true} {
+		present, err := s.ToggleReaction(ctx, id, "reactor", "👍")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if present != want {
+			t.Errorf("toggle %d: present = %v, want %v", i+1, present, want)
+		}
+	}
+	if _, err := s.ToggleReaction(ctx, id, "someone else", "👍"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ToggleReaction(ctx, id, "reactor", "😂"); err != nil {
+		t.Fatal(err)
+	}
+
+	counts, err := s.GetReactionCounts(ctx, []int64{id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts[id]["👍"] != 2 || counts[id]["😂"] != 1 {
+		t.Errorf("counts = %v, want 2 👍 and 1 😂", counts[id])
+	}
+}
+
+func TestToggleReactionMissingComment(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	if _, err := s.ToggleReaction(context.Background(), 42, "reactor", "👍"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,193 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+)
+
+var ErrAlreadyReported = errors.New("comment already reported")
+
+type ReportedComment struct {
+	Comment
+	Reports int
+}
+
+func createReportsTable(ctx context.Context, tx *sql.Tx, d dialect) error {
+	_, err := tx.ExecContext(
+		ctx,
+		d.ddl(`CREATE TABLE IF NOT EXISTS comment_reports (
+            comment_id INTEGER NOT NULL REFERENCES comments(id),
+            reporter_token TEXT NOT NULL,
+            reason TEXT NOT NULL,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (comment_id, reporter_token)
+        )`),
+	)
+	return err
+}
+
+// File a report against a comment and hide the comment once it has at least
+// threshold reports. Reports whether the comment is now hidden.
+func (s *Store) ReportComment(ctx context.Context, commentID int64, reporterToken, reason string, threshold int) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO comment_reports (comment_id, reporter_token, reason, created_at)
+		SELECT id, ?, ?, ? FROM comments WHERE id = ? AND deleted_at IS NULL`,
+		reporterToken, reason, now(), commentID,
+	)
+	if err != nil {
+		if isUniqueViolation(err, "") {
+			return false, ErrAlreadyReported
+		}
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		return false, ErrNotFound
+	}
+
+	res, err = tx.ExecContext(
+		ctx,
+		`UPDATE comments SET hidden = 1
+		WHERE id = ? AND (SELECT COUNT(*) FROM comment_reports WHERE comment_id = ?) >= ?`,
+		commentID, commentID, threshold,
+	)
+	if err != nil {
+		return false, err
+	}
+	hidden, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return hidden > 0, tx.Commit()
+}
+
+const reportCount = "(SELECT COUNT(*) FROM comment_reports WHERE comment_id = comments.id)"
+
+// A page of the comments that have been reported or hidden, hidden and most
+// reported first, continuing from the cursor when it is valid. Comments
+// approved while the queue is paged through don't move where pages start.
+func (s *Store) ListReportedComments(ctx context.Context, limit int, cursor string) (Page[ReportedComment], error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	after, args := "", []any{}
+	if key, ok := decodeCursor(cursor, 3); ok {
+		after = " AND (hidden, " + reportCount + ", id) < (?, ?, ?)"
+		args = append(args, key[0], key[1], key[2])
+	}
+	// One extra row tells whether there is another page
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT `+commentColumns+`, `+reportCount+` AS reports
+		FROM comments
+		WHERE deleted_at IS NULL
+		AND (hidden = 1 OR EXISTS (SELECT 1 FROM comment_reports WHERE comment_id = comments.id))`+after+`
+		ORDER BY hidden DESC, reports DESC, id DESC
+		LIMIT ?`,
+		append(args, limit+1)...,
+	)
+	if err != nil {
+		return Page[ReportedComment]{}, err
+	}
+	defer rows.Close()
+
+	var reported []ReportedComment
+	for rows.Next() {
+		var r ReportedComment
+		r.Comment, err = scanComment(rows, &r.Reports)
+		if err != nil {
+			return Page[ReportedComment]{}, err
+		}
+		reported = append(reported, r)
+	}
+	if err := rows.Err(); err != nil {
+		return Page[ReportedComment]{}, err
+	}
+	return newPage(reported, limit, func(r ReportedComment) []int64 {
+		var hidden int64
+		if r.Hidden {
+			hidden = 1
+		}
+		return []int64{hidden, int64(r.Reports), r.ID}
+	}), nil
+}
+
+// Comments posted from the IP address with the given hash, hidden ones
+// included, newest first
+func (s *Store) CommentsByIPHash(ctx context.Context, ipHash string, limit int) ([]Comment, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT "+commentColumns+" FROM comments WHERE ip_hash = ? AND deleted_at IS NULL ORDER BY created_at DESC, id DESC LIMIT ?",
+		ipHash, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+// Make a comment public again and discard the reports against it
+func (s *Store) ApproveComment(ctx context.Context, id int64, audit AuditEntry) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := approveComment(ctx, tx, id, audit); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func approveComment(ctx context.Context, tx queryer, id int64, audit AuditEntry) error {
+	res, err := tx.ExecContext(ctx, "UPDATE comments SET hidden = 0 WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM comment_reports WHERE comment_id = ?", id); err != nil {
+		return err
+	}
+	audit.Action, audit.TargetType, audit.TargetID = "approve", "comment", strconv.FormatInt(id, 10)
+	return audit.write(ctx, tx)
+}
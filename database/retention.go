@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// Tables whose rows belong to a single comment
+var commentChildTables = []string{"comment_votes", "comment_reactions", "comment_reports", "comment_revisions"}
+
+// Delete comments posted before the given time, along with their votes,
+// reactions, reports and revisions. Replies go with the comment they answer,
+// however new they are. Rows are removed batchSize comments at a time so no
+// transaction holds the database for long. Returns how many rows went.
+func (s *Store) PurgeComments(ctx context.Context, before time.Time, batchSize int) (int64, error) {
+	var total int64
+	for {
+		n, err := s.purgeCommentBatch(ctx, formatTime(before), batchSize)
+		if err != nil {
+			return total, err
+		}
+		if n == 0 {
+			return total, nil
+		}
+		total += n
+	}
+}
+
+func (s *Store) purgeCommentBatch(ctx context.Context, before string, batchSize int) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`WITH RECURSIVE doomed(id) AS (
+			SELECT id FROM (SELECT id FROM comments WHERE created_at < ? ORDER BY id LIMIT ?) AS oldest
+			UNION
+			SELECT comments.id FROM comments JOIN doomed ON comments.parent_id = doomed.id
+		)
+		SELECT id FROM doomed`,
+		before, batchSize,
+	)
+	if err != nil {
+		return 0, err
+	}
+	var args []any
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		args = append(args, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(args) == 0 {
+		return 0, nil
+	}
+
+	var removed int64
+	in := "(" + placeholders(len(args)) + ")"
+	for _, table := range commentChildTables {
+		res, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE comment_id IN "+in, args...)
+		if err != nil {
+			return 0, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		removed += n
+	}
+	res, err := tx.ExecContext(ctx, "DELETE FROM comments WHERE id IN "+in, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	removed += n
+	return removed, tx.Commit()
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Text a comment had before one of its edits
+type Revision struct {
+	ID        int64
+	CommentID int64
+	Text      string
+	// When the text was replaced
+	CreatedAt time.Time
+}
+
+func createRevisionsTable(ctx context.Context, tx *sql.Tx, d dialect) error {
+	_, err := tx.ExecContext(
+		ctx,
+		d.ddl(`CREATE TABLE IF NOT EXISTS comment_revisions (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            comment_id INTEGER NOT NULL REFERENCES comments(id),
+            comment TEXT NOT NULL,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        )`),
+	)
+	return err
+}
+
+// Earlier versions of a comment, oldest first
+func (s *Store) ListRevisions(ctx context.Context, commentID int64) ([]Revision, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT id, comment_id, comment, created_at FROM comment_revisions WHERE comment_id = ? ORDER BY id",
+		commentID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []Revision
+	for rows.Next() {
+		var r Revision
+		if err := rows.Scan(&r.ID, &r.CommentID, &r.Text, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, rows.Err()
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestEditsKeepRevisionsInOrder(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+	id, err := s.AddComment(ctx, NewComment{VideoID: "dQw4w9WgXcQ", Text: "First", AuthorHash: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateComment(ctx, id, "Second", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateComment(ctx, id, "Third", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	revisions, err := s.ListRevisions(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 || revisions[0].Text != "First" || revisions[1].Text != "Second" {
+		t.Fatalf("revisions = %+v, want First then Second", revisions)
+	}
+	comment, err := s.GetComment(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment.Text != "Third" || comment.EditCount != 2 || comment.EditedAt == nil {
+		t.Errorf("comment = %q edited %d times at %v, want Third edited twice", comment.Text, comment.EditCount, comment.EditedAt)
+	}
+}
+
+func TestUpdateMissingComment(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	if err := s.UpdateComment(context.Background(), 42, "Text", ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestHardDeleteRemovesRevisions(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+	id, err := s.AddComment(ctx, NewComment{VideoID: "dQw4w9WgXcQ", Text: "First", AuthorHash: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateComment(ctx, id, "Second", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.HardDeleteComment(ctx, id, AuditEntry{Actor: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+	revisions, err := s.ListRevisions(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 0 {
+		t.Errorf("%d revisions left after deleting the comment", len(revisions))
+	}
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Markers snippet() puts around matched terms, chosen so they can't clash
+// with comment text and are easy to swap for HTML after escaping
+const (
+	MatchStart = "\x02"
+	MatchEnd   = "\x03"
+)
+
+// A comment matching a search, with an excerpt around the matched terms
+type SearchResult struct {
+	Comment
+	Snippet string
+}
+
+// The comments_fts index mirrors comment text through triggers. The first
+// time it is created it is filled from the existing comments. Postgres keeps
+// the words of each comment in a generated column instead.
+func createSearchTable(ctx context.Context, tx *sql.Tx, d dialect) error {
+	if d == postgres {
+		return createPostgresSearch(ctx, tx)
+	}
+
+	var exists bool
+	if err := tx.QueryRowContext(
+		ctx,
+		"SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'comments_fts')",
+	).Scan(&exists); err != nil {
+		return err
+	}
+
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS comments_fts USING fts5(
+            comment, content='comments', content_rowid='id'
+        )`,
+		`CREATE TRIGGER IF NOT EXISTS comments_fts_insert AFTER INSERT ON comments BEGIN
+            INSERT INTO comments_fts(rowid, comment) VALUES (new.id, new.comment);
+        END`,
+		`CREATE TRIGGER IF NOT EXISTS comments_fts_delete AFTER DELETE ON comments BEGIN
+            INSERT INTO comments_fts(comments_fts, rowid, comment) VALUES ('delete', old.id, old.comment);
+        END`,
+		`CREATE TRIGGER IF NOT EXISTS comments_fts_update AFTER UPDATE OF comment ON comments BEGIN
+            INSERT INTO comments_fts(comments_fts, rowid, comment) VALUES ('delete', old.id, old.comment);
+            INSERT INTO comments_fts(rowid, comment) VALUES (new.id, new.comment);
+        END`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	if exists {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, rebuildSearchIndex)
+	return err
+}
+
+const rebuildSearchIndex = "INSERT INTO comments_fts(comments_fts) VALUES ('rebuild')"
+
+// The 'simple' configuration splits words without stemming them, like FTS5's
+// default tokenizer
+func createPostgresSearch(ctx context.Context, tx *sql.Tx) error {
+	statements := []string{
+		"ALTER TABLE comments ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (to_tsvector('simple', comment)) STORED",
+		"CREATE INDEX IF NOT EXISTS comments_search ON comments USING GIN (search)",
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Re-index every comment from scratch
+func (s *Store) RebuildSearchIndex(ctx context.Context) error {
+	statement := rebuildSearchIndex
+	if s.dialect == postgres {
+		statement = "REINDEX INDEX comments_search"
+	}
+	_, err := s.db.ExecContext(ctx, statement)
+	return err
+}
+
+// Visible comments across all videos containing every word of the query,
+// best matches first. Deleted, hidden and shadowbanned comments are left out.
+func (s *Store) SearchComments(ctx context.Context, query string, limit, offset int) ([]SearchResult, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	match := matchQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+
+	search := `SELECT ` + commentColumns + `, hits.snippet FROM comments
+		JOIN (
+			SELECT rowid, rank, snippet(comments_fts, 0, ?, ?, '…', 16) AS snippet
+			FROM comments_fts WHERE comments_fts MATCH ?
+		) AS hits ON hits.rowid = comments.id
+		WHERE hidden = 0 AND deleted_at IS NULL AND visible_to_author_only = 0
+		ORDER BY hits.rank, id DESC LIMIT ? OFFSET ?`
+	args := []any{MatchStart, MatchEnd, match, limit, offset}
+	if s.dialect == postgres {
+		search = `SELECT ` + commentColumns + `, ts_headline('simple', comment, hits.query, ?) FROM comments
+			CROSS JOIN plainto_tsquery('simple', ?) AS hits(query)
+			WHERE search @@ hits.query AND hidden = 0 AND deleted_at IS NULL AND visible_to_author_only = 0
+			ORDER BY ts_rank(search, hits.query) DESC, id DESC LIMIT ? OFFSET ?`
+		options := fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxWords=16, MinWords=8`, MatchStart, MatchEnd)
+		args = []any{options, query, limit, offset}
+	}
+
+	rows, err := s.db.QueryContext(ctx, search, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var result SearchResult
+		comment, err := scanComment(rows, &result.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		result.Comment = comment
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// Turn free text into an FTS5 query matching every word literally, so
+// operators and stray quotes in user input can't cause syntax errors
+func matchQuery(query string) string {
+	var terms []string
+	for _, word := range strings.Fields(query) {
+		word = strings.ReplaceAll(word, `"`, `""`)
+		terms = append(terms, `"`+word+`"`)
+	}
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+var ErrNotEmpty = errors.New("database already has comments")
+
+// What Seed generates. Zero fields take the defaults below.
+type SeedOptions struct {
+	// Videos commented on, default 8
+	Videos int
+	// Top-level comments on each video, default 40
+	CommentsPerVideo int
+	// The same value always generates the same comments
+	Seed int64
+	// Seed even when the database already has comments
+	Force bool
+	// Time the newest comment is posted at, default now. Everything else is
+	// spread over the four weeks before it.
+	Now time.Time
+}
+
+// Well-known videos the fixtures are posted on, so their pages load. Videos
+// past the end of the list get made-up IDs.
+var seedVideos = []string{
+	"dQw4w9WgXcQ", "jNQXAC9IVRw", "9bZkp7q19f8", "kJQP7kiw5Fk",
+	"OPf0YbXqDm0", "fJ9rUzIMcZQ", "hT_nvWreIhg", "RgKAFK5djSk",
+}
+
+var seedAuthors = []string{
+	"", "", "", "Ada", "Grace", "Linus", "Margaret", "Ken", "Barbara", "Dennis",
+	"Radia", "Edsger", "Frances", "Alan", "Hedy", "Tim",
+}
+
+var seedWords = strings.Fields(`the this song video part moment really love great honestly
+	never always again watching still years later who else here best ever sound music
+	remember first time chorus guitar drums voice lyrics beautiful amazing classic
+	underrated nostalgia replay volume live version original cover mix perfect`)
+
+// Fill the database with comments for developing the UI and benchmarking:
+// replies, votes, authors and video times included, of varied lengths and
+// posted over several weeks. Refuses with ErrNotEmpty when there are
+// comments already, unless opts.Force is set. Returns the number of
+// comments added.
+func Seed(ctx context.Context, s *Store, opts SeedOptions) (int, error) {
+	if opts.Videos <= 0 {
+		opts.Videos = 8
+	}
+	if opts.CommentsPerVideo <= 0 {
+		opts.CommentsPerVideo = 40
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	var added int
+	err := s.WithTx(ctx, func(tx *Tx) error {
+		var exists bool
+		if err := tx.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM comments)").Scan(&exists); err != nil {
+			return err
+		}
+		if exists && !opts.Force {
+			return ErrNotEmpty
+		}
+
+		hashes := make(map[string]string, len(seedAuthors))
+		for _, author := range seedAuthors {
+			hashes[author] = fmt.Sprintf("seed-%016x", rng.Uint64())
+		}
+		const span = 28 * 24 * time.Hour
+		for v := 0; v < opts.Videos; v++ {
+			videoID := seedVideoID(rng, v)
+			for i := 0; i < opts.CommentsPerVideo; i++ {
+				at := opts.Now.Add(-time.Duration(rng.Int63n(int64(span))))
+				id, err := seedComment(ctx, tx, rng, videoID, nil, at, hashes)
+				if err != nil {
+					return err
+				}
+				added++
+
+				// About a third of the comments start a thread
+				if rng.Intn(3) > 0 {
+					continue
+				}
+				for r := rng.Intn(5) + 1; r > 0; r-- {
+					at = at.Add(time.Duration(rng.Int63n(int64(opts.Now.Sub(at))/2 + 1)))
+					if _, err := seedComment(ctx, tx, rng, videoID, &id, at, hashes); err != nil {
+						return err
+					}
+					added++
+				}
+			}
+		}
+		return nil
+	})
+	return added, err
+}
+
+func seedVideoID(rng *rand.Rand, n int) string {
+	if n < len(seedVideos) {
+		return seedVideos[n]
+	}
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+	id := make([]byte, 11)
+	for i := range id {
+		id[i] = alphabet[rng.Intn(len(alphabet))]
+	}
+	return string(id)
+}
+
+// Insert one made-up comment with a few votes, returning its ID
+func seedComment(ctx context.Context, tx *Tx, rng *rand.Rand, videoID string, parentID *int64, at time.Time, hashes map[string]string) (int64, error) {
+	author := seedAuthors[rng.Intn(len(seedAuthors))]
+	var videoTime *int
+	if parentID == nil && rng.Intn(4) == 0 {
+		t := rng.Intn(240)
+		videoTime = &t
+	}
+
+	var id int64
+	err := tx.db.QueryRowContext(
+		ctx,
+		`INSERT INTO comments (video_id, comment, author, author_hash, parent_id, video_time_seconds, lang, created_at)
+		VALUES (?, ?, NULLIF(?, ''), ?, ?, ?, 'en', ?)
+		RETURNING id`,
+		videoID, seedText(rng), author, hashes[author], parentID, videoTime, formatTime(at),
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	// Mostly a handful of votes, leaning up, with the odd popular comment
+	votes := rng.Intn(6)
+	if rng.Intn(10) == 0 {
+		votes += rng.Intn(40)
+	}
+	for i := 0; i < votes; i++ {
+		value := 1
+		if rng.Intn(4) == 0 {
+			value = -1
+		}
+		if _, err := tx.db.ExecContext(
+			ctx,
+			"INSERT INTO comment_votes (comment_id, voter_token, value) VALUES (?, ?, ?)",
+			id, fmt.Sprintf("seed-voter-%d", i), value,
+		); err != nil {
+			return 0, err
+		}
+	}
+	return id, nil
+}
+
+// Text from a few words to a few paragraphs
+func seedText(rng *rand.Rand) string {
+	paragraphs := 1
+	if rng.Intn(8) == 0 {
+		paragraphs += rng.Intn(3) + 1
+	}
+	var b strings.Builder
+	for p := 0; p < paragraphs; p++ {
+		if p > 0 {
+			b.WriteString("\n\n")
+		}
+		words := rng.Intn(30) + 2
+		for w := 0; w < words; w++ {
+			word := seedWords[rng.Intn(len(seedWords))]
+			if w == 0 {
+				word = strings.ToUpper(word[:1]) + word[1:]
+			} else {
+				b.WriteByte(' ')
+			}
+			b.WriteString(word)
+		}
+		b.WriteString([]string{".", "!", "?", " 😂", ""}[rng.Intn(5)])
+	}
+	return b.String()
+}
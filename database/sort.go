@@ -0,0 +1,118 @@
+package database
+
+import "time"
+
+// Order in which top-level comments are listed
+type SortOrder int
+
+const (
+	SortNewest SortOrder = iota
+	SortOldest
+	SortTop
+	// Anchored comments by position in the video, unanchored ones last
+	SortTimestamp
+)
+
+// Parse a sort name from a query string, defaulting to newest first
+func ParseSortOrder(name string) SortOrder {
+	switch name {
+	case "oldest":
+		return SortOldest
+	case "top":
+		return SortTop
+	case "timestamp":
+		return SortTimestamp
+	default:
+		return SortNewest
+	}
+}
+
+func (s SortOrder) String() string {
+	switch s {
+	case SortOldest:
+		return "oldest"
+	case SortTop:
+		return "top"
+	case SortTimestamp:
+		return "timestamp"
+	default:
+		return "newest"
+	}
+}
+
+// ORDER BY clause for the sort, with ties on score falling back to created_at.
+// Pinned comments always come first.
+func (s SortOrder) orderBy() string {
+	switch s {
+	case SortOldest:
+		return "pinned DESC, created_at ASC, id ASC"
+	case SortTop:
+		return "pinned DESC, " + scoreOf("comments") + " DESC, created_at DESC, id DESC"
+	case SortTimestamp:
+		return "pinned DESC, video_time_seconds IS NULL, COALESCE(video_time_seconds, 0), id"
+	default:
+		return "pinned DESC, created_at DESC, id DESC"
+	}
+}
+
+// Keyset condition selecting the comments that come after the comment whose
+// id is bound to the placeholder. Ascending sorts compare -pinned so the
+// whole row value runs in one direction.
+func (s SortOrder) after() string {
+	switch s {
+	case SortOldest:
+		return "(-pinned, created_at, id) > (SELECT -pinned, created_at, id FROM comments WHERE id = ?)"
+	case SortTop:
+		return "(pinned, " + scoreOf("comments") + ", created_at, id) < " +
+			"(SELECT cursor.pinned, " + scoreOf("cursor") + ", cursor.created_at, cursor.id FROM comments AS cursor WHERE cursor.id = ?)"
+	case SortTimestamp:
+		return "(-pinned, video_time_seconds IS NULL, COALESCE(video_time_seconds, 0), id) > " +
+			"(SELECT -pinned, video_time_seconds IS NULL, COALESCE(video_time_seconds, 0), id FROM comments WHERE id = ?)"
+	default:
+		return "(pinned, created_at, id) < (SELECT pinned, created_at, id FROM comments WHERE id = ?)"
+	}
+}
+
+// Sort key of the comment in this order, as kept in page cursors. Times are
+// whole seconds, the precision they are stored with.
+func (s SortOrder) key(c Comment) []int64 {
+	var pinned int64
+	if c.Pinned {
+		pinned = 1
+	}
+	switch s {
+	case SortOldest:
+		return []int64{-pinned, c.CreatedAt.Unix(), c.ID}
+	case SortTop:
+		return []int64{pinned, int64(c.Score), c.CreatedAt.Unix(), c.ID}
+	case SortTimestamp:
+		untimed, at := int64(1), int64(0)
+		if c.VideoTime != nil {
+			untimed, at = 0, int64(*c.VideoTime)
+		}
+		return []int64{-pinned, untimed, at, c.ID}
+	default:
+		return []int64{pinned, c.CreatedAt.Unix(), c.ID}
+	}
+}
+
+// Keyset condition selecting the comments that come after the given sort
+// key, with the arguments it binds
+func (s SortOrder) afterKey(key []int64) (string, []any) {
+	switch s {
+	case SortOldest:
+		return "(-pinned, created_at, id) > (?, ?, ?)", []any{key[0], storedTime(key[1]), key[2]}
+	case SortTop:
+		return "(pinned, " + scoreOf("comments") + ", created_at, id) < (?, ?, ?, ?)", []any{key[0], key[1], storedTime(key[2]), key[3]}
+	case SortTimestamp:
+		return "(-pinned, CASE WHEN video_time_seconds IS NULL THEN 1 ELSE 0 END, COALESCE(video_time_seconds, 0), id) > (?, ?, ?, ?)",
+			[]any{key[0], key[1], key[2], key[3]}
+	default:
+		return "(pinned, created_at, id) < (?, ?, ?)", []any{key[0], storedTime(key[1]), key[2]}
+	}
+}
+
+// A Unix time in the form timestamps are stored in
+func storedTime(unix int64) string {
+	return formatTime(time.Unix(unix, 0))
+}
@@ -0,0 +1,202 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+)
+
+// Number of comments posted on a video
+type VideoCommentCount struct {
+	VideoID  string
+	Comments int
+}
+
+// Total number of comments, including replies and hidden ones but not
+// deleted ones
+func (s *Store) CountComments(ctx context.Context) (int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var n int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM comments WHERE deleted_at IS NULL").Scan(&n)
+	return n, err
+}
+
+// Number of comments posted since the given time
+func (s *Store) CountCommentsSince(ctx context.Context, since time.Time) (int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var n int
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT COUNT(*) FROM comments WHERE created_at >= ?",
+		formatTime(since),
+	).Scan(&n)
+	return n, err
+}
+
+// Videos with the most comments, busiest first
+func (s *Store) MostCommentedVideos(ctx context.Context, limit int) ([]VideoCommentCount, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT video_id, COUNT(*) AS n FROM comments WHERE deleted_at IS NULL GROUP BY video_id ORDER BY n DESC, video_id LIMIT ?",
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var videos []VideoCommentCount
+	for rows.Next() {
+		var v VideoCommentCount
+		if err := rows.Scan(&v.VideoID, &v.Comments); err != nil {
+			return nil, err
+		}
+		videos = append(videos, v)
+	}
+	return videos, rows.Err()
+}
+
+// Number of comments in the moderation queue, see ListReportedComments
+func (s *Store) CountPendingReports(ctx context.Context) (int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var n int
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM comments
+		WHERE deleted_at IS NULL
+		AND (hidden = 1 OR EXISTS (SELECT 1 FROM comment_reports WHERE comment_id = comments.id))`,
+	).Scan(&n)
+	return n, err
+}
+
+// Number of visible comments on each of the given videos, replies included,
+// as kept in video_comment_counts. Every ID is in the map, with 0 for videos
+// nobody has commented on.
+func (s *Store) CountCommentsByVideoIDs(ctx context.Context, ids []string) (map[string]int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	counts := make(map[string]int, len(ids))
+	if len(ids) == 0 {
+		return counts, nil
+	}
+
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		counts[id] = 0
+		args = append(args, id)
+	}
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT video_id, count FROM video_comment_counts WHERE video_id IN ("+placeholders(len(ids))+")",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, err
+		}
+		counts[id] = n
+	}
+	return counts, rows.Err()
+}
+
+// Videos with the most visible comments posted since the given time,
+// busiest first
+func (s *Store) MostDiscussedVideos(ctx context.Context, since time.Time, limit, offset int) ([]VideoCommentCount, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT video_id, COUNT(*) AS n FROM comments
+		WHERE created_at >= ? AND hidden = 0 AND deleted_at IS NULL AND visible_to_author_only = 0
+		GROUP BY video_id ORDER BY n DESC, video_id LIMIT ? OFFSET ?`,
+		formatTime(since), limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var videos []VideoCommentCount
+	for rows.Next() {
+		var v VideoCommentCount
+		if err := rows.Scan(&v.VideoID, &v.Comments); err != nil {
+			return nil, err
+		}
+		videos = append(videos, v)
+	}
+	return videos, rows.Err()
+}
+
+// Tables counted for the admin stats, the full-text index aside
+var countedTables = []string{
+	"comments", "comment_votes", "comment_reactions", "comment_reports", "comment_revisions",
+	"users", "sessions", "bans", "drafts", "video_settings", "video_comment_counts", "audit_log", "link_previews", "video_cache", "api_quota",
+}
+
+// Rows in each table, keyed by table name. Counting scans every table, so
+// callers shouldn't run it on every request.
+func (s *Store) TableCounts(ctx context.Context) (map[string]int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	selects := make([]string, len(countedTables))
+	for i, table := range countedTables {
+		selects[i] = "SELECT '" + table + "', COUNT(*) FROM " + table
+	}
+	rows, err := s.db.QueryContext(ctx, strings.Join(selects, " UNION ALL "))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int, len(countedTables))
+	for rows.Next() {
+		var table string
+		var n int
+		if err := rows.Scan(&table, &n); err != nil {
+			return nil, err
+		}
+		counts[table] = n
+	}
+	return counts, rows.Err()
+}
+
+// Bytes on disk taken by an SQLite database file and its write-ahead log,
+// both 0 for Postgres and in-memory databases
+func (s *Store) DiskUsage() (db, wal int64, err error) {
+	if s.path == "" {
+		return 0, 0, nil
+	}
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return 0, 0, err
+	}
+	// The log is removed when the last connection closes
+	if walInfo, err := os.Stat(s.path + "-wal"); err == nil {
+		wal = walInfo.Size()
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return 0, 0, err
+	}
+	return info.Size(), wal, nil
+}
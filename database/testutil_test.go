@@ -0,0 +1,16 @@
+package database
+
+import "testing"
+
+// A store on a fresh in-memory database with the schema in place, closed
+// when the test ends. Each call gets a database of its own, so tests using
+// it can run in parallel.
+func NewTestStore(t testing.TB) *Store {
+	t.Helper()
+	s, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("opening in-memory database: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Keep the in-memory store's one connection busy until the test ends, so
+// every other call has to wait for it
+func holdConnection(t *testing.T, s *Store) {
+	t.Helper()
+	held, release := make(chan struct{}), make(chan struct{})
+	go s.WithTx(context.Background(), func(*Tx) error {
+		close(held)
+		<-release
+		return nil
+	})
+	<-held
+	t.Cleanup(func() { close(release) })
+}
+
+func TestQueryCancelled(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	holdConnection(t, s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := s.CountComments(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("returned %s after cancelling", took)
+	}
+}
+
+func TestDefaultQueryTimeout(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	s.QueryTimeout = 50 * time.Millisecond
+	holdConnection(t, s)
+
+	start := time.Now()
+	_, err := s.CountComments(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("returned after %s, want the %s timeout", took, s.QueryTimeout)
+	}
+
+	// A deadline of the caller's own wins
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if _, err := s.CountComments(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if took := time.Since(start); took < 150*time.Millisecond {
+		t.Errorf("returned after %s, before the caller's deadline", took)
+	}
+}
+
+// A statement that keeps SQLite busy for far longer than any test runs
+const slowQuery = `WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 1000000000)
+SELECT COUNT(*) FROM n`
+
+func TestRunningQueryInterrupted(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		ctx  func() (context.Context, context.CancelFunc)
+		want error
+	}{
+		{"cancelled", func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(100*time.Millisecond, cancel)
+			return ctx, cancel
+		}, context.Canceled},
+		{"deadline", func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 100*time.Millisecond)
+		}, context.DeadlineExceeded},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			s := NewTestStore(t)
+			ctx, cancel := test.ctx()
+			defer cancel()
+
+			start := time.Now()
+			var n int64
+			err := s.db.QueryRowContext(ctx, slowQuery).Scan(&n)
+			if !errors.Is(err, test.want) {
+				t.Errorf("err = %v, want %v", err, test.want)
+			}
+			if took := time.Since(start); took > 2*time.Second {
+				t.Errorf("returned after %s, want soon after 100ms", took)
+			}
+
+			// The connection is free for the next query
+			if _, err := s.CountComments(context.Background()); err != nil {
+				t.Errorf("query after the interrupt: %v", err)
+			}
+		})
+	}
+}
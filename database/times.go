@@ -0,0 +1,16 @@
+package database
+
+import "time"
+
+// Times are written by Go as RFC 3339 in UTC rather than left to the
+// database's CURRENT_TIMESTAMP, whose form depends on the engine and the
+// server's zone. Stored this way they sort and compare correctly as text in
+// SQLite, and Postgres parses them into its TIMESTAMP columns.
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// The current time as it is stored
+func now() string {
+	return formatTime(time.Now())
+}
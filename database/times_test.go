@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLegacyTimestamps(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "comments.db")
+	recent := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	// CURRENT_TIMESTAMP wrote times like this before they were RFC 3339
+	writeFixture(t, path,
+		`CREATE TABLE comments (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            video_id TEXT NOT NULL,
+            comment TEXT,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        )`,
+		`INSERT INTO comments (video_id, comment, created_at) VALUES ('dQw4w9WgXcQ', 'Old', '2023-01-02 03:04:05')`,
+		`INSERT INTO comments (video_id, comment, created_at) VALUES ('dQw4w9WgXcQ', 'Recent', '`+recent.Format(time.DateTime)+`')`,
+	)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	var stored string
+	if err := s.db.QueryRowContext(ctx, "SELECT created_at FROM comments WHERE comment = 'Old'").Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored != "2023-01-02T03:04:05Z" {
+		t.Errorf("stored as %q, want RFC 3339", stored)
+	}
+	old, err := s.GetComment(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC); !old.CreatedAt.Equal(want) {
+		t.Errorf("created at %v, want %v", old.CreatedAt, want)
+	}
+
+	// Counting the last day compares migrated rows and new ones alike
+	if _, err := s.AddComment(ctx, NewComment{VideoID: "dQw4w9WgXcQ", Text: "New", AuthorHash: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	n, err := s.CountCommentsSince(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("%d comments in the last day, want 2", n)
+	}
+}
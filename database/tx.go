@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+)
+
+var ErrNestedTx = errors.New("transaction already open, use the Tx it gave instead")
+
+// What queries run on: the connection pool, or the transaction of a Tx
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// A transaction opened by WithTx. It has every method of the Store, each
+// run inside the transaction, and like a single connection isn't safe for
+// concurrent use.
+type Tx struct {
+	*Store
+}
+
+// Run fn in a transaction, committing it when fn returns nil and rolling it
+// back when fn returns an error or panics, in which case the panic carries
+// on. Calling WithTx again on the Tx returns ErrNestedTx rather than waiting
+// on a lock the transaction itself holds.
+func (s *Store) WithTx(ctx context.Context, fn func(tx *Tx) error) (err error) {
+	if s.tx != nil {
+		return ErrNestedTx
+	}
+	sqlTx, err := s.pool.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			sqlTx.Rollback()
+			panic(p)
+		}
+	}()
+
+	tx := &Tx{&Store{db: sqlTx, pool: s.pool, tx: sqlTx, dialect: s.dialect, QueryTimeout: s.QueryTimeout}}
+	if err := fn(tx); err != nil {
+		sqlTx.Rollback()
+		return err
+	}
+	return sqlTx.Commit()
+}
+
+// A transaction begun by one of the Store's methods. Inside a Tx it is a
+// savepoint instead, as transactions don't nest, so the method's work is
+// still undone on its own when it fails.
+type txn struct {
+	*sql.Tx
+	savepoint string
+	done      bool
+}
+
+func (s *Store) begin(ctx context.Context) (*txn, error) {
+	if s.tx == nil {
+		tx, err := s.pool.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &txn{Tx: tx}, nil
+	}
+
+	s.savepoints++
+	name := "sp" + strconv.Itoa(s.savepoints)
+	if _, err := s.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return nil, err
+	}
+	return &txn{Tx: s.tx, savepoint: name}, nil
+}
+
+func (t *txn) Commit() error {
+	if t.savepoint == "" {
+		return t.Tx.Commit()
+	}
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	_, err := t.Tx.ExecContext(context.Background(), "RELEASE SAVEPOINT "+t.savepoint)
+	return err
+}
+
+func (t *txn) Rollback() error {
+	if t.savepoint == "" {
+		return t.Tx.Rollback()
+	}
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	if _, err := t.Tx.ExecContext(context.Background(), "ROLLBACK TO SAVEPOINT "+t.savepoint); err != nil {
+		return err
+	}
+	_, err := t.Tx.ExecContext(context.Background(), "RELEASE SAVEPOINT "+t.savepoint)
+	return err
+}
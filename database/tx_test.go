@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithTxRollsBackOnError(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+	id, err := s.AddComment(ctx, NewComment{VideoID: "dQw4w9WgXcQ", Text: "Before", AuthorHash: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed := errors.New("failed half way")
+	err = s.WithTx(ctx, func(tx *Tx) error {
+		if _, err := tx.AddComment(ctx, NewComment{VideoID: "dQw4w9WgXcQ", Text: "During", AuthorHash: "a"}); err != nil {
+			return err
+		}
+		if err := tx.Vote(ctx, id, "voter", 1); err != nil {
+			return err
+		}
+		// Methods with transactions of their own use savepoints inside one
+		if err := tx.HardDeleteComment(ctx, id, AuditEntry{Actor: "admin"}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("err = %v, want the function's error", err)
+	}
+
+	comment, err := s.GetComment(ctx, id)
+	if err != nil {
+		t.Fatalf("comment deleted by the rolled back transaction: %v", err)
+	}
+	if comment.Score != 0 {
+		t.Errorf("score = %d, want the vote rolled back", comment.Score)
+	}
+	if n, err := s.CountComments(ctx); err != nil || n != 1 {
+		t.Errorf("%d comments, %v; want only the first", n, err)
+	}
+}
+
+func TestWithTxRollsBackOnPanic(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("recovered %v, want the panic to carry on", p)
+			}
+		}()
+		s.WithTx(ctx, func(tx *Tx) error {
+			if _, err := tx.AddComment(ctx, NewComment{VideoID: "dQw4w9WgXcQ", Text: "Doomed", AuthorHash: "a"}); err != nil {
+				t.Fatal(err)
+			}
+			panic("boom")
+		})
+	}()
+
+	if n, err := s.CountComments(ctx); err != nil || n != 0 {
+		t.Errorf("%d comments, %v; want none", n, err)
+	}
+}
+
+func TestNestedWithTx(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.WithTx(ctx, func(tx *Tx) error {
+			return tx.WithTx(ctx, func(*Tx) error { return nil })
+		})
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrNestedTx) {
+			t.Errorf("err = %v, want ErrNestedTx", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("nested WithTx deadlocked")
+	}
+}
@@ -0,0 +1,205 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrUsernameTaken   = errors.New("username already taken")
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+type User struct {
+	ID       int64
+	Username string
+	// Empty for accounts that only sign in with Google
+	PasswordHash string
+	// Google account ID and profile picture for users who signed in with Google
+	GoogleSub  string
+	PictureURL string
+	IsAdmin    bool
+	CreatedAt  time.Time
+}
+
+// Columns selected by every user query, in the order scanUser expects
+const userColumns = "users.id, users.username, users.password_hash, COALESCE(users.google_sub, ''), COALESCE(users.picture_url, ''), users.is_admin, users.created_at"
+
+func createUsersTable(ctx context.Context, tx *sql.Tx, d dialect) error {
+	_, err := tx.ExecContext(
+		ctx,
+		d.ddl(`CREATE TABLE IF NOT EXISTS users (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            username TEXT NOT NULL UNIQUE COLLATE NOCASE,
+            password_hash TEXT NOT NULL,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        )`),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Columns added after the table was first created
+	if err := addColumn(ctx, tx, d, "users", "google_sub", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumn(ctx, tx, d, "users", "picture_url", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumn(ctx, tx, d, "users", "is_admin", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "CREATE UNIQUE INDEX IF NOT EXISTS users_google_sub ON users (google_sub)")
+	return err
+}
+
+// Sessions are looked up by a hash of the cookie token, so a database leak
+// can't be used to log in
+func createSessionsTable(ctx context.Context, tx *sql.Tx, d dialect) error {
+	_, err := tx.ExecContext(
+		ctx,
+		d.ddl(`CREATE TABLE IF NOT EXISTS sessions (
+            token_hash TEXT PRIMARY KEY,
+            user_id INTEGER NOT NULL REFERENCES users(id),
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            expires_at TIMESTAMP NOT NULL
+        )`),
+	)
+	return err
+}
+
+// Usernames are unique regardless of case
+func (s *Store) CreateUser(ctx context.Context, username, passwordHash string) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var id int64
+	err := s.db.QueryRowContext(
+		ctx,
+		"INSERT INTO users (username, password_hash, created_at) VALUES (?, ?, ?) RETURNING id",
+		username, passwordHash, now(),
+	).Scan(&id)
+	if isUniqueViolation(err, "") {
+		return 0, ErrUsernameTaken
+	}
+	return id, err
+}
+
+func (s *Store) GetUserByUsername(ctx context.Context, username string) (User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return scanUser(s.db.QueryRowContext(
+		ctx,
+		"SELECT "+userColumns+" FROM users WHERE username = ?",
+		username,
+	))
+}
+
+func scanUser(row scanner) (User, error) {
+	var user User
+	err := row.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.GoogleSub, &user.PictureURL, &user.IsAdmin, &user.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrUserNotFound
+	}
+	return user, err
+}
+
+// Find the user for a Google account, creating one the first time it signs
+// in. The username is taken from the Google profile name, with a number
+// appended when it is already in use.
+func (s *Store) UpsertGoogleUser(ctx context.Context, sub, username, pictureURL string) (User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	user, err := scanUser(s.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE google_sub = ?", sub))
+	if err == nil {
+		_, err = s.db.ExecContext(ctx, "UPDATE users SET picture_url = ? WHERE id = ?", pictureURL, user.ID)
+		user.PictureURL = pictureURL
+		return user, err
+	}
+	if !errors.Is(err, ErrUserNotFound) {
+		return User{}, err
+	}
+
+	for i := 1; ; i++ {
+		candidate := username
+		if i > 1 {
+			candidate = fmt.Sprintf("%s-%d", username, i)
+		}
+		var id int64
+		err := s.db.QueryRowContext(
+			ctx,
+			"INSERT INTO users (username, password_hash, google_sub, picture_url, created_at) VALUES (?, '', ?, ?, ?) RETURNING id",
+			candidate, sub, pictureURL, now(),
+		).Scan(&id)
+		if isUniqueViolation(err, "users.username") {
+			continue
+		}
+		if err != nil {
+			return User{}, err
+		}
+		return User{ID: id, Username: candidate, GoogleSub: sub, PictureURL: pictureURL, CreatedAt: time.Now()}, nil
+	}
+}
+
+// Grant or revoke admin rights for the named user
+func (s *Store) SetUserAdmin(ctx context.Context, username string, admin bool) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, "UPDATE users SET is_admin = ? WHERE username = ?", admin, username)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *Store) CreateSession(ctx context.Context, tokenHash string, userID int64, expires time.Time) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(
+		ctx,
+		"INSERT INTO sessions (token_hash, user_id, expires_at, created_at) VALUES (?, ?, ?, ?)",
+		tokenHash, userID, formatTime(expires), now(),
+	)
+	return err
+}
+
+// The user a session belongs to, as long as the session hasn't expired
+func (s *Store) GetSessionUser(ctx context.Context, tokenHash string) (User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	user, err := scanUser(s.db.QueryRowContext(
+		ctx,
+		`SELECT `+userColumns+`
+		FROM sessions JOIN users ON users.id = sessions.user_id
+		WHERE sessions.token_hash = ? AND sessions.expires_at > ?`,
+		tokenHash, now(),
+	))
+	if errors.Is(err, ErrUserNotFound) {
+		return User{}, ErrSessionNotFound
+	}
+	return user, err
+}
+
+func (s *Store) DeleteSession(ctx context.Context, tokenHash string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE token_hash = ?", tokenHash)
+	return err
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Details of a YouTube video as the caller serialized them, and when they
+// were fetched
+type CachedVideo struct {
+	Data      []byte
+	FetchedAt time.Time
+}
+
+// video_cache keeps what YouTube said about each video looked up, so it
+// isn't asked again on every page or after a restart
+func createVideoCacheTable(ctx context.Context, tx *sql.Tx, d dialect) error {
+	_, err := tx.ExecContext(
+		ctx,
+		d.ddl(`CREATE TABLE IF NOT EXISTS video_cache (
+            video_id TEXT PRIMARY KEY,
+            data TEXT NOT NULL,
+            fetched_at TIMESTAMP NOT NULL
+        )`),
+	)
+	return err
+}
+
+// Cached details of the given videos, whatever their age, keyed by video
+// ID. Videos never cached are left out.
+func (s *Store) GetCachedVideos(ctx context.Context, ids []string) (map[string]CachedVideo, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	videos := make(map[string]CachedVideo)
+	if len(ids) == 0 {
+		return videos, nil
+	}
+
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT video_id, data, fetched_at FROM video_cache WHERE video_id IN ("+placeholders(len(ids))+")",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, data string
+		var video CachedVideo
+		if err := rows.Scan(&id, &data, &video.FetchedAt); err != nil {
+			return nil, err
+		}
+		video.Data = []byte(data)
+		videos[id] = video
+	}
+	return videos, rows.Err()
+}
+
+// Store freshly fetched details of videos, keyed by video ID, replacing
+// what was cached before
+func (s *Store) SaveCachedVideos(ctx context.Context, videos map[string][]byte) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if len(videos) == 0 {
+		return nil
+	}
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	fetched := now()
+	for id, data := range videos {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO video_cache (video_id, data, fetched_at) VALUES (?, ?, ?)
+			ON CONFLICT (video_id) DO UPDATE SET data = excluded.data, fetched_at = excluded.fetched_at`,
+			id, string(data), fetched,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// Per-video options, one row per video that has any set
+func createVideoSettingsTable(ctx context.Context, tx *sql.Tx, d dialect) error {
+	_, err := tx.ExecContext(
+		ctx,
+		d.ddl(`CREATE TABLE IF NOT EXISTS video_settings (
+            video_id TEXT PRIMARY KEY,
+            locked INTEGER NOT NULL DEFAULT 0
+        )`),
+	)
+	if err != nil {
+		return err
+	}
+	return addColumn(ctx, tx, d, "video_settings", "slow_mode_seconds", "INTEGER NOT NULL DEFAULT 0")
+}
+
+// Report whether new comments and votes on the video are refused
+func (s *Store) IsVideoLocked(ctx context.Context, videoID string) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var locked bool
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT EXISTS(SELECT 1 FROM video_settings WHERE video_id = ? AND locked = 1)",
+		videoID,
+	).Scan(&locked)
+	return locked, err
+}
+
+// Lock or unlock a video, creating its settings row if needed
+func (s *Store) SetVideoLocked(ctx context.Context, videoID string, locked bool, audit AuditEntry) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO video_settings (video_id, locked) VALUES (?, ?)
+		ON CONFLICT (video_id) DO UPDATE SET locked = excluded.locked`,
+		videoID, locked,
+	)
+	if err != nil {
+		return err
+	}
+	audit.Action, audit.TargetType, audit.TargetID = "lock", "video", videoID
+	if !locked {
+		audit.Action = "unlock"
+	}
+	if err := audit.write(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Shortest time allowed between comments from one author on the video, or 0
+// when slow mode is off
+func (s *Store) GetSlowMode(ctx context.Context, videoID string) (time.Duration, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var seconds int
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT slow_mode_seconds FROM video_settings WHERE video_id = ?",
+		videoID,
+	).Scan(&seconds)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return time.Duration(seconds) * time.Second, err
+}
+
+// Set the video's slow mode interval, 0 turning it off
+func (s *Store) SetSlowMode(ctx context.Context, videoID string, interval time.Duration, audit AuditEntry) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	seconds := int(interval / time.Second)
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO video_settings (video_id, slow_mode_seconds) VALUES (?, ?)
+		ON CONFLICT (video_id) DO UPDATE SET slow_mode_seconds = excluded.slow_mode_seconds`,
+		videoID, seconds,
+	)
+	if err != nil {
+		return err
+	}
+	audit.Action, audit.TargetType, audit.TargetID = "slow-mode", "video", videoID
+	if audit.Note == "" {
+		audit.Note = strconv.Itoa(seconds) + "s"
+	}
+	if err := audit.write(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// When the author last commented on the video, deleted comments included.
+// Reports false when they never have.
+func (s *Store) LastCommentAt(ctx context.Context, videoID, authorHash string) (time.Time, bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var last time.Time
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT created_at FROM comments WHERE video_id = ? AND author_hash = ? ORDER BY created_at DESC LIMIT 1",
+		videoID, authorHash,
+	).Scan(&last)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	return last, err == nil, err
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+func createVotesTable(ctx context.Context, tx *sql.Tx, d dialect) error {
+	_, err := tx.ExecContext(
+		ctx,
+		d.ddl(`CREATE TABLE IF NOT EXISTS comment_votes (
+            comment_id INTEGER NOT NULL REFERENCES comments(id),
+            voter_token TEXT NOT NULL,
+            value INTEGER NOT NULL CHECK (value IN (-1, 1)),
+            PRIMARY KEY (comment_id, voter_token)
+        )`),
+	)
+	return err
+}
+
+// Record a vote, replacing any earlier vote by the same voter on that comment
+func (s *Store) Vote(ctx context.Context, commentID int64, voterToken string, value int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO comment_votes (comment_id, voter_token, value)
+		SELECT id, ?, ? FROM comments WHERE id = ? AND deleted_at IS NULL
+		ON CONFLICT (comment_id, voter_token) DO UPDATE SET value = excluded.value`,
+		voterToken, value, commentID,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestVote(t *testing.T) {
+	t.Parallel()
+	s := NewTestStore(t)
+	ctx := context.Background()
+	id, err := s.AddComment(ctx, NewComment{VideoID: "dQw4w9WgXcQ", Text: "Vote on me", AuthorHash: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	score := func() int {
+		t.Helper()
+		comment, err := s.GetComment(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return comment.Score
+	}
+	for _, vote := range []struct {
+		voter string
+		value int
+		want  int
+	}{
+		{"x", 1, 1},
+		{"y", 1, 2},
+		// A second vote replaces the first
+		{"x", -1, 0},
+		{"x", -1, 0},
+	} {
+		if err := s.Vote(ctx, id, vote.voter, vote.value); err != nil {
+			t.Fatal(err)
+		}
+		if got := score(); got != vote.want {
+			t.Errorf("after %s voted %d: score = %d, want %d", vote.voter, vote.value, got, vote.want)
+		}
+	}
+
+	if err := s.Vote(ctx, id, "z", 2); err == nil {
+		t.Error("vote of 2 accepted")
+	}
+	if err := s.Vote(ctx, id+100, "x", 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("vote on a missing comment: err = %v, want ErrNotFound", err)
+	}
+	if err := s.DeleteComment(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Vote(ctx, id, "z", 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("vote on a deleted comment: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestClosedStore(t *testing.T) {
+	t.Parallel()
+	s, err := Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Ping(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Ping(context.Background()); err == nil {
+		t.Error("closed store still answers")
+	}
+}
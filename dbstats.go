@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"expvar"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// How long the row counts on /admin/stats are reused, as counting scans
+// every table
+const statsCacheTTL = 30 * time.Second
+
+var statsCache struct {
+	sync.Mutex
+	counted  time.Time
+	comments int
+	tables   map[string]int
+}
+
+// Size and contents of the database and when it was last looked after, and
+// the day's YouTube API usage, as JSON
+func showStats(keys *keyring) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		comments, tables, counted, err := cachedCounts(c.Request.Context())
+		if err != nil {
+			log.Println("Error counting rows:", err)
+			c.String(http.StatusInternalServerError, "Failed to load database stats.")
+			return
+		}
+		dbSize, walSize, err := store.DiskUsage()
+		if err != nil {
+			log.Println("Error measuring database files:", err)
+			c.String(http.StatusInternalServerError, "Failed to load database stats.")
+			return
+		}
+
+		var lastMaintenance, lastBackup any
+		if run, ok := optimizeStats.Get("last_run").(*expvar.String); ok {
+			lastMaintenance = run.Value()
+		}
+		if taken, ok := latestBackup(); ok {
+			lastBackup = taken.Format(time.RFC3339)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"comments":         comments,
+			"users":            tables["users"],
+			"db_size":          dbSize,
+			"wal_size":         walSize,
+			"last_maintenance": lastMaintenance,
+			"last_backup":      lastBackup,
+			"tables":           tables,
+			"counted_at":       counted.UTC().Format(time.RFC3339),
+			"youtube_quota": gin.H{
+				"day":        quotaDay(time.Now()),
+				"used":       quotaSpent(c.Request.Context()),
+				"soft_limit": quotaLimit(keys),
+				"keys":       keys.Len(),
+				"keys_left":  keys.Left(),
+			},
+		})
+	}
+}
+
+// Comment and table row counts, taken again once statsCacheTTL has passed
+func cachedCounts(ctx context.Context) (int, map[string]int, time.Time, error) {
+	statsCache.Lock()
+	defer statsCache.Unlock()
+
+	if time.Since(statsCache.counted) < statsCacheTTL {
+		return statsCache.comments, statsCache.tables, statsCache.counted, nil
+	}
+	comments, err := store.CountComments(ctx)
+	if err != nil {
+		return 0, nil, time.Time{}, err
+	}
+	tables, err := store.TableCounts(ctx)
+	if err != nil {
+		return 0, nil, time.Time{}, err
+	}
+	statsCache.counted, statsCache.comments, statsCache.tables = time.Now(), comments, tables
+	return comments, tables, statsCache.counted, nil
+}
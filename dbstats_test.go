@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+)
+
+// Count rows afresh on the next stats request, and after the test
+func clearStatsCache(t *testing.T) {
+	statsCache.counted = time.Time{}
+	t.Cleanup(func() { statsCache.counted = time.Time{} })
+}
+
+func TestShowStats(t *testing.T) {
+	useTestStore(t)
+	clearStatsCache(t)
+	useBackupDir(t)
+	for _, text := range []string{"One", "Two", "Three"} {
+		postTestComment(t, "author", database.NewComment{Text: text})
+	}
+	keys := testKeyring(t, func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("no YouTube in tests")
+	})
+	router := testRouter(http.MethodGet, "/admin/stats", showStats(keys))
+
+	stats := func() map[string]any {
+		t.Helper()
+		w := serve(router, requestAs(http.MethodGet, "/admin/stats", ""))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+		var body map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return body
+	}
+	body := stats()
+	if body["comments"] != 3.0 {
+		t.Errorf("comments = %v, want 3", body["comments"])
+	}
+	if tables, ok := body["tables"].(map[string]any); !ok || tables["comments"] != 3.0 {
+		t.Errorf("tables = %v, want 3 comments", body["tables"])
+	}
+	for _, key := range []string{"users", "db_size", "wal_size", "last_maintenance", "last_backup", "counted_at", "youtube_quota"} {
+		if _, ok := body[key]; !ok {
+			t.Errorf("stats have no %q", key)
+		}
+	}
+
+	// Counts are reused for a while rather than taken on every request
+	postTestComment(t, "author", database.NewComment{Text: "Four"})
+	if body := stats(); body["comments"] != 3.0 {
+		t.Errorf("comments = %v, want the cached 3", body["comments"])
+	}
+	statsCache.counted = time.Now().Add(-statsCacheTTL)
+	if body := stats(); body["comments"] != 4.0 {
+		t.Errorf("comments = %v, want 4 once the cache expired", body["comments"])
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	useTestStore(t)
+	router := testRouter(http.MethodGet, "/healthz", healthCheck)
+	if w := serve(router, requestAs(http.MethodGet, "/healthz", "")); w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Errorf("healthy: %d %q", w.Code, w.Body)
+	}
+
+	store.Close()
+	w := serve(router, requestAs(http.MethodGet, "/healthz", ""))
+	if w.Code != http.StatusServiceUnavailable || w.Body.String() != "degraded: database unreachable" {
+		t.Errorf("database closed: %d %q", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+	"github.com/TanishkBansode/right-to-comment/identity"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Save the comment being written so it survives the tab closing. Saving
+// empty text throws the draft away.
+func saveDraft(c *gin.Context) {
+	videoID := c.Param("id")
+	text := c.PostForm("comment")
+	if utf8.RuneCountInString(text) > maxCommentLength {
+		c.String(http.StatusBadRequest, validationMessage(errCommentTooLong))
+		return
+	}
+
+	ctx := c.Request.Context()
+	viewer := identity.Hash(identity.FromContext(c))
+	var err error
+	if strings.TrimSpace(text) == "" {
+		err = store.DeleteDraft(ctx, viewer, videoID)
+	} else {
+		err = store.SaveDraft(ctx, viewer, videoID, text)
+	}
+	if err != nil {
+		log.Println("Error saving draft:", err)
+		c.String(http.StatusInternalServerError, "Failed to save draft.")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Return the draft saved by this browser for the video
+func getDraft(c *gin.Context) {
+	draft, err := store.GetDraft(c.Request.Context(), identity.Hash(identity.FromContext(c)), c.Param("id"))
+	if errors.Is(err, database.ErrDraftNotFound) {
+		c.String(http.StatusNotFound, "No draft saved.")
+		return
+	}
+	if err != nil {
+		log.Println("Error loading draft:", err)
+		c.String(http.StatusInternalServerError, "Failed to load draft.")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"text": draft.Text, "updated_at": draft.UpdatedAt})
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+	"github.com/TanishkBansode/right-to-comment/identity"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Notifications waiting to be sent, beyond which new ones are dropped
+	mailQueueSize = 100
+
+	// Longest address accepted, per RFC 5321
+	maxEmailLength = 254
+
+	// What unsubscribe tokens are signed for
+	unsubscribePurpose = "unsubscribe"
+)
+
+var errBadEmail = errors.New("email address is not valid")
+
+// Sends reply notifications over SMTP, nil unless SMTP_HOST is set
+var mailer *replyMailer
+
+type replyMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+
+	queue chan replyNotice
+	wg    sync.WaitGroup
+}
+
+// A reply to tell the parent comment's author about
+type replyNotice struct {
+	replyID int64
+	// Scheme and host the reply was posted on, for building links
+	baseURL string
+}
+
+// Set up the mailer from the SMTP_* variables and start its worker. Stopping
+// it sends what's queued first. The stop function is nil without SMTP_HOST.
+func loadMailer() (stop func()) {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return nil
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	username := os.Getenv("SMTP_USERNAME")
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = username
+	}
+	if from == "" {
+		log.Fatal("SMTP_FROM must be set when SMTP_HOST is")
+	}
+
+	mailer = &replyMailer{
+		addr:  net.JoinHostPort(host, port),
+		from:  from,
+		queue: make(chan replyNotice, mailQueueSize),
+	}
+	if username != "" {
+		mailer.auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+	mailer.wg.Add(1)
+	go mailer.work()
+	return func() {
+		close(mailer.queue)
+		mailer.wg.Wait()
+	}
+}
+
+// Check an optional address given for reply notifications
+func validateEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return "", nil
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > maxEmailLength {
+		return "", errBadEmail
+	}
+	return email, nil
+}
+
+// Queue an email about a new reply without holding up the request
+func notifyReply(c *gin.Context, replyID int64) {
+	if mailer == nil {
+		return
+	}
+	select {
+	case mailer.queue <- replyNotice{replyID: replyID, baseURL: absoluteURL(c, "")}:
+	default:
+		log.Println("Mail queue full, dropping reply notification for comment", replyID)
+	}
+}
+
+func (m *replyMailer) work() {
+	defer m.wg.Done()
+	for notice := range m.queue {
+		if err := m.send(context.Background(), notice); err != nil {
+			log.Println("Error sending reply notification:", err)
+		}
+	}
+}
+
+// Email the author of the comment replied to, if they asked for it
+func (m *replyMailer) send(ctx context.Context, notice replyNotice) error {
+	reply, err := store.GetComment(ctx, notice.replyID)
+	if err != nil {
+		return err
+	}
+	if reply.ParentID == nil {
+		return nil
+	}
+	parent, err := store.GetComment(ctx, *reply.ParentID)
+	if err != nil {
+		return err
+	}
+	// Nobody needs to hear about their own replies
+	if parent.AuthorHash != "" && parent.AuthorHash == reply.AuthorHash {
+		return nil
+	}
+	to, err := store.GetNotifyEmail(ctx, parent.ID)
+	if err != nil || to == "" {
+		return err
+	}
+
+	permalink := notice.baseURL + permalinkPath(reply.VideoID, reply.ID)
+	unsubscribe := notice.baseURL + "/unsubscribe?token=" + url.QueryEscape(unsubscribeToken(parent.ID))
+	author := reply.Author
+	if author == "" {
+		author = "Someone"
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	msg.WriteString("Subject: New reply to your comment\r\n")
+	fmt.Fprintf(&msg, "List-Unsubscribe: <%s>\r\n", unsubscribe)
+	msg.WriteString("List-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	fmt.Fprintf(&msg, "%s replied to your comment:\r\n\r\n", author)
+	msg.WriteString(strings.ReplaceAll(reply.Text, "\n", "\r\n"))
+	fmt.Fprintf(&msg, "\r\n\r\nSee the reply: %s\r\n", permalink)
+	fmt.Fprintf(&msg, "\r\nStop emails about replies to this comment: %s\r\n", unsubscribe)
+
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(msg.String()))
+}
+
+func unsubscribeToken(commentID int64) string {
+	return identity.Sign(secretKey, unsubscribePurpose, strconv.FormatInt(commentID, 10))
+}
+
+// Forget the address on a comment, from the link in a notification email
+func unsubscribe(c *gin.Context) {
+	value, ok := identity.Verify(secretKey, unsubscribePurpose, c.Query("token"))
+	id, err := strconv.ParseInt(value, 10, 64)
+	if !ok || err != nil {
+		renderError(c, http.StatusBadRequest, "This unsubscribe link is not valid.")
+		return
+	}
+
+	err = store.ClearNotifyEmail(c.Request.Context(), id)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		log.Println("Error clearing notification address:", err)
+		renderError(c, http.StatusInternalServerError, "Failed to unsubscribe, please try again.")
+		return
+	}
+	c.String(http.StatusOK, "You won't get any more emails about replies to this comment.")
+}
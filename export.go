@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// A row of a comment export
+type exportedComment struct {
+	ID        int64     `json:"id"`
+	Author    string    `json:"author"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+	Score     int       `json:"score"`
+	ParentID  *int64    `json:"parent_id"`
+}
+
+func newExportedComment(comment database.Comment) exportedComment {
+	return exportedComment{
+		ID:        comment.ID,
+		Author:    comment.Author,
+		Text:      comment.Text,
+		CreatedAt: comment.CreatedAt,
+		Score:     comment.Score,
+		ParentID:  comment.ParentID,
+	}
+}
+
+// Download every comment on a video as JSON or CSV. The file is written as
+// the rows are read, so errors after the first row can only be logged.
+func exportComments(c *gin.Context) {
+	videoID := c.Param("id")
+	format := c.DefaultQuery("format", "json")
+
+	var write func(c *gin.Context, videoID string) error
+	switch format {
+	case "json":
+		write = writeJSONExport
+		c.Header("Content-Type", "application/json; charset=utf-8")
+	case "csv":
+		write = writeCSVExport
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	default:
+		c.String(http.StatusBadRequest, `Unknown export format, use "json" or "csv".`)
+		return
+	}
+
+	filename := "comments-" + videoID + "." + format
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Status(http.StatusOK)
+
+	if err := write(c, videoID); err != nil {
+		log.Println("Error exporting comments:", err)
+	}
+}
+
+// Stream the comments as a JSON array, one element at a time
+func writeJSONExport(c *gin.Context, videoID string) error {
+	if _, err := c.Writer.WriteString("["); err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(c.Writer)
+	first := true
+	err := store.EachComment(c.Request.Context(), videoID, func(comment database.Comment) error {
+		if !first {
+			if _, err := c.Writer.WriteString(","); err != nil {
+				return err
+			}
+		}
+		first = false
+		return encoder.Encode(newExportedComment(comment))
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Writer.WriteString("]\n")
+	return err
+}
+
+// Stream the comments as CSV with a header row
+func writeCSVExport(c *gin.Context, videoID string) error {
+	w := csv.NewWriter(c.Writer)
+	if err := w.Write([]string{"id", "author", "text", "created_at", "score", "parent_id"}); err != nil {
+		return err
+	}
+
+	err := store.EachComment(c.Request.Context(), videoID, func(comment database.Comment) error {
+		parentID := ""
+		if comment.ParentID != nil {
+			parentID = strconv.FormatInt(*comment.ParentID, 10)
+		}
+		return w.Write([]string{
+			strconv.FormatInt(comment.ID, 10),
+			comment.Author,
+			comment.Text,
+			comment.CreatedAt.UTC().Format(time.RFC3339),
+			strconv.Itoa(comment.Score),
+			parentID,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	w.Flush()
+	return w.Error()
+}
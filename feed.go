@@ -0,0 +1,161 @@
+package main
+
+import (
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Number of comments in a video's feed
+	feedSize = 50
+
+	// Longest entry title, taken from the start of the comment
+	feedTitleLength = 60
+)
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published"`
+	Author    atomAuthor  `xml:"author"`
+	Link      atomLink    `xml:"link"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// Serve an Atom feed of the newest comments on a video
+func commentFeed(keys *keyring) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		videoID := c.Param("id")
+		ctx := c.Request.Context()
+
+		comments, err := store.LatestComments(ctx, videoID, feedSize)
+		if err != nil {
+			log.Println("Error loading comments for feed:", err)
+			c.String(http.StatusInternalServerError, "Failed to load comments.")
+			return
+		}
+
+		title := "Comments on " + videoID
+		if video, err := lookupVideo(ctx, keys, videoID); err != nil {
+			log.Println("Error fetching video title:", err)
+		} else {
+			title = "Comments on " + video.Title
+		}
+
+		selfURL := absoluteURL(c, "/video/"+videoID+"/comments.atom")
+		feed := atomFeed{
+			ID:    selfURL,
+			Title: title,
+			Links: []atomLink{
+				{Href: selfURL, Rel: "self", Type: "application/atom+xml"},
+				{Href: absoluteURL(c, "/video/"+videoID), Rel: "alternate", Type: "text/html"},
+			},
+		}
+
+		// The feed changed when its newest entry did, or now for an empty feed
+		var updated time.Time
+		for _, comment := range comments {
+			if t := commentUpdated(comment); t.After(updated) {
+				updated = t
+			}
+			feed.Entries = append(feed.Entries, newAtomEntry(c, comment))
+		}
+		if updated.IsZero() {
+			updated = time.Now()
+		}
+		feed.Updated = feedTime(updated)
+
+		out, err := xml.MarshalIndent(feed, "", "  ")
+		if err != nil {
+			log.Println("Error encoding feed:", err)
+			c.String(http.StatusInternalServerError, "Failed to build feed.")
+			return
+		}
+		c.Data(http.StatusOK, "application/atom+xml; charset=utf-8", append([]byte(xml.Header), out...))
+	}
+}
+
+func newAtomEntry(c *gin.Context, comment database.Comment) atomEntry {
+	permalink := absoluteURL(c, permalinkPath(comment.VideoID, comment.ID))
+	return atomEntry{
+		// The permalink never changes, so it doubles as the entry ID
+		ID:        permalink,
+		Title:     excerpt(comment.Text, feedTitleLength),
+		Updated:   feedTime(commentUpdated(comment)),
+		Published: feedTime(comment.CreatedAt),
+		Author:    atomAuthor{Name: cmp.Or(comment.Author, "Anonymous")},
+		Link:      atomLink{Href: permalink, Rel: "alternate", Type: "text/html"},
+		Content:   atomContent{Type: "text", Body: comment.Text},
+	}
+}
+
+// Shorten comment text to a single line of at most n characters
+func excerpt(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:n])) + "…"
+}
+
+// When a comment last changed
+func commentUpdated(comment database.Comment) time.Time {
+	if comment.EditedAt != nil {
+		return *comment.EditedAt
+	}
+	return comment.CreatedAt
+}
+
+func feedTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Path of the page showing a comment
+func permalinkPath(videoID string, id int64) string {
+	return fmt.Sprintf("/video/%s/comments/%d", videoID, id)
+}
+
+// Turn a path into a full URL on the host the request came in on
+func absoluteURL(c *gin.Context, path string) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + path
+}
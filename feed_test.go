@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the current output")
+
+// Times in a feed, which change from run to run
+var feedTimePattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z`)
+
+func TestCommentFeed(t *testing.T) {
+	useTestStore(t)
+	keys, _ := countingVideos(t)
+	first := postTestComment(t, "a", database.NewComment{Text: "First!", Author: "Ada"})
+	postTestComment(t, "b", database.NewComment{Text: "Tags like <b> & \"quotes\" are escaped, and this one is long enough to be cut short in its title"})
+	if err := store.UpdateComment(context.Background(), first, "First, edited", ""); err != nil {
+		t.Fatal(err)
+	}
+	postTestComment(t, "c", database.NewComment{VideoID: "otherVideo1", Text: "Elsewhere"})
+
+	router := testRouter(http.MethodGet, "/video/:id/comments.atom", commentFeed(keys))
+	w := serve(router, requestAs(http.MethodGet, "http://example.com/video/dQw4w9WgXcQ/comments.atom", ""))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/atom+xml; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	checkAtom(t, w.Body.Bytes())
+
+	got := feedTimePattern.ReplaceAll(w.Body.Bytes(), []byte("2006-01-02T15:04:05Z"))
+	golden := filepath.Join("testdata", "comments.atom.golden")
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("feed differs from %s, run with -update if that's intended:\n%s", golden, got)
+	}
+}
+
+// Check the rules of RFC 4287 that feed validators enforce on what the feed
+// can get wrong
+func checkAtom(t *testing.T, body []byte) {
+	t.Helper()
+	var feed struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string   `xml:"id"`
+		Title   string   `xml:"title"`
+		Updated string   `xml:"updated"`
+		Links   []struct {
+			Rel  string `xml:"rel,attr"`
+			Href string `xml:"href,attr"`
+		} `xml:"link"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Title   string `xml:"title"`
+			Updated string `xml:"updated"`
+			Author  struct {
+				Name string `xml:"name"`
+			} `xml:"author"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		t.Fatalf("feed isn't well-formed Atom: %v", err)
+	}
+	if feed.ID == "" || feed.Title == "" {
+		t.Error("feed needs an id and a title")
+	}
+	if _, err := time.Parse(time.RFC3339, feed.Updated); err != nil {
+		t.Errorf("feed updated %q isn't RFC 3339", feed.Updated)
+	}
+	var self bool
+	for _, link := range feed.Links {
+		self = self || link.Rel == "self"
+	}
+	if !self {
+		t.Error("feed needs a self link")
+	}
+	ids := map[string]bool{}
+	for _, entry := range feed.Entries {
+		if entry.ID == "" || entry.Title == "" || entry.Author.Name == "" {
+			t.Errorf("entry %+v needs an id, a title and an author", entry)
+		}
+		if ids[entry.ID] {
+			t.Errorf("entry id %s used twice", entry.ID)
+		}
+		ids[entry.ID] = true
+		if _, err := time.Parse(time.RFC3339, entry.Updated); err != nil {
+			t.Errorf("entry updated %q isn't RFC 3339", entry.Updated)
+		}
+	}
+}
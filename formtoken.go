@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/identity"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Signed one-time token on the comment forms, so resubmitting a form
+	// doesn't post the same comment twice
+	formTokenField = "form_token"
+	// How long a form can sit in a tab before its token is refused
+	formTokenTTL = 24 * time.Hour
+)
+
+// Result of checking a submission's form token
+type formTokenState int
+
+const (
+	formTokenFresh formTokenState = iota
+	formTokenUsed
+	formTokenInvalid
+)
+
+// Tokens already used, with when they expire. Expired tokens are refused
+// anyway, so they can be forgotten.
+var usedFormTokens = struct {
+	mu    sync.Mutex
+	spent map[string]time.Time
+}{spent: make(map[string]time.Time)}
+
+// New one-time token for a comment form
+func formToken() (string, error) {
+	nonce, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	return identity.Sign(secretKey, formTokenField, strconv.FormatInt(time.Now().Unix(), 10)+"."+nonce), nil
+}
+
+// Check the submission's form token, using it up if it is fresh
+func claimFormToken(c *gin.Context) formTokenState {
+	value, ok := identity.Verify(secretKey, formTokenField, c.PostForm(formTokenField))
+	if !ok {
+		return formTokenInvalid
+	}
+	issued, nonce, ok := strings.Cut(value, ".")
+	if !ok || nonce == "" {
+		return formTokenInvalid
+	}
+	unix, err := strconv.ParseInt(issued, 10, 64)
+	if err != nil {
+		return formTokenInvalid
+	}
+	expires := time.Unix(unix, 0).Add(formTokenTTL)
+	now := time.Now()
+	if now.After(expires) {
+		return formTokenInvalid
+	}
+
+	usedFormTokens.mu.Lock()
+	defer usedFormTokens.mu.Unlock()
+	for token, at := range usedFormTokens.spent {
+		if now.After(at) {
+			delete(usedFormTokens.spent, token)
+		}
+	}
+	if _, spent := usedFormTokens.spent[nonce]; spent {
+		return formTokenUsed
+	}
+	usedFormTokens.spent[nonce] = expires
+	return formTokenFresh
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/identity"
+)
+
+func TestDoubleSubmitPostsOnce(t *testing.T) {
+	useTestStore(t)
+	// A double click sends two forms with their own tokens
+	for range 2 {
+		if code := postComment(t, "clicker", commentForm("Posted  twice", time.Minute)); code != http.StatusOK {
+			t.Errorf("status = %d, want 200", code)
+		}
+	}
+	if n := countComments(t); n != 1 {
+		t.Errorf("%d comments posted, want 1", n)
+	}
+
+	// Someone else saying the same thing still gets to
+	if code := postComment(t, "agreer", commentForm("Posted twice", time.Minute)); code != http.StatusOK {
+		t.Errorf("status = %d, want 200", code)
+	}
+	if n := countComments(t); n != 2 {
+		t.Errorf("%d comments posted, want 2", n)
+	}
+}
+
+func TestResubmittedFormPostsOnce(t *testing.T) {
+	useTestStore(t)
+	form := commentForm("Refreshed the page", time.Minute)
+	for range 2 {
+		if code := postComment(t, "refresher", form); code != http.StatusOK {
+			t.Errorf("status = %d, want 200", code)
+		}
+		// A different comment under the same token
+		form.Set("comment", "Changed my mind")
+	}
+	if n := countComments(t); n != 1 {
+		t.Errorf("%d comments posted, want 1", n)
+	}
+
+	form.Set(formTokenField, form.Get(formTokenField)+"tampered")
+	if code := postComment(t, "refresher", form); code != http.StatusBadRequest {
+		t.Errorf("forged token: status = %d, want 400", code)
+	}
+}
+
+func TestFormTokenSignedForAnotherPurpose(t *testing.T) {
+	useTestStore(t)
+	value := strconv.FormatInt(time.Now().Unix(), 10) + ".nonce"
+	for _, purpose := range []string{"voter", formStampField, unsubscribePurpose} {
+		form := commentForm("Replayed", time.Minute)
+		form.Set(formTokenField, identity.Sign(secretKey, purpose, value))
+		if code := postComment(t, "replayer", form); code != http.StatusBadRequest {
+			t.Errorf("value signed for %s: status = %d, want 400", purpose, code)
+		}
+	}
+	if n := countComments(t); n != 0 {
+		t.Errorf("%d comments posted, want none", n)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/TanishkBansode/right-to-comment/identity"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	oauthStateCookie = "oauth_state"
+	// Time allowed for the round trip through Google's consent screen
+	oauthStateMaxAge = 10 * 60
+
+	googleUserInfoURL = "https://openidconnect.googleapis.com/v1/userinfo"
+)
+
+// OAuth client for signing in with Google, nil unless GOOGLE_CLIENT_ID,
+// GOOGLE_CLIENT_SECRET and GOOGLE_REDIRECT_URL are all set
+var googleOAuth *oauth2.Config
+
+// Characters other than these are dropped when turning a Google profile
+// name into a username
+var usernameUnsafe = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// Set up the Google OAuth client from the environment
+func loadGoogleOAuth() {
+	clientID := os.Getenv("GOOGLE_CLIENT_ID")
+	clientSecret := os.Getenv("GOOGLE_CLIENT_SECRET")
+	redirectURL := os.Getenv("GOOGLE_REDIRECT_URL")
+	if clientID == "" || clientSecret == "" || redirectURL == "" {
+		return
+	}
+
+	googleOAuth = &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Endpoint:     google.Endpoint,
+		Scopes:       []string{"openid", "profile"},
+	}
+}
+
+// Send the visitor to Google's consent screen. The state parameter is
+// remembered in a signed cookie so the callback can tell the request
+// started here.
+func googleLogin(c *gin.Context) {
+	nonce, err := randomToken()
+	if err != nil {
+		log.Println("Error generating OAuth state:", err)
+		c.String(http.StatusInternalServerError, "Failed to start Google sign-in.")
+		return
+	}
+
+	state := nonce + "|" + redirectTarget(c)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthStateCookie, identity.Sign(secretKey, oauthStateCookie, state), oauthStateMaxAge, "/auth/google", "", false, true)
+	c.Redirect(http.StatusFound, googleOAuth.AuthCodeURL(nonce))
+}
+
+// Profile fields read from Google's userinfo endpoint
+type googleProfile struct {
+	Sub     string `json:"sub"`
+	Name    string `json:"name"`
+	Picture string `json:"picture"`
+}
+
+// Finish signing in with Google: check the state, exchange the code, look
+// up the profile and log into the matching account
+func googleCallback(c *gin.Context) {
+	if reason := c.Query("error"); reason != "" {
+		log.Println("Google sign-in failed:", reason)
+		renderError(c, http.StatusBadRequest, "Google sign-in was cancelled or refused. You can try again or keep commenting anonymously.")
+		return
+	}
+
+	cookie, _ := c.Cookie(oauthStateCookie)
+	c.SetCookie(oauthStateCookie, "", -1, "/auth/google", "", false, true)
+	state, ok := identity.Verify(secretKey, oauthStateCookie, cookie)
+	nonce, next, _ := strings.Cut(state, "|")
+	if !ok || subtle.ConstantTimeCompare([]byte(nonce), []byte(c.Query("state"))) != 1 {
+		renderError(c, http.StatusBadRequest, "Your sign-in link expired or didn't come from this site. Please try again.")
+		return
+	}
+
+	profile, err := fetchGoogleProfile(c, c.Query("code"))
+	if err != nil {
+		log.Println("Error fetching Google profile:", err)
+		renderError(c, http.StatusBadGateway, "We couldn't reach Google to finish signing you in. Please try again.")
+		return
+	}
+
+	user, err := store.UpsertGoogleUser(c.Request.Context(), profile.Sub, googleUsername(profile.Name), profile.Picture)
+	if err != nil {
+		log.Println("Error saving Google user:", err)
+		c.String(http.StatusInternalServerError, "Failed to log in.")
+		return
+	}
+
+	if err := startSession(c, user.ID); err != nil {
+		log.Println("Error starting session:", err)
+		c.String(http.StatusInternalServerError, "Failed to log in.")
+		return
+	}
+	c.Redirect(http.StatusSeeOther, next)
+}
+
+func fetchGoogleProfile(c *gin.Context, code string) (googleProfile, error) {
+	ctx := c.Request.Context()
+	token, err := googleOAuth.Exchange(ctx, code)
+	if err != nil {
+		return googleProfile{}, err
+	}
+
+	resp, err := googleOAuth.Client(ctx, token).Get(googleUserInfoURL)
+	if err != nil {
+		return googleProfile{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return googleProfile{}, fmt.Errorf("userinfo returned %s", resp.Status)
+	}
+
+	var profile googleProfile
+	if err := json.NewDecoder(resp.Body).Decode(&profile); err != nil {
+		return googleProfile{}, err
+	}
+	if profile.Sub == "" {
+		return googleProfile{}, errors.New("userinfo response has no sub")
+	}
+	return profile, nil
+}
+
+// Turn a Google profile name into something that fits the username rules
+func googleUsername(name string) string {
+	username := usernameUnsafe.ReplaceAllString(strings.ReplaceAll(strings.TrimSpace(name), " ", "_"), "")
+	if len(username) > 26 {
+		username = username[:26]
+	}
+	if len(username) < 3 {
+		username = "user"
+	}
+	return username
+}
+
+// Show a full-page error for flows that can't fall back to a form
+func renderError(c *gin.Context, status int, message string) {
+	c.HTML(status, "error.html", gin.H{"Message": message})
+}
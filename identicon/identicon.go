@@ -0,0 +1,55 @@
+// Package identicon draws small symmetric block avatars. The same key always
+// produces the same image, so a commenter keeps their avatar without having
+// to upload one.
+package identicon
+
+import (
+	"crypto/sha256"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// Cells across and down the pattern
+const grid = 5
+
+var background = color.RGBA{0xf0, 0xf0, 0xf0, 0xff}
+
+// Draw the identicon for key as a size by size image
+func New(key string, size int) *image.Paletted {
+	sum := sha256.Sum256([]byte(key))
+
+	// Keep every channel away from white so the blocks stand out
+	foreground := color.RGBA{0x30 + sum[0]%0xa0, 0x30 + sum[1]%0xa0, 0x30 + sum[2]%0xa0, 0xff}
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{background, foreground})
+
+	// Half a cell of margin on each side
+	cell := size / (grid + 1)
+	margin := (size - cell*grid) / 2
+
+	for row := 0; row < grid; row++ {
+		// The left columns decide the pattern and are mirrored onto the right
+		for col := 0; col < (grid+1)/2; col++ {
+			if sum[3+row*3+col]&1 == 0 {
+				continue
+			}
+			fill(img, margin+col*cell, margin+row*cell, cell)
+			fill(img, margin+(grid-1-col)*cell, margin+row*cell, cell)
+		}
+	}
+	return img
+}
+
+func fill(img *image.Paletted, x, y, cell int) {
+	for dy := 0; dy < cell; dy++ {
+		for dx := 0; dx < cell; dx++ {
+			img.SetColorIndex(x+dx, y+dy, 1)
+		}
+	}
+}
+
+// Write the identicon for key to w as a PNG
+func WritePNG(w io.Writer, key string, size int) error {
+	return png.Encode(w, New(key, size))
+}
@@ -0,0 +1,54 @@
+package identicon
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden images with the current output")
+
+func TestGolden(t *testing.T) {
+	for _, key := range []string{"0123456789abcdef0123456789abcdef", "fedcba9876543210fedcba9876543210"} {
+		var got bytes.Buffer
+		if err := WritePNG(&got, key, 64); err != nil {
+			t.Fatal(err)
+		}
+		golden := filepath.Join("testdata", key+".png")
+		if *update {
+			if err := os.WriteFile(golden, got.Bytes(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Bytes(), want) {
+			t.Errorf("identicon for %s differs from %s, run with -update if that's intended", key, golden)
+		}
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	a, b := New("same key", 64), New("same key", 64)
+	if !bytes.Equal(a.Pix, b.Pix) || a.Palette[1] != b.Palette[1] {
+		t.Error("the same key drew different images")
+	}
+	if c := New("other key", 64); bytes.Equal(a.Pix, c.Pix) && a.Palette[1] == c.Palette[1] {
+		t.Error("different keys drew the same image")
+	}
+}
+
+func TestSymmetric(t *testing.T) {
+	img := New("symmetric", 60)
+	for y := 0; y < 60; y++ {
+		for x := 0; x < 30; x++ {
+			if img.ColorIndexAt(x, y) != img.ColorIndexAt(59-x, y) {
+				t.Fatalf("pixel %d,%d doesn't mirror", x, y)
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Package identity gives every browser a stable anonymous ID, carried in a
+// cookie signed with a server secret so it can't be forged.
+package identity
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Named after the cookie that first held the ID, so earlier visitors keep theirs
+	cookieName = "voter"
+	cookieAge  = 365 * 24 * 60 * 60
+	// What the cookie's signature is for, see Sign
+	cookiePurpose = "voter"
+
+	contextKey = "identity"
+)
+
+// Append an HMAC of the value so it can't be forged by the client. The
+// value is prefixed with what it's for, like "voter:", so a value signed for
+// one purpose can't be passed off as one for another. Purposes don't contain
+// colons.
+func Sign(key []byte, purpose, value string) string {
+	value = purpose + ":" + value
+	return value + "." + mac(key, value)
+}
+
+// Return the original value if the signature matches and it was signed for
+// the purpose
+func Verify(key []byte, purpose, signed string) (string, bool) {
+	value, ok := verify(key, signed)
+	if !ok {
+		return "", false
+	}
+	value, ok = strings.CutPrefix(value, purpose+":")
+	if !ok {
+		return "", false
+	}
+	return value, true
+}
+
+func verify(key []byte, signed string) (string, bool) {
+	i := strings.LastIndex(signed, ".")
+	if i < 0 {
+		return "", false
+	}
+	value, sum := signed[:i], signed[i+1:]
+	if !hmac.Equal([]byte(sum), []byte(mac(key, value))) {
+		return "", false
+	}
+	return value, true
+}
+
+func mac(key []byte, value string) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(value))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Make the browser's ID available to handlers, issuing a fresh one when the
+// cookie is missing or has been tampered with
+func Middleware(key []byte) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, renew := "", false
+		if cookie, err := c.Cookie(cookieName); err == nil {
+			id, _ = Verify(key, cookiePurpose, cookie)
+			// Cookies signed before signatures had a purpose hold a bare ID,
+			// which nothing else signed looks like. They get a new signature.
+			if legacy, ok := verify(key, cookie); id == "" && ok && isID(legacy) {
+				id, renew = legacy, true
+			}
+		}
+
+		if id == "" {
+			b := make([]byte, 32)
+			if _, err := rand.Read(b); err != nil {
+				log.Println("Error generating identity:", err)
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+			id, renew = hex.EncodeToString(b), true
+		}
+		if renew {
+			c.SetSameSite(http.SameSiteLaxMode)
+			c.SetCookie(cookieName, Sign(key, cookiePurpose, id), cookieAge, "/", "", false, true)
+		}
+
+		c.Set(contextKey, id)
+		c.Next()
+	}
+}
+
+// Whether the value is an ID as Middleware makes them, 64 hex digits
+func isID(value string) bool {
+	_, err := hex.DecodeString(value)
+	return len(value) == 64 && err == nil
+}
+
+// The ID of the browser making the request, set by Middleware
+func FromContext(c *gin.Context) string {
+	return c.GetString(contextKey)
+}
+
+// Hash of an ID, stored on comments so a database leak can't be used to
+// act as their authors
+func Hash(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
+
+// Keyed hash of a client IP address, stored with comments and bans so
+// moderators can tell posts from the same address apart from others without
+// the address itself being kept. Without the key the hash can't be matched
+// against a guessed address.
+func HashIP(key []byte, ip string) string {
+	return mac(key, "ip:"+ip)
+}
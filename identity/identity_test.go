@@ -0,0 +1,146 @@
+package identity
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+var key = []byte("test secret")
+
+func TestSignVerify(t *testing.T) {
+	signed := Sign(key, "test", "abc123")
+	value, ok := Verify(key, "test", signed)
+	if !ok || value != "abc123" {
+		t.Errorf("Verify(Sign(abc123)) = %q, %v", value, ok)
+	}
+}
+
+func TestVerifyChecksPurpose(t *testing.T) {
+	// A signed ID can't be replayed as a form token, or the other way round
+	if value, ok := Verify(key, "form_token", Sign(key, cookiePurpose, "abc123")); ok {
+		t.Errorf("voter ID accepted as a form token: %q", value)
+	}
+	if value, ok := Verify(key, cookiePurpose, Sign(key, "form_token", "1700000000.abc")); ok {
+		t.Errorf("form token accepted as a voter ID: %q", value)
+	}
+	if value, ok := Verify(key, "erase", Sign(key, "eraser", "1")); ok {
+		t.Errorf("value signed for eraser accepted for erase: %q", value)
+	}
+}
+
+func TestVerifyRejectsForgeries(t *testing.T) {
+	signed := Sign(key, "test", "abc123")
+	_, sum, _ := strings.Cut(signed, ".")
+	changed := signed[:len(signed)-1] + "0"
+	if changed == signed {
+		changed = signed[:len(signed)-1] + "1"
+	}
+	forgeries := map[string]string{
+		"other value":   "test:xyz789." + sum,
+		"other key":     Sign([]byte("another secret"), "test", "abc123"),
+		"changed sum":   changed,
+		"no signature":  "test:abc123",
+		"empty sum":     "test:abc123.",
+		"empty":         "",
+		"appended text": signed + "x",
+	}
+	for name, forged := range forgeries {
+		if value, ok := Verify(key, "test", forged); ok {
+			t.Errorf("%s: Verify(%q) = %q, accepted", name, forged, value)
+		}
+	}
+}
+
+// The identity a request gets, and the cookie set for it if any
+func identify(cookie string) (string, string) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware(key))
+	var id string
+	router.GET("/", func(c *gin.Context) { id = FromContext(c) })
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if cookie != "" {
+		r.AddCookie(&http.Cookie{Name: cookieName, Value: cookie})
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return id, w.Header().Get("Set-Cookie")
+}
+
+func TestMiddleware(t *testing.T) {
+	id, setCookie := identify("")
+	if len(id) != 64 {
+		t.Fatalf("new identity = %q, want 64 hex digits", id)
+	}
+	for _, attr := range []string{"HttpOnly", "SameSite=Lax", cookieName + "=" + url.QueryEscape(Sign(key, cookiePurpose, id))} {
+		if !strings.Contains(setCookie, attr) {
+			t.Errorf("Set-Cookie = %q, missing %q", setCookie, attr)
+		}
+	}
+
+	// The browser keeps its identity
+	again, setCookie := identify(Sign(key, cookiePurpose, id))
+	if again != id || setCookie != "" {
+		t.Errorf("returning visitor got %q and cookie %q, want %q and none", again, setCookie, id)
+	}
+
+	// A tampered cookie gets a fresh identity rather than the one it claims
+	forged, setCookie := identify(Sign([]byte("guessed"), cookiePurpose, "victim"))
+	if forged == "victim" || forged == "" || setCookie == "" {
+		t.Errorf("forged cookie got identity %q and cookie %q", forged, setCookie)
+	}
+
+	// As does a value signed for something else
+	other, _ := identify(Sign(key, "form_token", "victim"))
+	if other == "victim" || other == "" {
+		t.Errorf("form token as the cookie got identity %q", other)
+	}
+}
+
+func TestMiddlewareRenewsLegacyCookies(t *testing.T) {
+	id := strings.Repeat("ab", 32)
+	// Signed the way cookies were before they had a purpose
+	legacy := id + "." + mac(key, id)
+	got, setCookie := identify(legacy)
+	if got != id {
+		t.Errorf("legacy cookie got identity %q, want %q", got, id)
+	}
+	if !strings.Contains(setCookie, cookieName+"="+url.QueryEscape(Sign(key, cookiePurpose, id))) {
+		t.Errorf("Set-Cookie = %q, want the ID signed again", setCookie)
+	}
+
+	// Only bare IDs are taken from them
+	for _, value := range []string{"1700000000", "erase:" + id, id[:62]} {
+		if got, _ := identify(value + "." + mac(key, value)); got == value {
+			t.Errorf("legacy signature of %q got identity %q", value, got)
+		}
+	}
+}
+
+func TestHash(t *testing.T) {
+	if Hash("a") == Hash("b") || Hash("a") != Hash("a") || Hash("a") == "a" {
+		t.Error("Hash isn't a stable one-way hash")
+	}
+	if HashIP(key, "192.0.2.1") == HashIP([]byte("other"), "192.0.2.1") {
+		t.Error("HashIP doesn't depend on the key")
+	}
+}
+
+func TestHashIP(t *testing.T) {
+	hash := HashIP(key, "192.0.2.1")
+	if HashIP(key, "192.0.2.1") != hash {
+		t.Error("the same IP hashed differently")
+	}
+	if HashIP(key, "192.0.2.2") == hash {
+		t.Error("different IPs hashed the same")
+	}
+	if strings.Contains(hash, "192.0.2.1") || hash == Hash("192.0.2.1") {
+		t.Error("hash reveals the IP")
+	}
+}
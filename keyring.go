@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	apikey "google.golang.org/api/googleapi/transport"
+	"google.golang.org/api/option"
+	"google.golang.org/api/youtube/v3"
+)
+
+// YouTube API keys, from YOUTUBE_API_KEYS and YOUTUBE_API_KEY, each with a
+// service made once and shared by every request. Each key has its own
+// project's quota, so when one runs out the next is used until the quota day
+// ends.
+type keyring struct {
+	mu       sync.Mutex
+	keys     []string
+	services []*youtube.Service
+	current  int
+	// Quota day each key ran out on, by its index
+	exhausted map[int]string
+}
+
+// A keyring of the non-empty keys given, in order, without duplicates, whose
+// services make their requests through the transport
+func newKeyring(ctx context.Context, transport http.RoundTripper, keys ...string) (*keyring, error) {
+	k := &keyring{exhausted: map[int]string{}}
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" || slices.Contains(k.keys, key) {
+			continue
+		}
+		// Searches and lookups have shorter deadlines of their own, this
+		// only stops a request hanging for good
+		client := &http.Client{
+			Transport: &apikey.APIKey{Key: key, Transport: transport},
+			Timeout:   time.Minute,
+		}
+		service, err := youtube.NewService(ctx, option.WithHTTPClient(client))
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", len(k.keys)+1, err)
+		}
+		k.keys = append(k.keys, key)
+		k.services = append(k.services, service)
+	}
+	return k, nil
+}
+
+// The transport YouTube API requests share, keeping connections open between
+// them
+func youtubeTransport() http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 16
+	transport.ResponseHeaderTimeout = 30 * time.Second
+	return transport
+}
+
+// How many keys there are
+func (k *keyring) Len() int {
+	return len(k.keys)
+}
+
+// The index and service of the key to use now, the current one unless it ran
+// out today. False when every key has.
+func (k *keyring) Service() (int, *youtube.Service, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	day := quotaDay(time.Now())
+	for range k.keys {
+		if k.exhausted[k.current] != day {
+			return k.current, k.services[k.current], true
+		}
+		k.current = (k.current + 1) % len(k.keys)
+	}
+	return 0, nil, false
+}
+
+// Mark the key at the index as out of quota for the rest of the day and move
+// on to the next
+func (k *keyring) Exhaust(i int) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.exhausted[i] = quotaDay(time.Now())
+	if i == k.current {
+		k.current = (k.current + 1) % len(k.keys)
+	}
+	log.Printf("YouTube API key %d of %d ran out of quota", i+1, len(k.keys))
+}
+
+// How many keys haven't run out today
+func (k *keyring) Left() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	day := quotaDay(time.Now())
+	left := len(k.keys)
+	for _, exhausted := range k.exhausted {
+		if exhausted == day {
+			left--
+		}
+	}
+	return left
+}
+
+// Make calls to the YouTube API through the current key's service. When that
+// key's quota runs out the calls are made once more with the next key. With
+// every key out of quota the error wraps errYouTubeQuota.
+func withYouTube(ctx context.Context, keys *keyring, call func(*youtube.Service) error) error {
+	var err error
+	for range 2 {
+		i, service, ok := keys.Service()
+		if !ok {
+			return fmt.Errorf("%w: every API key has run out", errYouTubeQuota)
+		}
+		err = call(service)
+		if !quotaExceeded(err) {
+			return err
+		}
+		keys.Exhaust(i)
+	}
+	return err
+}
+
+// Whether the error is YouTube saying the key's daily quota is spent, rather
+// than a rate limit that passes
+func quotaExceeded(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return slices.ContainsFunc(apiErr.Errors, func(item googleapi.ErrorItem) bool { return item.Reason == "quotaExceeded" })
+}
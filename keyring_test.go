@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// A keyring of the keys whose calls YouTube answers as out of quota while
+// the key is in spent. Calls with any other key succeed.
+func rotatingKeyring(t *testing.T, spent *sync.Map, keys ...string) (*keyring, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	k, err := newKeyring(context.Background(), fakeTransport(func(r *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		if _, ok := spent.Load(r.URL.Query().Get("key")); ok {
+			return youtubeError(http.StatusForbidden, "quotaExceeded"), nil
+		}
+		return jsonResponse(http.StatusOK, map[string]any{"items": []any{}}), nil
+	}), keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k, &calls
+}
+
+func listVideos(ctx context.Context, keys *keyring) error {
+	return withYouTube(ctx, keys, func(service *youtube.Service) error {
+		_, err := service.Videos.List([]string{"id"}).Id("dQw4w9WgXcQ").Context(ctx).Do()
+		return err
+	})
+}
+
+func TestKeyringSkipsDuplicatesAndBlanks(t *testing.T) {
+	k, _ := rotatingKeyring(t, &sync.Map{}, "a", " ", "b", "a", " c ")
+	if k.Len() != 3 || k.Left() != 3 {
+		t.Errorf("Len = %d, Left = %d, want 3 keys", k.Len(), k.Left())
+	}
+}
+
+func TestKeyringSequentialExhaustion(t *testing.T) {
+	ctx := context.Background()
+	var spent sync.Map
+	keys, calls := rotatingKeyring(t, &spent, "a", "b", "c")
+
+	if err := listVideos(ctx, keys); err != nil || calls.Load() != 1 {
+		t.Fatalf("first call: %v after %d requests", err, calls.Load())
+	}
+
+	// The first key running out is retried once with the second
+	spent.Store("a", true)
+	calls.Store(0)
+	if err := listVideos(ctx, keys); err != nil || calls.Load() != 2 {
+		t.Errorf("after a ran out: %v after %d requests, want success after 2", err, calls.Load())
+	}
+	if keys.Left() != 2 {
+		t.Errorf("%d keys left, want 2", keys.Left())
+	}
+
+	// Later calls start at the second key
+	calls.Store(0)
+	if err := listVideos(ctx, keys); err != nil || calls.Load() != 1 {
+		t.Errorf("next call: %v after %d requests, want success after 1", err, calls.Load())
+	}
+
+	// Only one retry, even when the next key has run out too
+	spent.Store("b", true)
+	spent.Store("c", true)
+	calls.Store(0)
+	if err := listVideos(ctx, keys); !quotaExceeded(err) || calls.Load() != 2 {
+		t.Errorf("b and c spent: %v after %d requests, want quotaExceeded after 2", err, calls.Load())
+	}
+
+	// With every key spent no request is made
+	calls.Store(0)
+	if err := listVideos(ctx, keys); !errors.Is(err, errYouTubeQuota) || calls.Load() != 0 {
+		t.Errorf("all spent: %v after %d requests, want errYouTubeQuota without asking", err, calls.Load())
+	}
+	if keys.Left() != 0 {
+		t.Errorf("%d keys left, want 0", keys.Left())
+	}
+}
+
+func TestKeyringExhaustedKeysReturnNextDay(t *testing.T) {
+	keys, _ := rotatingKeyring(t, &sync.Map{}, "a", "b")
+	keys.Exhaust(0)
+	keys.Exhaust(1)
+	if _, _, ok := keys.Service(); ok {
+		t.Fatal("service given with every key spent")
+	}
+	// As if they ran out on an earlier quota day
+	keys.mu.Lock()
+	keys.exhausted[0], keys.exhausted[1] = "2000-01-01", "2000-01-01"
+	keys.mu.Unlock()
+	if i, _, ok := keys.Service(); !ok || keys.Left() != 2 {
+		t.Errorf("Service = %d, %v with %d left, want both keys back", i, ok, keys.Left())
+	}
+}
+
+func TestKeyringConcurrentUse(t *testing.T) {
+	keys, _ := rotatingKeyring(t, &sync.Map{}, "a", "b", "c", "d")
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				keys.Service()
+				keys.Left()
+			}
+			keys.Exhaust(i)
+		}()
+	}
+	wg.Wait()
+	if _, _, ok := keys.Service(); ok || keys.Left() != 0 {
+		t.Errorf("%d keys left after exhausting all of them", keys.Left())
+	}
+}
+
+func TestKeyringReusesServices(t *testing.T) {
+	useTestStore(t)
+	resetQuotaUsage(t)
+	var requests atomic.Int32
+	keys := testKeyring(t, func(r *http.Request) (*http.Response, error) {
+		requests.Add(1)
+		return searchStub("dQw4w9WgXcQ")(r)
+	})
+
+	_, first, _ := keys.Service()
+	for _, query := range []string{"cats", "dogs", "birds"} {
+		if _, err := searchYouTube(context.Background(), keys, searchOptions{Query: query, Type: "videos", Duration: "any"}, ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, service, _ := keys.Service(); service != first {
+			t.Fatal("service made again")
+		}
+	}
+	// Every call went through the transport the keyring was made with
+	if requests.Load() != 6 {
+		t.Errorf("transport saw %d requests, want 6 for three searches and their details", requests.Load())
+	}
+}
+
+func TestYouTubeTransport(t *testing.T) {
+	transport, ok := youtubeTransport().(*http.Transport)
+	if !ok {
+		t.Fatalf("transport is a %T", youtubeTransport())
+	}
+	if transport == http.DefaultTransport {
+		t.Error("the default transport is shared, not cloned")
+	}
+	if transport.MaxIdleConnsPerHost < 2 || transport.ResponseHeaderTimeout == 0 {
+		t.Errorf("%d idle connections per host and %s header timeout, want connections kept and a timeout",
+			transport.MaxIdleConnsPerHost, transport.ResponseHeaderTimeout)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"regexp"
+
+	"github.com/TanishkBansode/right-to-comment/langdetect"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Comments whose language is detected per backfill transaction
+const langBackfillBatch = 500
+
+// Classifies comments as they are posted and edited, switched off with
+// LANGUAGE_DETECTION=false
+var langDetector langdetect.Detector = langdetect.New()
+
+// Values accepted for the lang filter
+var langCode = regexp.MustCompile(`^(?:[a-z]{2}|und)$`)
+
+// Detect the language of comments stored before detection was added
+func backfillLangs(c *gin.Context) {
+	ctx := c.Request.Context()
+	total := 0
+	for {
+		comments, err := store.CommentsWithoutLang(ctx, langBackfillBatch)
+		if err != nil {
+			log.Println("Error loading comments for language backfill:", err)
+			c.String(http.StatusInternalServerError, "Failed to detect comment languages.")
+			return
+		}
+		if len(comments) == 0 {
+			break
+		}
+
+		langs := make(map[int64]string, len(comments))
+		for _, comment := range comments {
+			langs[comment.ID] = langDetector.Detect(comment.Text)
+		}
+		if err := store.SetCommentLangs(ctx, langs); err != nil {
+			log.Println("Error storing comment languages:", err)
+			c.String(http.StatusInternalServerError, "Failed to detect comment languages.")
+			return
+		}
+		total += len(comments)
+	}
+	audit(c, "backfill-langs", "site", "languages", c.PostForm("note"))
+	c.String(http.StatusOK, "Detected the language of %d comments.", total)
+}
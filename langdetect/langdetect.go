@@ -0,0 +1,185 @@
+// Package langdetect guesses the language of comment text, by script for
+// languages with their own alphabet and by letter trigrams for the rest.
+package langdetect
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Code for text too short or too mixed to tell
+const Undetermined = "und"
+
+const (
+	// Texts with fewer letters than this are Undetermined
+	minLetters = 12
+	// Trigrams kept in each language profile
+	profileSize = 300
+	// Share of letters a script needs before it decides the language alone
+	scriptShare = 0.5
+)
+
+// Guesses the ISO 639-1 code of a text, or Undetermined
+type Detector interface {
+	Detect(text string) string
+}
+
+// A Detector that never guesses, for when detection is switched off
+type Off struct{}
+
+func (Off) Detect(string) string {
+	return Undetermined
+}
+
+// Detector comparing trigram frequencies with built-in profiles
+type NGram struct {
+	// Rank of each trigram in each language, most frequent first
+	profiles map[string]map[string]int
+}
+
+// Build the detector from the sample text of every supported language
+func New() *NGram {
+	d := &NGram{profiles: make(map[string]map[string]int, len(samples))}
+	for lang, text := range samples {
+		d.profiles[lang] = rankTrigrams(text)
+	}
+	return d
+}
+
+func (d *NGram) Detect(text string) string {
+	if lang, ok := byScript(text); ok {
+		return lang
+	}
+
+	ranks := rankTrigrams(text)
+	if len(ranks) == 0 {
+		return Undetermined
+	}
+
+	// Out-of-place distance: how far each trigram's rank is from its rank in
+	// the profile, with missing trigrams counting as far as possible
+	best, bestDistance, secondDistance := Undetermined, -1, -1
+	for lang, profile := range d.profiles {
+		distance := 0
+		for trigram, rank := range ranks {
+			if profileRank, ok := profile[trigram]; ok {
+				distance += abs(rank - profileRank)
+			} else {
+				distance += profileSize
+			}
+		}
+		switch {
+		case bestDistance < 0 || distance < bestDistance:
+			best, bestDistance, secondDistance = lang, distance, bestDistance
+		case secondDistance < 0 || distance < secondDistance:
+			secondDistance = distance
+		}
+	}
+
+	// Too close a call between two languages, or nothing in common with any
+	worst := len(ranks) * profileSize
+	if bestDistance >= worst*95/100 || (secondDistance >= 0 && secondDistance-bestDistance < len(ranks)) {
+		return Undetermined
+	}
+	return best
+}
+
+// Scripts that each belong to a single language here
+var scripts = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Cyrillic, "ru"},
+	{unicode.Greek, "el"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Hangul, "ko"},
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Han, "zh"},
+	{unicode.Thai, "th"},
+}
+
+// Decide by alphabet when most letters are in one of the scripts above.
+// Japanese mixes kana with Han characters, so any kana make it Japanese.
+func byScript(text string) (string, bool) {
+	counts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, s := range scripts {
+			if unicode.Is(s.table, r) {
+				counts[s.lang]++
+				break
+			}
+		}
+	}
+	if counts["ja"] > 0 {
+		counts["ja"] += counts["zh"]
+		delete(counts, "zh")
+	}
+
+	// CJK text packs a word into a character or two, so it needs fewer
+	need := minLetters
+	if counts["ja"]+counts["zh"]+counts["ko"] > 0 {
+		need = minLetters / 3
+	}
+	if letters < need {
+		return Undetermined, true
+	}
+
+	for lang, n := range counts {
+		if float64(n) > scriptShare*float64(letters) {
+			if lang == "ru" && strings.ContainsAny(strings.ToLower(text), "іїєґ") {
+				return "uk", true
+			}
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+// Rank the trigrams of the lowercased words of the text, most frequent
+// first, keeping at most profileSize. Words are padded with spaces so their
+// first and last letters count too.
+func rankTrigrams(text string) map[string]int {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		runes := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			counts[string(runes[i:i+3])]++
+		}
+	}
+
+	trigrams := make([]string, 0, len(counts))
+	for trigram := range counts {
+		trigrams = append(trigrams, trigram)
+	}
+	sort.Slice(trigrams, func(i, j int) bool {
+		if counts[trigrams[i]] != counts[trigrams[j]] {
+			return counts[trigrams[i]] > counts[trigrams[j]]
+		}
+		return trigrams[i] < trigrams[j]
+	})
+	if len(trigrams) > profileSize {
+		trigrams = trigrams[:profileSize]
+	}
+
+	ranks := make(map[string]int, len(trigrams))
+	for i, trigram := range trigrams {
+		ranks[trigram] = i
+	}
+	return ranks
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
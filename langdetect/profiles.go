@@ -0,0 +1,82 @@
+package langdetect
+
+// Everyday text in each supported Latin-script language. The profiles are
+// built from these at startup, so adding a language is a matter of adding a
+// few paragraphs of typical text.
+var samples = map[string]string{
+	"en": `This is one of the best videos I have seen in a long time. Thank you for
+sharing it with us, and please keep making more like this. I watched it twice
+with my family and we all learned something new. The part about the history of
+the city was really interesting, but I think the music was a little too loud.
+What camera do you use? The picture quality is amazing. I would love to see a
+follow up where you talk about what happened after that. Does anyone know the
+name of the song at the end? Great work, everyone should watch this. It is
+always nice when people take the time to explain things clearly and without
+rushing. I have been waiting for this video all week and it was worth it.`,
+
+	"es": `Este es uno de los mejores videos que he visto en mucho tiempo. Gracias por
+compartirlo con nosotros, y por favor sigue haciendo más como este. Lo vi dos
+veces con mi familia y todos aprendimos algo nuevo. La parte sobre la historia
+de la ciudad fue muy interesante, pero creo que la música estaba un poco alta.
+¿Qué cámara usas? La calidad de la imagen es increíble. Me encantaría ver una
+continuación donde hables de lo que pasó después. ¿Alguien sabe el nombre de la
+canción del final? Buen trabajo, todo el mundo debería verlo. Siempre es bueno
+cuando la gente se toma el tiempo de explicar las cosas con claridad y sin
+prisa. He estado esperando este video toda la semana y valió la pena.`,
+
+	"fr": `C'est une des meilleures vidéos que j'ai vues depuis longtemps. Merci de
+l'avoir partagée avec nous, et s'il te plaît continue à en faire d'autres comme
+celle-ci. Je l'ai regardée deux fois avec ma famille et nous avons tous appris
+quelque chose de nouveau. La partie sur l'histoire de la ville était vraiment
+intéressante, mais je trouve que la musique était un peu trop forte. Quelle
+caméra utilises-tu ? La qualité de l'image est incroyable. J'aimerais beaucoup
+voir une suite où tu parles de ce qui s'est passé ensuite. Est-ce que quelqu'un
+connaît le nom de la chanson à la fin ? Beau travail, tout le monde devrait la
+regarder. C'est toujours agréable quand les gens prennent le temps d'expliquer
+les choses clairement et sans se presser. J'attendais cette vidéo toute la
+semaine et ça valait le coup.`,
+
+	"de": `Das ist eines der besten Videos, die ich seit langer Zeit gesehen habe.
+Danke, dass du es mit uns teilst, und bitte mach weiter so. Ich habe es zweimal
+mit meiner Familie angeschaut und wir haben alle etwas Neues gelernt. Der Teil
+über die Geschichte der Stadt war wirklich interessant, aber ich finde, die
+Musik war ein bisschen zu laut. Welche Kamera benutzt du? Die Bildqualität ist
+unglaublich. Ich würde gerne eine Fortsetzung sehen, in der du erzählst, was
+danach passiert ist. Weiß jemand, wie das Lied am Ende heißt? Gute Arbeit, das
+sollte sich jeder ansehen. Es ist immer schön, wenn sich Leute die Zeit nehmen,
+die Dinge klar und ohne Eile zu erklären. Ich habe die ganze Woche auf dieses
+Video gewartet und es hat sich gelohnt.`,
+
+	"it": `Questo è uno dei migliori video che abbia visto da molto tempo. Grazie per
+averlo condiviso con noi, e per favore continua a farne altri come questo. L'ho
+guardato due volte con la mia famiglia e abbiamo tutti imparato qualcosa di
+nuovo. La parte sulla storia della città era davvero interessante, ma secondo
+me la musica era un po' troppo alta. Che fotocamera usi? La qualità
+dell'immagine è incredibile. Mi piacerebbe vedere un seguito in cui parli di
+quello che è successo dopo. Qualcuno sa il nome della canzone alla fine? Ottimo
+lavoro, tutti dovrebbero guardarlo. È sempre bello quando le persone si
+prendono il tempo di spiegare le cose con chiarezza e senza fretta. Ho aspettato
+questo video tutta la settimana e ne è valsa la pena.`,
+
+	"pt": `Este é um dos melhores vídeos que eu vi em muito tempo. Obrigado por
+compartilhar com a gente, e por favor continue fazendo mais como este. Eu
+assisti duas vezes com a minha família e todos nós aprendemos algo novo. A
+parte sobre a história da cidade foi muito interessante, mas acho que a música
+estava um pouco alta demais. Qual câmera você usa? A qualidade da imagem é
+incrível. Eu adoraria ver uma continuação em que você fala sobre o que
+aconteceu depois. Alguém sabe o nome da música do final? Ótimo trabalho, todo
+mundo deveria assistir. É sempre bom quando as pessoas dedicam tempo para
+explicar as coisas com clareza e sem pressa. Eu estava esperando este vídeo a
+semana toda e valeu a pena.`,
+
+	"nl": `Dit is een van de beste video's die ik in lange tijd heb gezien. Bedankt
+dat je hem met ons deelt, en ga alsjeblieft door met meer van dit soort video's.
+Ik heb hem twee keer met mijn familie bekeken en we hebben allemaal iets nieuws
+geleerd. Het stuk over de geschiedenis van de stad was echt interessant, maar
+ik vind dat de muziek een beetje te hard stond. Welke camera gebruik je? De
+beeldkwaliteit is ongelooflijk. Ik zou graag een vervolg zien waarin je vertelt
+wat er daarna gebeurde. Weet iemand hoe het liedje aan het einde heet? Goed
+gedaan, iedereen zou dit moeten zien. Het is altijd fijn als mensen de tijd
+nemen om dingen duidelijk en zonder haast uit te leggen. Ik heb de hele week op
+deze video gewacht en het was het waard.`,
+}
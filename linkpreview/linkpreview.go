@@ -0,0 +1,243 @@
+// Package linkpreview fetches the title and description of pages linked
+// from comments, refusing to connect to private or local addresses so
+// comments can't be used to probe the server's network.
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	timeout = 5 * time.Second
+	// Most of a page read while looking for its title
+	maxBodySize  = 512 << 10
+	maxRedirects = 3
+	// Longest title and description kept, in characters
+	maxTitleLength       = 200
+	maxDescriptionLength = 300
+)
+
+var (
+	ErrNotHTML   = errors.New("not an HTML page")
+	ErrNoTitle   = errors.New("page has no title")
+	ErrForbidden = errors.New("address not allowed")
+	ErrBadScheme = errors.New("only http and https links are previewed")
+)
+
+// Carrier-grade NAT space, which net.IP.IsPrivate doesn't cover
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// Links in text that could get a preview, up to max of them
+var linkPattern = regexp.MustCompile(`https?://[^\s<>"'()\[\]]+`)
+
+// What a page says about itself
+type Preview struct {
+	URL         string
+	Title       string
+	Description string
+}
+
+// Fetcher fetches previews over a client that only connects to public
+// addresses. The check happens when connecting, after DNS resolution, so a
+// hostname resolving to a private address is refused too.
+type Fetcher struct {
+	client *http.Client
+}
+
+func New() *Fetcher {
+	dialer := &net.Dialer{Timeout: timeout, Control: refusePrivate}
+	transport := &http.Transport{
+		// A proxy would be dialed instead of the page, defeating the check
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+	return &Fetcher{client: &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return ErrBadScheme
+			}
+			return nil
+		},
+	}}
+}
+
+// Links in the text eligible for a preview, in order and without repeats
+func Links(text string, max int) []string {
+	var links []string
+	for _, link := range linkPattern.FindAllString(text, -1) {
+		// Punctuation right after a link usually ends the sentence
+		link = strings.TrimRight(link, ".,;:!?")
+		if !eligible(link) || slices.Contains(links, link) {
+			continue
+		}
+		links = append(links, link)
+		if len(links) == max {
+			break
+		}
+	}
+	return links
+}
+
+func eligible(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.User == nil
+}
+
+// Fetch the page and read its title and description
+func (f *Fetcher) Fetch(ctx context.Context, link string) (Preview, error) {
+	if !eligible(link) {
+		return Preview{}, ErrBadScheme
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return Preview{}, err
+	}
+	req.Header.Set("Accept", "text/html")
+	req.Header.Set("User-Agent", "RightToCommentBot/1.0 (link previews)")
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return Preview{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return Preview{}, fmt.Errorf("unexpected status %s", res.Status)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); mediaType != "text/html" {
+		return Preview{}, ErrNotHTML
+	}
+
+	preview := parse(io.LimitReader(res.Body, maxBodySize))
+	if preview.Title == "" {
+		return Preview{}, ErrNoTitle
+	}
+	preview.URL = link
+	return preview, nil
+}
+
+// Read the Open Graph title and description, falling back to the <title>
+// element and the description meta tag. Stops at the end of <head>.
+func parse(r io.Reader) Preview {
+	var preview Preview
+	var title, description string
+	tokenizer := html.NewTokenizer(r)
+	inTitle := false
+	for {
+		tt := tokenizer.Next()
+		switch tt {
+		case html.ErrorToken:
+			return finish(preview, title, description)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "title":
+				inTitle = tt == html.StartTagToken
+			case "meta":
+				name, content := metaAttrs(token)
+				switch name {
+				case "og:title":
+					preview.Title = content
+				case "og:description":
+					preview.Description = content
+				case "description":
+					description = content
+				}
+			case "body":
+				return finish(preview, title, description)
+			}
+		case html.TextToken:
+			if inTitle && title == "" {
+				title = string(tokenizer.Text())
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				return finish(preview, title, description)
+			}
+		}
+	}
+}
+
+func finish(preview Preview, title, description string) Preview {
+	if preview.Title == "" {
+		preview.Title = title
+	}
+	if preview.Description == "" {
+		preview.Description = description
+	}
+	preview.Title = clean(preview.Title, maxTitleLength)
+	preview.Description = clean(preview.Description, maxDescriptionLength)
+	return preview
+}
+
+func metaAttrs(token html.Token) (name, content string) {
+	for _, attr := range token.Attr {
+		switch attr.Key {
+		case "name", "property":
+			name = strings.ToLower(attr.Val)
+		case "content":
+			content = attr.Val
+		}
+	}
+	return name, content
+}
+
+// Collapse whitespace and cut the text to at most n characters
+func clean(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if !utf8.ValidString(s) {
+		s = strings.ToValidUTF8(s, "")
+	}
+	if utf8.RuneCountInString(s) > n {
+		s = strings.TrimSpace(string([]rune(s)[:n-1])) + "…"
+	}
+	return s
+}
+
+// Refuse connections to loopback, private, link-local and other addresses
+// that aren't on the public internet
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !public(ip) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func public(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip))
+}
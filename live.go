@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+	"github.com/TanishkBansode/right-to-comment/pubsub"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Events a live viewer can fall behind by before missing some
+	liveBufferSize = 16
+	// How often an idle stream gets a comment line, so proxies don't close it
+	liveKeepAlive = 25 * time.Second
+)
+
+// Kinds of liveEvent
+const (
+	eventCommentCreated = "comment-created"
+	eventCommentDeleted = "comment-deleted"
+	eventScoreChanged   = "score-changed"
+)
+
+// Something that happened to a video's comments, for whoever has it open
+type liveEvent struct {
+	Type      string
+	CommentID int64
+	// New vote total, for eventScoreChanged
+	Score int
+}
+
+var (
+	// Live viewers of each video, by video ID
+	liveComments = pubsub.New[liveEvent](liveBufferSize)
+	// Closed when the server shuts down, to end streams and sockets
+	liveClosing = make(chan struct{})
+	liveClosed  sync.Once
+)
+
+func init() {
+	expvar.Publish("live_viewers", expvar.Func(func() any { return liveComments.Subscribers() }))
+	expvar.Publish("live_events_dropped", expvar.Func(func() any { return liveComments.Dropped() }))
+}
+
+// Tell live viewers about a comment that was just posted. Only top-level
+// comments are streamed; replies show up when the thread is next loaded.
+func publishComment(id int64, comment database.NewComment, parentID string) {
+	// Shadowbanned comments stay invisible to everyone else
+	if comment.VisibleToAuthorOnly || parentID != "" {
+		return
+	}
+	liveComments.Publish(comment.VideoID, liveEvent{Type: eventCommentCreated, CommentID: id})
+}
+
+// Tell live viewers a comment was deleted or its score changed
+func publishChange(eventType string, comment database.Comment) {
+	if comment.VisibleToAuthorOnly {
+		return
+	}
+	liveComments.Publish(comment.VideoID, liveEvent{Type: eventType, CommentID: comment.ID, Score: comment.Score})
+}
+
+// End live streams and sockets, which the server's shutdown doesn't wait for
+func closeLive() {
+	liveClosed.Do(func() { close(liveClosing) })
+}
+
+// Stream the video's new comments as server-sent events, each rendered as
+// the HTML the viewer would get for it in the comment list
+func streamComments(router *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		videoID := c.Param("id")
+		if !videoIDPattern.MatchString(videoID) {
+			c.String(http.StatusNotFound, "There's no YouTube video with that ID.")
+			return
+		}
+		sub := liveComments.Subscribe(videoID)
+		defer sub.Close()
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		// Stop nginx from buffering the stream
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		keepAlive := time.NewTicker(liveKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			var err error
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case <-liveClosing:
+				return
+			case <-keepAlive.C:
+				_, err = c.Writer.WriteString(": keep-alive\n\n")
+			case event, ok := <-sub.Messages():
+				if !ok {
+					return
+				}
+				// The page only adds new comments
+				if event.Type != eventCommentCreated {
+					continue
+				}
+				err = writeCommentEvent(c, router, event)
+			}
+			if err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// Write one comment as an SSE event named after the event type, with the
+// comment ID as the event ID so the page can skip comments it already shows
+func writeCommentEvent(c *gin.Context, router *gin.Engine, event liveEvent) error {
+	view, err := loadCommentView(c, event.CommentID)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		log.Println("Error loading live comment:", err)
+		return nil
+	}
+	// Gone or held for review between posting and now
+	if view.DeletedAt != nil || view.Hidden {
+		return nil
+	}
+
+	var fragment fragmentWriter
+	if err := router.HTMLRender.Instance("comment", view).Render(&fragment); err != nil {
+		log.Println("Error rendering live comment:", err)
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "event: %s\nid: %d\n", event.Type, event.CommentID)
+	for _, line := range strings.Split(strings.TrimSpace(fragment.String()), "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	_, err = c.Writer.WriteString(b.String())
+	return err
+}
+
+// Collects a rendered template in memory
+type fragmentWriter struct {
+	bytes.Buffer
+	header http.Header
+}
+
+func (w *fragmentWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = make(http.Header)
+	}
+	return w.header
+}
+
+func (w *fragmentWriter) WriteHeader(int) {}
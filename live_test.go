@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+	"github.com/TanishkBansode/right-to-comment/identity"
+)
+
+// Wait until there are n live viewers
+func waitForViewers(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for liveComments.Subscribers() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d live viewers, want %d", liveComments.Subscribers(), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStreamReceivesNewComment(t *testing.T) {
+	useTestStore(t)
+	router := testRouter(http.MethodPost, "/video/:id/comments", identity.Middleware(secretKey), addComment)
+	router.GET("/video/:id/comments/stream", identity.Middleware(secretKey), streamComments(router))
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/video/dQw4w9WgXcQ/comments/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	waitForViewers(t, 1)
+
+	if code := postComment(t, "poster", commentForm("Live from the stream", time.Minute)); code != http.StatusOK {
+		t.Fatalf("posting: status = %d", code)
+	}
+
+	events := make(chan string)
+	go func() {
+		defer close(events)
+		var event strings.Builder
+		lines := bufio.NewScanner(resp.Body)
+		for lines.Scan() {
+			if lines.Text() == "" {
+				events <- event.String()
+				event.Reset()
+				continue
+			}
+			event.WriteString(lines.Text() + "\n")
+		}
+	}()
+	select {
+	case event := <-events:
+		if !strings.HasPrefix(event, "event: "+eventCommentCreated+"\n") || !strings.Contains(event, "Live from the stream") {
+			t.Errorf("unexpected event:\n%s", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+
+	resp.Body.Close()
+	waitForViewers(t, 0)
+}
+
+func TestPublishCommentSkipsHidden(t *testing.T) {
+	sub := liveComments.Subscribe("dQw4w9WgXcQ")
+	defer sub.Close()
+	publishComment(1, database.NewComment{VideoID: "dQw4w9WgXcQ", Text: "A reply"}, "7")
+	publishComment(2, database.NewComment{VideoID: "dQw4w9WgXcQ", Text: "Shadowbanned", VisibleToAuthorOnly: true}, "")
+	select {
+	case event := <-sub.Messages():
+		t.Errorf("published %+v", event)
+	default:
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+const lockedMessage = "Comments are locked on this video."
+
+// Refuse new comments on a locked video, taken from the URL
+func rejectLocked(c *gin.Context) {
+	checkLocked(c, c.Param("id"))
+}
+
+// Refuse votes, reactions and edits on comments of a locked video
+func rejectLockedComment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusNotFound, "Comment not found.")
+		c.Abort()
+		return
+	}
+	comment, err := store.GetComment(c.Request.Context(), id)
+	if errors.Is(err, database.ErrNotFound) {
+		c.String(http.StatusNotFound, "Comment not found.")
+		c.Abort()
+		return
+	}
+	if err != nil {
+		log.Println("Error loading comment:", err)
+		c.String(http.StatusInternalServerError, "Something went wrong, please try again.")
+		c.Abort()
+		return
+	}
+	checkLocked(c, comment.VideoID)
+}
+
+func checkLocked(c *gin.Context, videoID string) {
+	locked, err := store.IsVideoLocked(c.Request.Context(), videoID)
+	if err != nil {
+		log.Println("Error checking video lock:", err)
+		c.String(http.StatusInternalServerError, "Something went wrong, please try again.")
+		c.Abort()
+		return
+	}
+	if locked {
+		c.String(http.StatusForbidden, lockedMessage)
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// Freeze a video's comments, leaving the existing ones visible
+func lockVideo(c *gin.Context) {
+	setLocked(c, true)
+}
+
+func unlockVideo(c *gin.Context) {
+	setLocked(c, false)
+}
+
+func setLocked(c *gin.Context, locked bool) {
+	videoID := c.Param("id")
+	if err := store.SetVideoLocked(c.Request.Context(), videoID, locked, adminAudit(c)); err != nil {
+		log.Println("Error locking video:", err)
+		c.String(http.StatusInternalServerError, "Failed to update the video.")
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/video/"+videoID)
+}
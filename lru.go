@@ -0,0 +1,82 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Values by key, keeping at most size of them with the least recently used
+// going first. Safe for concurrent use.
+type lru[V any] struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	// Most recently used at the front
+	order *list.List
+}
+
+type lruEntry[V any] struct {
+	key    string
+	value  V
+	stored time.Time
+}
+
+func newLRU[V any](size int) *lru[V] {
+	return &lru[V]{size: size, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// The value kept for the key and when it was put there
+func (c *lru[V]) Get(key string) (V, time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, time.Time{}, false
+	}
+	c.order.MoveToFront(element)
+	entry := element.Value.(*lruEntry[V])
+	return entry.value, entry.stored, true
+}
+
+// Keep the value for the key, dropping the least recently used once there
+// are more than size
+func (c *lru[V]) Put(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry[V]{key: key, value: value, stored: time.Now()})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[V]).key)
+	}
+}
+
+func (c *lru[V]) Remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+}
+
+// Forget every value, returning how many there were
+func (c *lru[V]) Clear() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := c.order.Len()
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+	return n
+}
+
+func (c *lru[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -2,116 +2,657 @@ package main
 
 import (
 	"context"
+	"errors"
+	"expvar"
+	"flag"
 	"fmt"
+	"html/template"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/TanishkBansode/right-to-comment/captcha"
 	"github.com/TanishkBansode/right-to-comment/database"
+	"github.com/TanishkBansode/right-to-comment/identity"
+	"github.com/TanishkBansode/right-to-comment/langdetect"
+	"github.com/TanishkBansode/right-to-comment/markdown"
+	"github.com/TanishkBansode/right-to-comment/mention"
+	"github.com/TanishkBansode/right-to-comment/ratelimit"
+	"github.com/TanishkBansode/right-to-comment/timestamp"
+	"github.com/TanishkBansode/right-to-comment/wordfilter"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
-	"google.golang.org/api/option"
 	"google.golang.org/api/youtube/v3"
 )
 
+// The comments database, opened once at startup
+var store *database.Store
+
+const (
+	// How long requests in flight get to finish once the server is stopped
+	shutdownTimeout = 10 * time.Second
+	// How long background jobs then get to finish their queued work
+	drainTimeout = 10 * time.Second
+	// How long the health check waits for the database to answer
+	healthTimeout = 2 * time.Second
+)
+
 func main() {
+	flag.Parse()
+
 	// Load environment variables from .env
 	err := godotenv.Load()
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
 
-	// Get API key from environment variables
-	apiKey := os.Getenv("YOUTUBE_API_KEY")
-	if apiKey == "" {
+	// Get API keys from environment variables
+	keys, err := newKeyring(context.Background(), youtubeTransport(), append(strings.Split(os.Getenv("YOUTUBE_API_KEYS"), ","), os.Getenv("YOUTUBE_API_KEY"))...)
+	if err != nil {
+		log.Fatal("Error creating YouTube service: ", err)
+	}
+	if keys.Len() == 0 {
 		log.Fatal("YouTube API key not found in environment")
 	}
-	database.InitDB("./data")
+	editWindow = durationEnv("COMMENT_EDIT_WINDOW", editWindow)
+	trendingWindow = durationEnv("TRENDING_WINDOW", trendingWindow)
+	videoCacheTTL = durationEnv("VIDEO_CACHE_TTL", videoCacheTTL)
+	searchCacheTTL = durationEnv("SEARCH_CACHE_TTL", searchCacheTTL)
+	searchTimeout = durationEnv("SEARCH_TIMEOUT", searchTimeout)
+	detailsTimeout = durationEnv("DETAILS_TIMEOUT", detailsTimeout)
+	quotaSoftLimit = intEnv("YOUTUBE_QUOTA_LIMIT", quotaSoftLimit)
+	youtubeCommentCheck = boolEnv("YOUTUBE_COMMENT_CHECK", youtubeCommentCheck)
+	relatedEnabled = boolEnv("RELATED_VIDEOS", relatedEnabled)
+	reportThreshold = intEnv("REPORT_THRESHOLD", reportThreshold)
+	pinLimit = intEnv("PIN_LIMIT", pinLimit)
+	quoteMaxLength = intEnv("QUOTE_MAX_LENGTH", quoteMaxLength)
+	retentionDays = intEnv("COMMENT_RETENTION_DAYS", retentionDays)
+	if dir := os.Getenv("BACKUP_DIR"); dir != "" {
+		backupDir = dir
+	}
+	backupsKept = intEnv("BACKUP_KEEP", backupsKept)
+	optimizeInterval = durationEnv("DB_MAINTENANCE_INTERVAL", optimizeInterval)
+	maxCommentLength = intEnv("COMMENT_MAX_LENGTH", maxCommentLength)
+	maxCombiningMarks = intEnv("COMMENT_MAX_COMBINING_MARKS", maxCombiningMarks)
+	spamConfig.Links = boolEnv("SPAM_CHECK_LINKS", spamConfig.Links)
+	spamConfig.MaxLinks = intEnv("SPAM_MAX_LINKS", spamConfig.MaxLinks)
+	spamConfig.Duplicates = boolEnv("SPAM_CHECK_DUPLICATES", spamConfig.Duplicates)
+	spamConfig.Shouting = boolEnv("SPAM_CHECK_SHOUTING", spamConfig.Shouting)
+	spamConfig.Repetitive = boolEnv("SPAM_CHECK_REPETITIVE", spamConfig.Repetitive)
+	accountsEnabled = boolEnv("ACCOUNTS_ENABLED", accountsEnabled)
+	socketsEnabled = boolEnv("WEBSOCKETS_ENABLED", socketsEnabled)
+	if !boolEnv("LANGUAGE_DETECTION", true) {
+		langDetector = langdetect.Off{}
+	}
+	loadSecretKey()
+	loadGoogleOAuth()
+	loadWebhooks()
+	stopMailer := loadMailer()
+	loadSearchDefaults()
+	if boolEnv("CAPTCHA_ENABLED", false) {
+		challenger = captcha.NewArithmetic(captchaTTL)
+	}
+
+	wordFilter, err = wordfilter.New(
+		wordfilter.ParseMode(os.Getenv("WORD_FILTER_MODE")),
+		os.Getenv("WORD_FILTER_FILE"),
+		os.Getenv("WORD_FILTER"),
+	)
+	if err != nil {
+		log.Fatal("Error loading word filter: ", err)
+	}
+	source := os.Getenv("DATABASE_URL")
+	if source == "" {
+		source = "./data"
+	}
+	store, err = database.Open(source)
+	if err != nil {
+		log.Fatal("Error opening database: ", err)
+	}
+	store.QueryTimeout = durationEnv("DB_QUERY_TIMEOUT", store.QueryTimeout)
+	if *seedFlag {
+		seed()
+		store.Close()
+		return
+	}
+	grantAdmins(os.Getenv("ADMIN_USERS"))
+
+	router := gin.New()
+	router.Use(gin.LoggerWithFormatter(requestLog), gin.Recovery())
+	router.Use(identity.Middleware(secretKey))
+	if accountsEnabled {
+		router.Use(loadSession)
+	}
 
-	router := gin.Default()
+	// Only trust X-Forwarded-For when it comes from a configured proxy
+	var trustedProxies []string
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		trustedProxies = strings.Split(proxies, ",")
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES: ", err)
+	}
+
+	commentLimiter := ratelimit.New(intEnv("COMMENT_RATE_PER_MINUTE", 5), intEnv("COMMENT_RATE_BURST", 2))
+	// Background jobs, stopped in this order on shutdown
+	stopJobs := []func(){commentLimiter.StartCleanup(time.Minute), startMaintenance()}
+	if optimizeInterval > 0 {
+		stopJobs = append(stopJobs, startOptimizing())
+	}
+	if boolEnv("LINK_PREVIEWS", true) {
+		stopJobs = append(stopJobs, startPreviews())
+	}
+	if webhooks != nil {
+		stopJobs = append(stopJobs, webhooks.Close)
+	}
+	if stopMailer != nil {
+		stopJobs = append(stopJobs, stopMailer)
+	}
+	if hours := intEnv("BACKUP_INTERVAL_HOURS", 0); hours > 0 {
+		stopJobs = append(stopJobs, startBackups(time.Duration(hours)*time.Hour))
+	}
+	router.SetFuncMap(templateFuncs)
 	router.LoadHTMLGlob("templates/*")
 	router.Static("/static", "./static")
 
-	router.GET("/comments/:videoId", getComments)
-	router.POST("/comments/:videoId", addComment)
+	router.GET("/video/:id/comments", getComments)
+	router.POST("/video/:id/comments", rejectBanned, rejectLocked, enforceSlowMode, rateLimit(commentLimiter), addComment)
 	router.GET("/", showHomePage)
-	router.POST("/search", handleSearch(apiKey))
-	router.GET("/embed/:id", embedVideo)
+	router.GET("/healthz", healthCheck)
+	router.GET("/search", handleSearch(keys))
+	router.POST("/search", redirectSearch)
+	router.GET("/search/comments", searchComments)
+	router.GET("/channel/:id", showChannel(keys))
+	router.GET("/playlist/:id", showPlaylist(keys))
+	router.GET("/recent", showRecent(keys))
+	router.GET("/trending-comments", showTrending(keys))
+	router.GET("/my-comments", showMyComments)
+	router.POST("/my-comments/delete-all", deleteMyComments)
+	router.GET("/api/v1/comments/recent", recentJSON(keys))
+	router.GET("/api/v1/video/:id", videoMetadataJSON(keys))
+	router.GET("/api/v1/video/:id/comment-count", commentCount)
+	router.GET("/video/:id", embedVideo(keys))
+	router.GET("/video/:id/related", showRelated(keys))
+	router.GET("/video/:id/transcript", showTranscript)
+	router.GET("/embed/:id", embedVideo(keys))
+	router.GET("/video/:id/comments/:commentID", embedVideo(keys))
+	router.GET("/video/:id/comments/export", exportComments)
+	router.GET("/video/:id/comments/stream", streamComments(router))
+	if socketsEnabled {
+		router.GET("/ws/video/:id", serveSocket)
+	}
+	router.GET("/video/:id/comments.atom", commentFeed(keys))
+	router.GET("/video/:id/draft", getDraft)
+	router.PUT("/video/:id/draft", saveDraft)
+	router.DELETE("/comments/:id", deleteComment)
+	router.POST("/comments/:id/delete", deleteComment)
+	router.PUT("/comments/:id/edit", rejectBanned, rejectLockedComment, editComment)
+	router.POST("/comments/:id/edit", rejectBanned, rejectLockedComment, editComment)
+	router.POST("/comments/:id/upvote", rejectBanned, rejectLockedComment, voteComment(1))
+	router.POST("/comments/:id/downvote", rejectBanned, rejectLockedComment, voteComment(-1))
+	router.POST("/comments/:id/react", rejectBanned, rejectLockedComment, reactComment)
+	router.POST("/comments/:id/report", reportComment)
+	router.GET("/unsubscribe", unsubscribe)
+	router.GET("/avatar/:hash", serveAvatar)
+	router.POST("/unsubscribe", unsubscribe)
 
-	router.Run(":8080")
+	if accountsEnabled {
+		router.GET("/register", showRegister)
+		router.POST("/register", register)
+		router.GET("/login", showLogin)
+		router.POST("/login", login)
+		router.POST("/logout", logout)
+		if googleOAuth != nil {
+			router.GET("/auth/google/login", googleLogin)
+			router.GET("/auth/google/callback", googleCallback)
+		}
+	}
+
+	admin := router.Group("/admin", requireAdmin(adminToken()))
+	admin.GET("", showDashboard)
+	admin.GET("/moderation", showModeration)
+	admin.GET("/ip/:hash", showIPComments)
+	admin.POST("/comments/bulk", bulkModerate)
+	admin.POST("/comments/:id/approve", approveComment)
+	admin.POST("/comments/:id/delete", adminDeleteComment)
+	admin.POST("/comments/:id/ban", banCommentAuthor)
+	admin.GET("/comments/:id/history", showHistory)
+	admin.POST("/comments/:id/pin", pinComment)
+	admin.POST("/comments/:id/unpin", unpinComment)
+	admin.POST("/videos/:id/lock", lockVideo)
+	admin.POST("/videos/:id/unlock", unlockVideo)
+	admin.POST("/videos/:id/slow-mode", setSlowMode)
+	admin.GET("/audit", showAudit)
+	admin.GET("/bans", showBans)
+	admin.POST("/bans", addBan)
+	admin.POST("/bans/:id/delete", removeBan)
+	admin.POST("/word-filter/reload", reloadWordFilter)
+	admin.POST("/search/reindex", reindexSearch)
+	admin.POST("/search/cache/flush", flushSearchCache)
+	admin.POST("/backup", createBackup)
+	admin.POST("/languages/backfill", backfillLangs)
+	admin.GET("/metrics", gin.WrapH(expvar.Handler()))
+	admin.GET("/stats", showStats(keys))
+
+	server := &http.Server{Addr: ":8080", Handler: router}
+	// Shutdown doesn't wait for hijacked WebSocket connections or close
+	// event streams, so end those first
+	server.RegisterOnShutdown(closeLive)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+	<-ctx.Done()
+
+	log.Println("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Println("Error shutting down:", err)
+	}
+	if !drain(drainTimeout, stopJobs) {
+		log.Println("Background jobs still running after", drainTimeout)
+	}
+	if err := store.Close(); err != nil {
+		log.Println("Error closing database:", err)
+	}
+}
+
+// Call each stop function in turn, giving them timeout in all. Reports
+// whether they finished in time.
+func drain(timeout time.Duration, stops []func()) bool {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, stop := range stops {
+			stop()
+		}
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// gin's request log line without the client's IP address, which is never
+// logged
+func requestLog(p gin.LogFormatterParams) string {
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %-7s %#v\n%s",
+		p.TimeStamp.Format("2006/01/02 - 15:04:05"), p.StatusCode, p.Latency, p.Method, p.Path, p.ErrorMessage)
+}
+
+// Report whether the database can be reached, for load balancers and
+// uptime checks
+func healthCheck(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), healthTimeout)
+	defer cancel()
+	if err := store.Ping(ctx); err != nil {
+		log.Println("Health check failed:", err)
+		c.String(http.StatusServiceUnavailable, "degraded: database unreachable")
+		return
+	}
+	c.String(http.StatusOK, "ok")
+}
+
+// The token for basic auth on the admin pages when accounts are disabled.
+// ADMIN_PASSWORD is its older name.
+func adminToken() string {
+	if token := os.Getenv("ADMIN_TOKEN"); token != "" {
+		return token
+	}
+	return os.Getenv("ADMIN_PASSWORD")
+}
+
+// Flag the comma-separated usernames as admins
+func grantAdmins(usernames string) {
+	for _, username := range strings.Split(usernames, ",") {
+		username = strings.TrimSpace(username)
+		if username == "" {
+			continue
+		}
+		if err := store.SetUserAdmin(context.Background(), username, true); err != nil {
+			log.Printf("Error making %s an admin: %v", username, err)
+		}
+	}
+}
+
+// Read a duration such as "15m" from the environment, falling back when unset or invalid
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %s", key, value, fallback)
+		return fallback
+	}
+	return d
+}
+
+// Read a positive integer from the environment, falling back when unset or invalid
+func intEnv(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Printf("Invalid %s %q, using %d", key, value, fallback)
+		return fallback
+	}
+	return n
+}
+
+// Read a boolean such as "true" or "0" from the environment, falling back when unset or invalid
+func boolEnv(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %t", key, value, fallback)
+		return fallback
+	}
+	return b
 }
 
 // Show the home page with the search form
 func showHomePage(c *gin.Context) {
-	c.HTML(http.StatusOK, "index.html", gin.H{})
+	c.HTML(http.StatusOK, "index.html", gin.H{
+		"Types":     searchTypes,
+		"Orders":    searchOrders,
+		"Durations": searchDurations,
+		"Uploads":   searchUploads,
+	})
 }
 
-// Handle search and return top 10 video results
-func handleSearch(apiKey string) gin.HandlerFunc {
+// Send searches from forms that still POST to the shareable GET URL, or
+// straight to the video when one is pasted, see parseVideoLink
+func redirectSearch(c *gin.Context) {
+	if redirectVideoLink(c, c.PostForm("query")) {
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/search?"+parseSearchOptions(c.PostForm).values("", 0).Encode())
+}
+
+// Handle search and return a page of 10 video or channel results, ordered
+// and filtered as the query parameters ask, see parseSearchOptions. The
+// "pageToken" and "page" query parameters come from the next and previous
+// links.
+func handleSearch(keys *keyring) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		query := c.PostForm("query")
+		opts := parseSearchOptions(c.Query)
+		if redirectVideoLink(c, opts.Query) {
+			return
+		}
+		token := c.Query("pageToken")
+		page, err := strconv.Atoi(c.Query("page"))
+		if err != nil || page < 1 || token == "" {
+			page = 1
+		}
 
-		videos := searchYouTube(apiKey, query)
-		if len(videos) == 0 {
-			c.String(http.StatusNotFound, "No videos found.")
+		results, err := cachedSearch(c.Request.Context(), keys, opts, token)
+		if errors.Is(err, errYouTubePageToken) {
+			// A stale or mangled link, start over
+			page = 1
+			results, err = cachedSearch(c.Request.Context(), keys, opts, "")
+		}
+		if err != nil && !errors.Is(err, errSearchLimited) {
+			renderYouTubeError(c, "Error searching YouTube", err)
 			return
 		}
+		addCommentCounts(c.Request.Context(), results.Videos)
 
-		c.HTML(http.StatusOK, "results.html", gin.H{"Videos": videos})
+		data := gin.H{
+			"Videos":    results.Videos,
+			"Channels":  results.Channels,
+			"Playlists": results.Playlists,
+			"Search":    opts,
+			"Types":     searchTypes,
+			"Orders":    searchOrders,
+			"Durations": searchDurations,
+			"Uploads":   searchUploads,
+			"SafeModes": safeSearchModes[safeSearchRank(safeSearch):],
+			"Page":      page,
+			"Limited":   quotaLow(c.Request.Context(), keys),
+			"Uncached":  errors.Is(err, errSearchLimited),
+		}
+		if results.PrevToken != "" {
+			data["PrevURL"] = "/search?" + opts.values(results.PrevToken, max(page-1, 1)).Encode()
+		}
+		if results.NextToken != "" {
+			data["NextURL"] = "/search?" + opts.values(results.NextToken, page+1).Encode()
+		}
+		c.HTML(http.StatusOK, "results.html", data)
 	}
 }
 
-// Search YouTube using the API key and return video details
-func searchYouTube(apiKey, query string) []map[string]string {
-	ctx := context.Background()
-	service, err := youtube.NewService(ctx, option.WithAPIKey(apiKey))
+// Functions the templates use
+var templateFuncs = template.FuncMap{
+	"markdown":   markdown.Render,
+	"timestamps": linkTimestamps,
+	"timestamp":  timestamp.Format,
+	"formStamp":  formStamp,
+	"formToken":  formToken,
+	"snippet":    snippet,
+	"excerpt":    excerpt,
+	"mentions":   mention.Link,
+	"ago":        timeAgo,
+}
+
+// Add how many comments each video has here, to show where discussion is
+// already happening. Without them the videos are still worth showing, so a
+// failure is only logged.
+func addCommentCounts(ctx context.Context, videos []Video) {
+	ids := make([]string, 0, len(videos))
+	for _, video := range videos {
+		ids = append(ids, video.ID)
+	}
+	counts, err := store.CountCommentsByVideoIDs(ctx, ids)
 	if err != nil {
-		fmt.Println("Error initializing YouTube service:", err)
-		return nil
+		log.Println("Error counting comments:", err)
+	}
+	for i := range videos {
+		videos[i].Comments = counts[videos[i].ID]
 	}
+}
+
+// A video as result lists show it, with its counts and dates already
+// formatted
+type Video struct {
+	ID        string
+	Title     string
+	Channel   string
+	ChannelID string
+	// Like 4:13, shown unless Live is set
+	Duration string
+	// Like "LIVE" or "PREMIERE 3 Nov 2026 18:00 UTC", see liveBadge
+	Live      string
+	Views     string
+	Likes     string
+	Published string
+	// Width and height are 0 when unknown
+	Thumbnail       string
+	ThumbnailWidth  int64
+	ThumbnailHeight int64
+	Short           bool
+	// Comments here, see addCommentCounts
+	Comments int
+	// Place in a playlist, counting from 1, or 0 elsewhere
+	Position int64
+	// A private or deleted video left in a playlist, with only its ID known
+	Unavailable bool
+}
+
+// One page of YouTube search results, videos, channels or playlists, with
+// the tokens YouTube gives for the pages either side of it
+type searchPage struct {
+	Videos    []Video
+	Channels  []map[string]string
+	Playlists []map[string]string
+	NextToken string
+	PrevToken string
+}
+
+// Search YouTube using the API keys and return video, channel or playlist
+// details, ordered and filtered as opts asks, starting at the page the token
+// points to or at the first one when it's empty. Page tokens only work with
+// the options they were given for. Errors wrap errYouTubeQuota,
+// errYouTubeConfig, errYouTubeUnreachable, errYouTubePageToken or
+// errYouTubeTimeout when they are one of those.
+func searchYouTube(ctx context.Context, keys *keyring, opts searchOptions, pageToken string) (searchPage, error) {
+	ctx, cancel := context.WithTimeout(ctx, searchTimeout)
+	defer cancel()
 
-	// Search for the top 10 videos based on the query
-	searchCall := service.Search.List([]string{"id", "snippet"}).Q(query).MaxResults(10).Type("video")
-	searchResponse, err := searchCall.Do()
+	var page searchPage
+	err := withYouTube(ctx, keys, func(service *youtube.Service) (err error) {
+		page, err = searchWith(ctx, service, opts, pageToken)
+		return err
+	})
+	return page, err
+}
+
+// searchYouTube through the service
+func searchWith(ctx context.Context, service *youtube.Service, opts searchOptions, pageToken string) (searchPage, error) {
+	// Search for the top 10 results based on the query, or more when Shorts
+	// will be left out so the page isn't left nearly empty
+	perPage := int64(10)
+	if opts.Type == "videos" && opts.NoShorts {
+		perPage = shortsOverFetch
+	}
+	searchCall := service.Search.List([]string{"id", "snippet"}).Q(opts.Query).Order(opts.Order).SafeSearch(opts.SafeSearch).MaxResults(perPage)
+	switch opts.Type {
+	case "channels":
+		searchCall = searchCall.Type("channel")
+	case "playlists":
+		searchCall = searchCall.Type("playlist")
+	default:
+		searchCall = searchCall.Type("video")
+		if opts.Duration != "any" {
+			searchCall = searchCall.VideoDuration(opts.Duration)
+		}
+	}
+	if opts.Region != "" {
+		searchCall = searchCall.RegionCode(opts.Region)
+	}
+	if opts.Language != "" {
+		searchCall = searchCall.RelevanceLanguage(opts.Language)
+	}
+	if window, ok := uploadWindows[opts.Uploaded]; ok {
+		searchCall = searchCall.PublishedAfter(time.Now().Add(-window).UTC().Format(time.RFC3339))
+	}
+	if pageToken != "" {
+		searchCall = searchCall.PageToken(pageToken)
+	}
+	searchResponse, err := callYouTube(ctx, "search.list", searchCall.Context(ctx).Do)
 	if err != nil {
-		fmt.Println("Error searching YouTube:", err)
-		return nil
+		return searchPage{}, fmt.Errorf("searching: %w", classifyYouTubeError(err))
+	}
+	page := searchPage{NextToken: searchResponse.NextPageToken, PrevToken: searchResponse.PrevPageToken}
+	if len(searchResponse.Items) == 0 {
+		return page, nil
 	}
 
-	// Collect video IDs for content details request
-	var videoIDs []string
+	// Collect IDs for the details request
+	var ids []string
 	for _, item := range searchResponse.Items {
-		videoIDs = append(videoIDs, item.Id.VideoId)
+		switch opts.Type {
+		case "channels":
+			ids = append(ids, item.Id.ChannelId)
+		case "playlists":
+			ids = append(ids, item.Id.PlaylistId)
+		default:
+			ids = append(ids, item.Id.VideoId)
+		}
+	}
+	switch opts.Type {
+	case "channels":
+		page.Channels, err = channelDetails(ctx, service, ids)
+	case "playlists":
+		page.Playlists, err = playlistDetails(ctx, service, ids)
+	default:
+		page.Videos, err = videoDetails(ctx, service, ids)
+	}
+	if err != nil {
+		return searchPage{}, err
 	}
+	if opts.NoShorts {
+		page.Videos = slices.DeleteFunc(page.Videos, func(video Video) bool { return video.Short })
+	}
+	return page, nil
+}
 
-	// Fetch additional details (like duration) using the video IDs
-	detailsCall := service.Videos.List([]string{"snippet", "contentDetails"}).Id(strings.Join(videoIDs, ","))
-	detailsResponse, err := detailsCall.Do()
+// Fetch what the result lists show about each video (like its duration and
+// view count), in the order YouTube returns them
+func videoDetails(ctx context.Context, service *youtube.Service, videoIDs []string) ([]Video, error) {
+	detailsCall := service.Videos.List(videoParts).Id(strings.Join(videoIDs, ","))
+	detailsResponse, err := callYouTube(ctx, "videos.list", detailsCall.Context(ctx).Do)
 	if err != nil {
-		fmt.Println("Error fetching video details:", err)
-		return nil
+		return nil, fmt.Errorf("fetching video details: %w", classifyYouTubeError(err))
 	}
 
-	videos := make([]map[string]string, 0, len(detailsResponse.Items))
+	videos := make([]Video, 0, len(detailsResponse.Items))
+	infos := make(map[string]videoInfo, len(detailsResponse.Items))
 	for _, item := range detailsResponse.Items {
-		video := map[string]string{
-			"id":       item.Id,
-			"title":    item.Snippet.Title,
-			"channel":  item.Snippet.ChannelTitle,
-			"duration": formatDuration(item.ContentDetails.Duration),
+		info := cacheVideo(item)
+		infos[item.Id] = info
+		video := Video{
+			ID:        item.Id,
+			Title:     item.Snippet.Title,
+			Channel:   item.Snippet.ChannelTitle,
+			ChannelID: item.Snippet.ChannelId,
+			Duration:  formatDuration(item.ContentDetails.Duration),
+			Live:      liveBadge(info),
+			Views:     missingStat,
+			Likes:     missingStat,
+			Published: missingStat,
+			Thumbnail: info.Thumbnail,
+			Short:     isShort(info),
+		}
+		if stats := item.Statistics; stats != nil {
+			video.Views = compactCount(stats.ViewCount)
+			// Hidden likes are left out of the response, which the client
+			// can't tell apart from none
+			if stats.LikeCount > 0 {
+				video.Likes = compactCount(stats.LikeCount)
+			}
+		}
+		if published, err := time.Parse(time.RFC3339, item.Snippet.PublishedAt); err == nil {
+			video.Published = longAgo(published, time.Now())
+		}
+		if info.ThumbnailWidth > 0 && info.ThumbnailHeight > 0 {
+			video.ThumbnailWidth, video.ThumbnailHeight = info.ThumbnailWidth, info.ThumbnailHeight
 		}
 		videos = append(videos, video)
 	}
+	saveVideos(ctx, infos)
+	return videos, nil
+}
 
-	return videos
+// Parse an ISO 8601 duration such as PT1H2M3S
+func parseDuration(duration string) time.Duration {
+	d, _ := time.ParseDuration(strings.ReplaceAll(strings.ToLower(duration), "pt", ""))
+	return d
 }
 
 // Format ISO 8601 duration to H:MM:SS or MM:SS
 func formatDuration(duration string) string {
-	d, _ := time.ParseDuration(strings.ReplaceAll(strings.ToLower(duration), "pt", ""))
+	d := parseDuration(duration)
 
 	hours := int(d.Hours())
 	minutes := int(d.Minutes()) % 60
@@ -123,51 +664,116 @@ func formatDuration(duration string) string {
 	return fmt.Sprintf("%d:%02d", minutes, seconds)
 }
 
-// Embed the selected video
-func embedVideo(c *gin.Context) {
-	videoID := c.Param("id")
-	embedURL := fmt.Sprintf("https://www.youtube.com/embed/%s", videoID)
-	c.HTML(http.StatusOK, "embed.html", gin.H{"EmbedURL": embedURL, "VideoID": videoID})
-}
+// Show the selected video with its details and stored comments, starting at
+// the time given by the "t" parameter if there is one. Served at /video/ID,
+// the comments' home, and at /embed/ID for older links.
+func embedVideo(keys *keyring) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		videoID := c.Param("id")
+		if !videoIDPattern.MatchString(videoID) {
+			renderError(c, http.StatusNotFound, "There's no YouTube video with that ID.")
+			return
+		}
+		sort := database.ParseSortOrder(c.Query("sort"))
 
-func addComment(c *gin.Context) {
-	videoId := c.Param("videoId")
-	commentText := c.PostForm("comment")
+		// A permalink opens the page of comments holding the target
+		var target int64
+		var cursor string
+		if param := c.Param("commentID"); param != "" {
+			var err error
+			target, cursor, err = permalinkCursor(c, videoID, param, sort)
+			if errors.Is(err, database.ErrNotFound) {
+				c.String(http.StatusNotFound, "Comment not found.")
+				return
+			}
+			if err != nil {
+				log.Println("Error finding comment page:", err)
+				c.String(http.StatusInternalServerError, "Failed to load comments.")
+				return
+			}
+		}
 
-	err := database.AddComment(videoId, commentText)
-	if err != nil {
-		c.HTML(http.StatusInternalServerError, "error_template.html", gin.H{"error": "Failed to add comment"})
-		return
-	}
+		// The details head the page and the duration decides which
+		// timestamps in comments get linked, but the comments are worth
+		// showing without them when YouTube can't be reached
+		video, videoErr := lookupVideo(c.Request.Context(), keys, videoID)
+		if errors.Is(videoErr, errVideoNotFound) {
+			renderError(c, http.StatusNotFound, "This video doesn't exist or has been removed from YouTube.")
+			return
+		}
+		if videoErr != nil {
+			log.Println("Error fetching video details:", videoErr)
+		} else {
+			video = checkYouTubeComments(c.Request.Context(), keys, videoID, video)
+		}
+		duration := video.Duration
+		start, end := c.Query("t"), c.Query("end")
+		if video.Live != "" {
+			// Streams have no fixed timeline to seek or anchor comments to
+			start, end = "", ""
+		}
 
-	// Fetch updated comments after adding the new one
-	getComments(c)
-}
+		// Permalinks show the unfiltered listing so the target is on the page
+		filter := listingFilter(c)
+		if target != 0 {
+			filter = database.CommentFilter{}
+		}
+		page, err := commentPage(c, videoID, sort, cursor, defaultCommentLimit, filter)
+		if err != nil {
+			log.Println("Error loading comments:", err)
+			c.String(http.StatusInternalServerError, "Failed to load comments.")
+			return
+		}
+		if target != 0 {
+			highlight(page["Comments"].([]commentView), target)
+			// Keep the sort links, which reload the listing from the start
+			page["Paged"] = false
+		}
 
-func getComments(c *gin.Context) {
-	videoId := c.Param("videoId")
-	comments, err := database.GetComments(videoId)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load comments"})
-		return
-	}
+		if err := addChallenge(page); err != nil {
+			log.Println("Error creating CAPTCHA:", err)
+			c.String(http.StatusInternalServerError, "Failed to load the comment form.")
+			return
+		}
 
-	var commentsHTML strings.Builder
-	for _, comment := range comments {
-		// Parse and reformat the date
-		parsedDate, err := time.Parse(time.RFC3339, comment["createdAt"])
-		if err != nil {
-			log.Println("Error parsing date:", err)
-			parsedDate = time.Now() // Fallback if parsing fails
+		// Pick up where the visitor left off, unless they asked to quote a comment
+		draft, err := store.GetDraft(c.Request.Context(), identity.Hash(identity.FromContext(c)), videoID)
+		if err == nil {
+			page["FormText"] = draft.Text
+		} else if !errors.Is(err, database.ErrDraftNotFound) {
+			log.Println("Error loading draft:", err)
+		}
+		if c.Query("quote") != "" {
+			addQuote(c, page, videoID)
 		}
-		formattedDate := parsedDate.Format("2 Jan 2006")
 
-		// Construct HTML for each comment
-		commentsHTML.WriteString(fmt.Sprintf(
-			"<div><p>%s</p><p style='font-size: medium; color: gray;'>%s</p></div>",
-			comment["text"], formattedDate,
-		))
+		// Loaded by the page itself, see showRelated
+		page["Related"] = relatedEnabled
+
+		if videoErr == nil {
+			page["Video"] = newVideoDetailsView(videoID, video)
+		}
+		page["EmbedURL"] = embedURL(videoID, start, end, duration)
+		c.HTML(http.StatusOK, "embed.html", page)
 	}
+}
 
-	c.Data(http.StatusOK, "text/html", []byte(commentsHTML.String()))
+// Build the player URL, passing a start time given as seconds or as a
+// timestamp like "1:23" when it falls within the video, and an end time when
+// it falls after the start
+func embedURL(videoID, start, end string, duration time.Duration) string {
+	url := fmt.Sprintf("https://www.youtube.com/embed/%s", videoID)
+
+	seconds, ok := timestamp.ParseOffset(start)
+	if !ok || seconds < 0 || duration > 0 && time.Duration(seconds)*time.Second > duration {
+		return url
+	}
+	until, ok := timestamp.ParseOffset(end)
+	if !ok || until <= seconds || duration > 0 && time.Duration(until)*time.Second > duration {
+		if seconds == 0 {
+			return url
+		}
+		return fmt.Sprintf("%s?start=%d", url, seconds)
+	}
+	return fmt.Sprintf("%s?start=%d&end=%d", url, seconds, until)
 }
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// A router with the templates loaded and the handler at the path
+func testRouter(method, path string, handlers ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.SetFuncMap(templateFuncs)
+	router.LoadHTMLGlob("templates/*")
+	router.Handle(method, path, handlers...)
+	return router
+}
+
+// A YouTube API error response with the reason
+func youtubeError(status int, reason string) *http.Response {
+	return jsonResponse(status, map[string]any{"error": map[string]any{
+		"code":    status,
+		"message": reason,
+		"errors":  []map[string]any{{"reason": reason}},
+	}})
+}
+
+// A YouTube stub finding the one video given, or nothing when it's empty
+func searchStub(videoID string) fakeTransport {
+	return func(r *http.Request) (*http.Response, error) {
+		var items []map[string]any
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/search") && videoID != "":
+			items = append(items, map[string]any{"id": map[string]any{"kind": "youtube#video", "videoId": videoID}})
+		case strings.HasSuffix(r.URL.Path, "/videos") && videoID != "":
+			items = append(items, map[string]any{
+				"id":             videoID,
+				"snippet":        map[string]any{"title": "Found it", "channelTitle": "Someone", "channelId": "UCsomeone"},
+				"contentDetails": map[string]any{"duration": "PT4M13S"},
+				"statistics":     map[string]any{"viewCount": "1500"},
+			})
+		}
+		return jsonResponse(http.StatusOK, map[string]any{"items": items}), nil
+	}
+}
+
+func TestSearchYouTubeErrors(t *testing.T) {
+	useTestStore(t)
+	tests := []struct {
+		name      string
+		transport fakeTransport
+		want      error
+	}{
+		{"quota", func(*http.Request) (*http.Response, error) {
+			return youtubeError(http.StatusForbidden, "quotaExceeded"), nil
+		}, errYouTubeQuota},
+		{"invalid key", func(*http.Request) (*http.Response, error) {
+			return youtubeError(http.StatusBadRequest, "keyInvalid"), nil
+		}, errYouTubeConfig},
+		{"network", func(*http.Request) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		}, errYouTubeUnreachable},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := searchYouTube(context.Background(), testKeyring(t, test.transport), searchOptions{Query: "cats", Type: "videos", Duration: "any"}, "")
+			if !errors.Is(err, test.want) {
+				t.Errorf("err = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestSearchYouTubeVideos(t *testing.T) {
+	useTestStore(t)
+	page, err := searchYouTube(context.Background(), testKeyring(t, searchStub("dQw4w9WgXcQ")), searchOptions{Query: "cats", Type: "videos", Duration: "any"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Video{
+		ID:        "dQw4w9WgXcQ",
+		Title:     "Found it",
+		Channel:   "Someone",
+		ChannelID: "UCsomeone",
+		Duration:  "4:13",
+		Views:     "1.5K",
+		Likes:     missingStat,
+		Published: missingStat,
+	}
+	if len(page.Videos) != 1 || page.Videos[0] != want {
+		t.Errorf("videos = %+v, want %+v", page.Videos, want)
+	}
+}
+
+func TestHandleSearchStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		transport fakeTransport
+		status    int
+		body      string
+	}{
+		{"results", searchStub("dQw4w9WgXcQ"), http.StatusOK, "Found it"},
+		{"no results", searchStub(""), http.StatusOK, "No videos found."},
+		{"quota", func(*http.Request) (*http.Response, error) {
+			return youtubeError(http.StatusForbidden, "quotaExceeded"), nil
+		}, http.StatusServiceUnavailable, "try again later"},
+		{"invalid key", func(*http.Request) (*http.Response, error) {
+			return youtubeError(http.StatusBadRequest, "keyInvalid"), nil
+		}, http.StatusInternalServerError, "set up correctly"},
+		{"network", func(*http.Request) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		}, http.StatusBadGateway, "reach YouTube"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useTestStore(t)
+			router := testRouter(http.MethodGet, "/search", handleSearch(testKeyring(t, test.transport)))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search?query=cats", nil))
+			if w.Code != test.status {
+				t.Errorf("status = %d, want %d", w.Code, test.status)
+			}
+			if !strings.Contains(w.Body.String(), test.body) {
+				t.Errorf("body doesn't contain %q:\n%s", test.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestEmbedURLStart(t *testing.T) {
+	tests := []struct {
+		start    string
+		duration time.Duration
+		want     string
+	}{
+		{"", 10 * time.Minute, "https://www.youtube.com/embed/dQw4w9WgXcQ"},
+		{"155", 10 * time.Minute, "https://www.youtube.com/embed/dQw4w9WgXcQ?start=155"},
+		{"2:35", 10 * time.Minute, "https://www.youtube.com/embed/dQw4w9WgXcQ?start=155"},
+		{"11:00", 10 * time.Minute, "https://www.youtube.com/embed/dQw4w9WgXcQ"},
+		{"11:00", 0, "https://www.youtube.com/embed/dQw4w9WgXcQ?start=660"},
+		{"-5", 10 * time.Minute, "https://www.youtube.com/embed/dQw4w9WgXcQ"},
+		{"soon", 10 * time.Minute, "https://www.youtube.com/embed/dQw4w9WgXcQ"},
+	}
+	for _, test := range tests {
+		if got := embedURL("dQw4w9WgXcQ", test.start, "", test.duration); got != test.want {
+			t.Errorf("embedURL(%q, %s) = %q, want %q", test.start, test.duration, got, test.want)
+		}
+	}
+}
+
+// A keyring whose requests go to a YouTube stand-in that answers only after
+// the delay, or gives up when the request is cancelled. Cancelled requests
+// are reported on the channel.
+func slowYouTube(t *testing.T, delay time.Duration) (*keyring, <-chan struct{}) {
+	t.Helper()
+	cancelled := make(chan struct{}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"items": []}`))
+		case <-r.Context().Done():
+			cancelled <- struct{}{}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	target, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKeyring(t, func(r *http.Request) (*http.Response, error) {
+		r.URL.Scheme, r.URL.Host = target.Scheme, target.Host
+		return srv.Client().Transport.RoundTrip(r)
+	}), cancelled
+}
+
+func TestSearchDeadline(t *testing.T) {
+	useTestStore(t)
+	resetQuotaUsage(t)
+	previous := searchTimeout
+	searchTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { searchTimeout = previous })
+	keys, _ := slowYouTube(t, 5*time.Second)
+
+	start := time.Now()
+	_, err := searchYouTube(context.Background(), keys, searchOptions{Query: "cats", Type: "videos", Duration: "any"}, "")
+	if !errors.Is(err, errYouTubeTimeout) {
+		t.Errorf("err = %v, want errYouTubeTimeout", err)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("took %s with a %s deadline", took, searchTimeout)
+	}
+
+	router := testRouter(http.MethodGet, "/search", handleSearch(keys))
+	w := serve(router, requestAs(http.MethodGet, "/search?query=dogs", ""))
+	if w.Code != http.StatusGatewayTimeout || !strings.Contains(w.Body.String(), "took too long") {
+		t.Errorf("status = %d, want 504 with an explanation:\n%s", w.Code, w.Body)
+	}
+}
+
+func TestSearchCancelledWithRequest(t *testing.T) {
+	useTestStore(t)
+	resetQuotaUsage(t)
+	keys, cancelled := slowYouTube(t, 5*time.Second)
+
+	// The visitor closes the tab
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	done := make(chan error, 1)
+	go func() {
+		_, err := searchYouTube(ctx, keys, searchOptions{Query: "cats", Type: "videos", Duration: "any"}, "")
+		done <- err
+	}()
+
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("YouTube call carried on after the request was cancelled")
+	}
+	if err := <-done; err == nil {
+		t.Error("cancelled search succeeded")
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"expvar"
+	"log"
+	"time"
+)
+
+const (
+	// How often the maintenance job runs
+	maintenanceInterval = time.Hour
+
+	// Drafts left alone this long are thrown away
+	draftMaxAge = 30 * 24 * time.Hour
+
+	// Comments removed per transaction by the retention purge
+	retentionBatchSize = 500
+)
+
+// Days comments are kept before the purge removes them, 0 keeps them forever
+var retentionDays = 0
+
+// Outcome of the latest retention purge, shown on /admin/metrics
+var retentionStats = expvar.NewMap("comment_retention")
+
+// How often the database is optimized, set by DB_MAINTENANCE_INTERVAL
+var optimizeInterval = 6 * time.Hour
+
+// When the database was last optimized and how long it took, shown on
+// /admin/metrics
+var optimizeStats = expvar.NewMap("db_maintenance")
+
+// Run the periodic cleanup tasks until stop is called. Stopping cancels a
+// run in progress, rolling back the batch it was on, and waits for it to end.
+func startMaintenance() (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(maintenanceInterval)
+		defer ticker.Stop()
+		runMaintenance(ctx)
+		for {
+			select {
+			case <-ticker.C:
+				runMaintenance(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-stopped
+	}
+}
+
+func runMaintenance(ctx context.Context) {
+	if n, err := store.PurgeDrafts(ctx, time.Now().Add(-draftMaxAge)); err != nil {
+		log.Println("Error purging old drafts:", err)
+	} else if n > 0 {
+		log.Printf("Purged %d old drafts", n)
+	}
+	if retentionDays > 0 && ctx.Err() == nil {
+		purgeExpiredComments(ctx)
+	}
+	if ctx.Err() == nil {
+		reconcileCommentCounts(ctx)
+	}
+}
+
+// Fix the per-video comment counts should they have drifted from the
+// comments, which only happens when rows are changed behind the store's back
+func reconcileCommentCounts(ctx context.Context) {
+	drifts, err := store.ReconcileCommentCounts(ctx)
+	if err != nil {
+		log.Println("Error reconciling comment counts:", err)
+		return
+	}
+	for _, d := range drifts {
+		log.Printf("Corrected comment count of %s from %d to %d", d.VideoID, d.Stored, d.Actual)
+	}
+}
+
+// Optimize the database every optimizeInterval until stop is called, which
+// interrupts a run in progress
+func startOptimizing() (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(optimizeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				optimizeDatabase(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-stopped
+	}
+}
+
+// Failures are left for the next run to retry
+func optimizeDatabase(ctx context.Context) {
+	if !databaseJobMu.TryLock() {
+		log.Println("Skipping database maintenance while a backup runs")
+		return
+	}
+	defer databaseJobMu.Unlock()
+
+	start := time.Now()
+	if err := store.Optimize(ctx); err != nil {
+		if ctx.Err() == nil {
+			log.Println("Error optimizing database:", err)
+			optimizeStats.Add("failures", 1)
+		}
+		return
+	}
+	lastRun := new(expvar.String)
+	lastRun.Set(start.UTC().Format(time.RFC3339))
+	optimizeStats.Set("last_run", lastRun)
+	duration := new(expvar.Int)
+	duration.Set(time.Since(start).Milliseconds())
+	optimizeStats.Set("last_duration_ms", duration)
+}
+
+// A failed run only counts the batches that went, and is left for the next
+// run to finish
+func purgeExpiredComments(ctx context.Context) {
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+	n, err := store.PurgeComments(ctx, cutoff, retentionBatchSize)
+	if err != nil {
+		log.Printf("Error purging expired comments after removing %d rows: %v", n, err)
+		retentionStats.Add("failures", 1)
+		retentionStats.Add("rows_removed", n)
+		return
+	}
+	log.Printf("Retention purge removed %d rows older than %s", n, cutoff.Format(time.DateOnly))
+
+	lastRun := new(expvar.String)
+	lastRun.Set(time.Now().UTC().Format(time.RFC3339))
+	retentionStats.Set("last_run", lastRun)
+	retentionStats.Add("rows_removed", n)
+}
@@ -0,0 +1,40 @@
+// Package markdown renders the Markdown subset allowed in comments to
+// sanitized HTML.
+package markdown
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// Goldmark drops raw HTML by default, and the sanitizer below is a second
+// line of defence that only keeps the supported formatting
+var renderer = goldmark.New(goldmark.WithExtensions(extension.Linkify))
+
+var policy = newPolicy()
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "strong", "em", "code", "pre", "blockquote", "ul", "ol", "li")
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.RequireParseableURLs(true)
+	// Links open in a new tab with rel="nofollow noopener" so comments can't
+	// be used for link spam or to control the opener
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}
+
+// Render Markdown source to HTML that is safe to embed in a page
+func Render(source string) template.HTML {
+	var buf bytes.Buffer
+	if err := renderer.Convert([]byte(source), &buf); err != nil {
+		return template.HTML(template.HTMLEscapeString(source))
+	}
+	return template.HTML(policy.SanitizeBytes(buf.Bytes()))
+}
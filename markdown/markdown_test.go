@@ -0,0 +1,61 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderFormatting(t *testing.T) {
+	tests := map[string]string{
+		"**bold** and *italic*": "<p><strong>bold</strong> and <em>italic</em></p>",
+		"`code`":                "<p><code>code</code></p>",
+		"> quoted":              "<blockquote>\n<p>quoted</p>\n</blockquote>",
+		"- one\n- two":          "<ul>\n<li>one</li>\n<li>two</li>\n</ul>",
+	}
+	for source, want := range tests {
+		if got := strings.TrimSpace(string(Render(source))); got != want {
+			t.Errorf("Render(%q) = %q, want %q", source, got, want)
+		}
+	}
+}
+
+func TestRenderHostileInput(t *testing.T) {
+	tests := []struct {
+		source string
+		// None of these may appear in the output
+		forbidden []string
+	}{
+		{`<img src=x onerror=alert(1)>`, []string{"<img", "onerror"}},
+		{`<script>alert(1)</script>`, []string{"<script"}},
+		{`[click](javascript:alert(1))`, []string{"javascript:"}},
+		{`[click](JaVaScRiPt:alert(1))`, []string{"JaVaScRiPt:", "javascript:"}},
+		{`[click](data:text/html;base64,PHNjcmlwdD4=)`, []string{"data:"}},
+		{`[[nested](https://a.example)](https://b.example)`, []string{"<a href=\"https://b.example\"><a"}},
+		{`<a href="https://example.com" onclick="steal()">x</a>`, []string{"onclick"}},
+		{`<iframe src="https://evil.example"></iframe>`, []string{"<iframe"}},
+		{"**<svg onload=alert(1)>**", []string{"<svg", "onload"}},
+	}
+	for _, test := range tests {
+		got := string(Render(test.source))
+		for _, bad := range test.forbidden {
+			if strings.Contains(strings.ToLower(got), strings.ToLower(bad)) {
+				t.Errorf("Render(%q) = %q, contains %q", test.source, got, bad)
+			}
+		}
+	}
+}
+
+func TestRenderLinks(t *testing.T) {
+	for _, source := range []string{
+		"see https://example.com/page",
+		"[a page](https://example.com/page)",
+	} {
+		got := string(Render(source))
+		if !strings.Contains(got, `href="https://example.com/page"`) {
+			t.Errorf("Render(%q) = %q, want a link", source, got)
+		}
+		if !strings.Contains(got, `rel="nofollow noopener"`) {
+			t.Errorf("Render(%q) = %q, want rel=\"nofollow noopener\"", source, got)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Package mention turns "@name" mentions in rendered comments into links.
+package mention
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// An @ that starts a word, followed by the characters allowed in a name
+var pattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@])@([\p{L}\p{N}_.-]+)`)
+
+// Link mentions in the text of an HTML fragment to the URL given for the
+// lowercased name. Names missing from targets stay plain text, and text
+// inside links and code is left alone.
+func Link(fragment template.HTML, targets map[string]string) template.HTML {
+	if len(targets) == 0 {
+		return fragment
+	}
+
+	var out bytes.Buffer
+	tokenizer := xhtml.NewTokenizer(strings.NewReader(string(fragment)))
+	skipDepth := 0
+
+	for {
+		tt := tokenizer.Next()
+		if tt == xhtml.ErrorToken {
+			break
+		}
+		token := tokenizer.Token()
+
+		switch tt {
+		case xhtml.StartTagToken:
+			if skipsMentions(token.Data) {
+				skipDepth++
+			}
+		case xhtml.EndTagToken:
+			if skipsMentions(token.Data) && skipDepth > 0 {
+				skipDepth--
+			}
+		case xhtml.TextToken:
+			if skipDepth == 0 {
+				out.WriteString(linkText(token.Data, targets))
+				continue
+			}
+		}
+		out.WriteString(token.String())
+	}
+	return template.HTML(out.String())
+}
+
+func skipsMentions(tag string) bool {
+	return tag == "a" || tag == "code" || tag == "pre"
+}
+
+// Escape text, linking the mentions in it
+func linkText(text string, targets map[string]string) string {
+	var out strings.Builder
+	last := 0
+	for _, loc := range pattern.FindAllStringSubmatchIndex(text, -1) {
+		// Sentence punctuation after a name isn't part of it
+		start, end := loc[2], loc[3]
+		name := strings.TrimRight(text[start:end], ".-")
+		href, ok := targets[strings.ToLower(name)]
+		if !ok {
+			continue
+		}
+		end = start + len(name)
+
+		// Include the @ in the link
+		out.WriteString(html.EscapeString(text[last : start-1]))
+		fmt.Fprintf(&out, `<a href="%s" class="mention">%s</a>`,
+			html.EscapeString(href), html.EscapeString(text[start-1:end]))
+		last = end
+	}
+	out.WriteString(html.EscapeString(text[last:]))
+	return out.String()
+}
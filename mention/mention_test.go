@@ -0,0 +1,44 @@
+package mention
+
+import (
+	"html/template"
+	"testing"
+)
+
+var targets = map[string]string{
+	"ada":       "/video/x/comments/1",
+	"bob.smith": "/video/x/comments/2",
+	"evil":      `/video/x/comments/3"><script>`,
+}
+
+func TestLink(t *testing.T) {
+	tests := []struct {
+		name string
+		in   template.HTML
+		want template.HTML
+	}{
+		{"string start", "@Ada agreed", `<a href="/video/x/comments/1" class="mention">@Ada</a> agreed`},
+		{"mid-sentence", "<p>I think @ada is right</p>", `<p>I think <a href="/video/x/comments/1" class="mention">@ada</a> is right</p>`},
+		{"punctuation after", "<p>Thanks @ada!</p>", `<p>Thanks <a href="/video/x/comments/1" class="mention">@ada</a>!</p>`},
+		{"full stop after", "<p>Ask @bob.smith.</p>", `<p>Ask <a href="/video/x/comments/2" class="mention">@bob.smith</a>.</p>`},
+		{"punctuation before", "<p>(@ada)</p>", `<p>(<a href="/video/x/comments/1" class="mention">@ada</a>)</p>`},
+		{"unmatched", "<p>Hi @nobody</p>", "<p>Hi @nobody</p>"},
+		{"email address", "<p>mail ada@ada.example</p>", "<p>mail ada@ada.example</p>"},
+		{"double at", "<p>@@ada</p>", "<p>@@ada</p>"},
+		{"in code", "<p><code>@ada</code></p>", "<p><code>@ada</code></p>"},
+		{"in a link", `<p><a href="https://example.com">@ada</a></p>`, `<p><a href="https://example.com">@ada</a></p>`},
+		{"escaped text kept", "<p>&lt;b&gt; @ada</p>", `<p>&lt;b&gt; <a href="/video/x/comments/1" class="mention">@ada</a></p>`},
+		{"hostile target", "<p>@evil</p>", `<p><a href="/video/x/comments/3&#34;&gt;&lt;script&gt;" class="mention">@evil</a></p>`},
+	}
+	for _, test := range tests {
+		if got := Link(test.in, targets); got != test.want {
+			t.Errorf("%s: Link(%q) = %q, want %q", test.name, test.in, got, test.want)
+		}
+	}
+}
+
+func TestLinkWithoutTargets(t *testing.T) {
+	if got := Link("<p>@ada</p>", nil); got != "<p>@ada</p>" {
+		t.Errorf("Link = %q, want it unchanged", got)
+	}
+}
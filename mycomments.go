@@ -0,0 +1,95 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/identity"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// What erase confirmation tokens are signed for
+	erasePurpose = "erase"
+	// How long the confirmation on /my-comments stays usable
+	eraseTokenTTL = time.Hour
+)
+
+// List everything the visitor has contributed, with the button to erase it
+func showMyComments(c *gin.Context) {
+	id := identity.FromContext(c)
+	ctx := c.Request.Context()
+	comments, err := store.GetCommentsByAuthor(ctx, identity.Hash(id))
+	if err != nil {
+		log.Println("Error loading comments by author:", err)
+		renderError(c, http.StatusInternalServerError, "Failed to load your comments.")
+		return
+	}
+	counts, err := store.CountContributions(ctx, id, identity.Hash(id))
+	if err != nil {
+		log.Println("Error counting contributions:", err)
+		renderError(c, http.StatusInternalServerError, "Failed to load your comments.")
+		return
+	}
+	c.HTML(http.StatusOK, "my_comments.html", gin.H{
+		"Comments":      comments,
+		"Counts":        counts,
+		"ConfirmToken":  eraseToken(id),
+		"ExcerptLength": recentExcerptLength,
+		"Zone":          viewerZone(c),
+	})
+}
+
+// Erase everything the visitor has contributed, once they've confirmed from
+// the listing page
+func deleteMyComments(c *gin.Context) {
+	id := identity.FromContext(c)
+	if !validEraseToken(c.PostForm("confirm"), id) {
+		renderError(c, http.StatusBadRequest, "Please confirm from the My comments page and try again.")
+		return
+	}
+
+	erased, err := store.EraseContributions(c.Request.Context(), id, identity.Hash(id))
+	if err != nil {
+		log.Println("Error erasing contributions:", err)
+		renderError(c, http.StatusInternalServerError, "Failed to delete your comments, nothing was removed.")
+		return
+	}
+	log.Printf("Erased %d comments, %d votes, %d reactions, %d reports and %d drafts on request",
+		erased.Comments, erased.Votes, erased.Reactions, erased.Reports, erased.Drafts)
+
+	if strings.Contains(c.GetHeader("Accept"), "application/json") {
+		c.JSON(http.StatusOK, gin.H{
+			"comments":   erased.Comments,
+			"tombstoned": erased.Tombstoned,
+			"votes":      erased.Votes,
+			"reactions":  erased.Reactions,
+			"reports":    erased.Reports,
+			"drafts":     erased.Drafts,
+		})
+		return
+	}
+	c.HTML(http.StatusOK, "my_comments.html", gin.H{"Erased": erased})
+}
+
+// Token for the erase form, tied to the visitor and when the page was shown
+func eraseToken(id string) string {
+	return identity.Sign(secretKey, erasePurpose, identity.Hash(id)+":"+strconv.FormatInt(time.Now().Unix(), 10))
+}
+
+func validEraseToken(token, id string) bool {
+	value, ok := identity.Verify(secretKey, erasePurpose, token)
+	if !ok {
+		return false
+	}
+	hash, issued, ok := strings.Cut(value, ":")
+	if !ok || hash != identity.Hash(id) {
+		return false
+	}
+	unix, err := strconv.ParseInt(issued, 10, 64)
+	return err == nil && time.Since(time.Unix(unix, 0)) < eraseTokenTTL
+}
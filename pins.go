@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Most comments that can be pinned on one video, set from PIN_LIMIT
+var pinLimit = 3
+
+// Pin a comment above the rest of its video's comments
+func pinComment(c *gin.Context) {
+	setPinned(c, true)
+}
+
+// Return a pinned comment to its normal place in the list
+func unpinComment(c *gin.Context) {
+	setPinned(c, false)
+}
+
+func setPinned(c *gin.Context, pinned bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusNotFound, "Comment not found.")
+		return
+	}
+
+	ctx := c.Request.Context()
+	comment, err := store.GetComment(ctx, id)
+	if err == nil {
+		if pinned {
+			err = store.PinComment(ctx, id, pinLimit, adminAudit(c))
+		} else {
+			err = store.UnpinComment(ctx, id, adminAudit(c))
+		}
+	}
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		c.String(http.StatusNotFound, "Comment not found.")
+		return
+	case errors.Is(err, database.ErrPinLimit):
+		c.String(http.StatusConflict, fmt.Sprintf("This video already has %d pinned comments, unpin one first.", pinLimit))
+		return
+	case errors.Is(err, database.ErrPinNotAllowed):
+		c.String(http.StatusBadRequest, "Only top-level comments can be pinned.")
+		return
+	case err != nil:
+		log.Println("Error pinning comment:", err)
+		c.String(http.StatusInternalServerError, "Failed to update pinned comment.")
+		return
+	}
+
+	c.Redirect(http.StatusSeeOther, "/video/"+comment.VideoID)
+}
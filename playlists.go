@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/api/youtube/v3"
+)
+
+var errPlaylistNotFound = errors.New("playlist not found")
+
+// YouTube playlist IDs, e.g. PL followed by 32 characters, or shorter ones
+// for uploads and mixes
+var playlistIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{10,64}$`)
+
+// Show a playlist and a page of its videos in playlist order. The
+// "pageToken" and "page" query parameters come from the next and previous
+// links.
+func showPlaylist(keys *keyring) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		playlistID := c.Param("id")
+		if !playlistIDPattern.MatchString(playlistID) {
+			renderError(c, http.StatusNotFound, "Playlist not found.")
+			return
+		}
+		token := c.Query("pageToken")
+		page, err := strconv.Atoi(c.Query("page"))
+		if err != nil || page < 1 || token == "" {
+			page = 1
+		}
+
+		playlist, items, err := playlistVideos(c.Request.Context(), keys, playlistID, token)
+		if errors.Is(err, errYouTubePageToken) {
+			// A stale or mangled link, start over
+			page = 1
+			playlist, items, err = playlistVideos(c.Request.Context(), keys, playlistID, "")
+		}
+		if errors.Is(err, errPlaylistNotFound) {
+			renderError(c, http.StatusNotFound, "Playlist not found.")
+			return
+		}
+		if err != nil {
+			renderYouTubeError(c, "Error loading playlist", err)
+			return
+		}
+		addCommentCounts(c.Request.Context(), items.Videos)
+
+		data := gin.H{
+			"Playlist": playlist,
+			"Videos":   items.Videos,
+			"Page":     page,
+		}
+		if items.PrevToken != "" {
+			data["PrevURL"] = playlistPageURL(playlistID, items.PrevToken, max(page-1, 1))
+		}
+		if items.NextToken != "" {
+			data["NextURL"] = playlistPageURL(playlistID, items.NextToken, page+1)
+		}
+		c.HTML(http.StatusOK, "playlist.html", data)
+	}
+}
+
+func playlistPageURL(playlistID, pageToken string, page int) string {
+	return "/playlist/" + playlistID + "?" + url.Values{
+		"pageToken": {pageToken},
+		"page":      {strconv.Itoa(page)},
+	}.Encode()
+}
+
+// Look up a playlist and the page of its items the token points to, or the
+// first when it's empty. Videos that are private or deleted stay in their
+// place, marked "unavailable". Errors are as for searchYouTube, plus
+// errPlaylistNotFound.
+func playlistVideos(ctx context.Context, keys *keyring, playlistID, pageToken string) (map[string]string, searchPage, error) {
+	ctx, cancel := context.WithTimeout(ctx, detailsTimeout)
+	defer cancel()
+
+	var playlist map[string]string
+	var items searchPage
+	err := withYouTube(ctx, keys, func(service *youtube.Service) (err error) {
+		playlist, items, err = playlistVideosWith(ctx, service, playlistID, pageToken)
+		return err
+	})
+	return playlist, items, err
+}
+
+// playlistVideos through the service
+func playlistVideosWith(ctx context.Context, service *youtube.Service, playlistID, pageToken string) (map[string]string, searchPage, error) {
+	playlists, err := playlistDetails(ctx, service, []string{playlistID})
+	if err != nil {
+		return nil, searchPage{}, err
+	}
+	if len(playlists) == 0 {
+		return nil, searchPage{}, errPlaylistNotFound
+	}
+
+	call := service.PlaylistItems.List([]string{"snippet", "contentDetails", "status"}).PlaylistId(playlistID).MaxResults(10)
+	if pageToken != "" {
+		call = call.PageToken(pageToken)
+	}
+	response, err := callYouTube(ctx, "playlistItems.list", call.Context(ctx).Do)
+	if err != nil {
+		return nil, searchPage{}, fmt.Errorf("fetching playlist items: %w", classifyYouTubeError(err))
+	}
+	items := searchPage{NextToken: response.NextPageToken, PrevToken: response.PrevPageToken}
+
+	// Private and deleted videos keep their place in the playlist, but
+	// YouTube won't give details of them
+	var videoIDs []string
+	for _, item := range response.Items {
+		if playlistItemAvailable(item) {
+			videoIDs = append(videoIDs, item.ContentDetails.VideoId)
+		}
+	}
+	details := map[string]Video{}
+	if len(videoIDs) > 0 {
+		videos, err := videoDetails(ctx, service, videoIDs)
+		if err != nil {
+			return nil, searchPage{}, err
+		}
+		for _, video := range videos {
+			details[video.ID] = video
+		}
+	}
+	for _, item := range response.Items {
+		var videoID string
+		if item.ContentDetails != nil {
+			videoID = item.ContentDetails.VideoId
+		}
+		video, ok := details[videoID]
+		if !ok {
+			video = Video{ID: videoID, Title: "Private or deleted video", Unavailable: true}
+		}
+		if item.Snippet != nil {
+			video.Position = item.Snippet.Position + 1
+		}
+		items.Videos = append(items.Videos, video)
+	}
+	return playlists[0], items, nil
+}
+
+// Whether a playlist item is a video anyone can watch, rather than the
+// placeholder left by a private or deleted one
+func playlistItemAvailable(item *youtube.PlaylistItem) bool {
+	if item.ContentDetails == nil || item.ContentDetails.VideoId == "" {
+		return false
+	}
+	if item.Status != nil && item.Status.PrivacyStatus == "private" {
+		return false
+	}
+	if item.Snippet != nil && (item.Snippet.Title == "Private video" || item.Snippet.Title == "Deleted video") {
+		return false
+	}
+	return true
+}
+
+// Fetch what the result lists show about each playlist, in the order
+// YouTube returns them
+func playlistDetails(ctx context.Context, service *youtube.Service, playlistIDs []string) ([]map[string]string, error) {
+	response, err := callYouTube(ctx, "playlists.list", service.Playlists.List([]string{"snippet", "contentDetails"}).Id(playlistIDs...).Context(ctx).Do)
+	if err != nil {
+		return nil, fmt.Errorf("fetching playlist details: %w", classifyYouTubeError(err))
+	}
+	playlists := make([]map[string]string, 0, len(response.Items))
+	for _, item := range response.Items {
+		playlist := map[string]string{
+			"id":    item.Id,
+			"title": item.Id,
+			"items": missingStat,
+		}
+		if snippet := item.Snippet; snippet != nil {
+			playlist["title"] = snippet.Title
+			playlist["channel"] = snippet.ChannelTitle
+			playlist["channel_id"] = snippet.ChannelId
+			if thumbnail := pickThumbnail(snippet.Thumbnails); thumbnail != nil {
+				playlist["thumbnail"] = thumbnail.Url
+				if thumbnail.Width > 0 && thumbnail.Height > 0 {
+					playlist["thumbnail_width"] = strconv.FormatInt(thumbnail.Width, 10)
+					playlist["thumbnail_height"] = strconv.FormatInt(thumbnail.Height, 10)
+				}
+			}
+		}
+		if item.ContentDetails != nil {
+			playlist["items"] = strconv.FormatInt(item.ContentDetails.ItemCount, 10)
+		}
+		playlists = append(playlists, playlist)
+	}
+	return playlists, nil
+}
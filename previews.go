@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+	"github.com/TanishkBansode/right-to-comment/linkpreview"
+)
+
+const (
+	// Links per comment that get a preview card
+	maxPreviewsPerComment = 2
+	// Links waiting to be fetched, beyond which new ones are skipped
+	previewQueueSize = 100
+	previewWorkers   = 2
+	// How long a fetched preview, or the failure to get one, is kept before
+	// the link is fetched again
+	previewMaxAge = 7 * 24 * time.Hour
+)
+
+// Fetches previews in the background once comments are saved, nil when
+// LINK_PREVIEWS is false
+var previews *previewFetcher
+
+type previewFetcher struct {
+	fetcher *linkpreview.Fetcher
+	queue   chan string
+	wg      sync.WaitGroup
+}
+
+// Start the preview workers, returning a function that waits for the queued
+// links once no more are coming
+func startPreviews() (stop func()) {
+	previews = &previewFetcher{fetcher: linkpreview.New(), queue: make(chan string, previewQueueSize)}
+	for range previewWorkers {
+		previews.wg.Add(1)
+		go previews.work()
+	}
+	return func() {
+		close(previews.queue)
+		previews.wg.Wait()
+	}
+}
+
+// Queue the comment's links for previews. Posting never waits on this, and
+// links that don't fit in the queue just go without a card.
+func queuePreviews(text string) {
+	if previews == nil {
+		return
+	}
+	for _, link := range linkpreview.Links(text, maxPreviewsPerComment) {
+		select {
+		case previews.queue <- link:
+		default:
+			log.Println("Link preview queue full, skipping", link)
+		}
+	}
+}
+
+func (p *previewFetcher) work() {
+	defer p.wg.Done()
+	for link := range p.queue {
+		p.fetch(link)
+	}
+}
+
+func (p *previewFetcher) fetch(link string) {
+	ctx := context.Background()
+	fetched, err := store.LinkPreviewFetchedSince(ctx, link, time.Now().Add(-previewMaxAge))
+	if err != nil {
+		log.Println("Error checking link preview:", err)
+		return
+	}
+	if fetched {
+		return
+	}
+
+	preview, err := p.fetcher.Fetch(ctx, link)
+	ok := err == nil
+	if !ok {
+		// Unreachable and non-HTML pages are common and simply get no card
+		preview.URL = link
+	}
+	err = store.SaveLinkPreview(ctx, database.LinkPreview{
+		URL:         preview.URL,
+		Title:       preview.Title,
+		Description: preview.Description,
+	}, ok)
+	if err != nil {
+		log.Println("Error saving link preview:", err)
+	}
+}
+
+// Previews to show under each of the comments, keyed by comment ID
+func commentPreviews(ctx context.Context, comments []database.Comment) (map[int64][]database.LinkPreview, error) {
+	if previews == nil {
+		return nil, nil
+	}
+	links := make(map[int64][]string, len(comments))
+	var all []string
+	for _, comment := range comments {
+		if comment.DeletedAt != nil {
+			continue
+		}
+		links[comment.ID] = linkpreview.Links(comment.Text, maxPreviewsPerComment)
+		all = append(all, links[comment.ID]...)
+	}
+	found, err := store.GetLinkPreviews(ctx, all)
+	if err != nil {
+		return nil, err
+	}
+
+	byComment := make(map[int64][]database.LinkPreview)
+	for id, commentLinks := range links {
+		for _, link := range commentLinks {
+			if preview, ok := found[link]; ok {
+				byComment[id] = append(byComment[id], preview)
+			}
+		}
+	}
+	return byComment, nil
+}
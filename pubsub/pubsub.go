@@ -0,0 +1,103 @@
+// Package pubsub fans messages out to in-process subscribers by topic, such
+// as everyone watching one video's comments.
+package pubsub
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Hub delivers each published message to the current subscribers of its
+// topic. It is safe for concurrent use.
+type Hub[T any] struct {
+	// Messages each subscriber can have waiting before new ones are dropped
+	buffer int
+	// Messages dropped because a subscriber wasn't keeping up
+	dropped atomic.Int64
+
+	mu     sync.Mutex
+	topics map[string]map[*Subscription[T]]struct{}
+}
+
+// One subscriber's messages for a topic, until Close
+type Subscription[T any] struct {
+	hub   *Hub[T]
+	topic string
+	ch    chan T
+	once  sync.Once
+}
+
+// Create a hub giving every subscriber room for buffer waiting messages
+func New[T any](buffer int) *Hub[T] {
+	return &Hub[T]{buffer: buffer, topics: make(map[string]map[*Subscription[T]]struct{})}
+}
+
+// Start receiving the messages published to the topic from now on
+func (h *Hub[T]) Subscribe(topic string) *Subscription[T] {
+	s := &Subscription[T]{hub: h, topic: topic, ch: make(chan T, h.buffer)}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.topics[topic] == nil {
+		h.topics[topic] = make(map[*Subscription[T]]struct{})
+	}
+	h.topics[topic][s] = struct{}{}
+	return s
+}
+
+// Send the message to every subscriber of the topic without waiting. A
+// subscriber whose buffer is full misses the message rather than holding up
+// the publisher. Returns how many subscribers got it.
+func (h *Hub[T]) Publish(topic string, msg T) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delivered := 0
+	for s := range h.topics[topic] {
+		select {
+		case s.ch <- msg:
+			delivered++
+		default:
+			h.dropped.Add(1)
+		}
+	}
+	return delivered
+}
+
+// Number of subscribers across all topics
+func (h *Hub[T]) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	n := 0
+	for _, subs := range h.topics {
+		n += len(subs)
+	}
+	return n
+}
+
+// Number of messages dropped for slow subscribers so far
+func (h *Hub[T]) Dropped() int64 {
+	return h.dropped.Load()
+}
+
+// Messages published to the topic, closed once the subscription is
+func (s *Subscription[T]) Messages() <-chan T {
+	return s.ch
+}
+
+// Stop receiving messages. Safe to call more than once.
+func (s *Subscription[T]) Close() {
+	s.once.Do(func() {
+		h := s.hub
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		// Closing under the lock means Publish never sends on a closed channel
+		delete(h.topics[s.topic], s)
+		if len(h.topics[s.topic]) == 0 {
+			delete(h.topics, s.topic)
+		}
+		close(s.ch)
+	})
+}
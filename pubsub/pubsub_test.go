@@ -0,0 +1,62 @@
+package pubsub
+
+import "testing"
+
+func TestPublishToTopic(t *testing.T) {
+	h := New[string](4)
+	a, b, other := h.Subscribe("video"), h.Subscribe("video"), h.Subscribe("other video")
+	defer a.Close()
+	defer b.Close()
+	defer other.Close()
+
+	if n := h.Publish("video", "hello"); n != 2 {
+		t.Errorf("delivered to %d subscribers, want 2", n)
+	}
+	for _, s := range []*Subscription[string]{a, b} {
+		if got := <-s.Messages(); got != "hello" {
+			t.Errorf("got %q, want hello", got)
+		}
+	}
+	select {
+	case got := <-other.Messages():
+		t.Errorf("other topic got %q", got)
+	default:
+	}
+}
+
+func TestSlowSubscriberDropsMessages(t *testing.T) {
+	h := New[int](2)
+	slow := h.Subscribe("video")
+	defer slow.Close()
+
+	for i := range 5 {
+		h.Publish("video", i)
+	}
+	if h.Dropped() != 3 {
+		t.Errorf("dropped %d, want 3", h.Dropped())
+	}
+	for _, want := range []int{0, 1} {
+		if got := <-slow.Messages(); got != want {
+			t.Errorf("got %d, want %d", got, want)
+		}
+	}
+}
+
+func TestClose(t *testing.T) {
+	h := New[int](1)
+	s := h.Subscribe("video")
+	if h.Subscribers() != 1 {
+		t.Fatalf("%d subscribers, want 1", h.Subscribers())
+	}
+	s.Close()
+	s.Close()
+	if h.Subscribers() != 0 {
+		t.Errorf("%d subscribers after closing, want 0", h.Subscribers())
+	}
+	if _, ok := <-s.Messages(); ok {
+		t.Error("messages still open after closing")
+	}
+	if n := h.Publish("video", 1); n != 0 {
+		t.Errorf("delivered to %d closed subscribers", n)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+	_ "time/tzdata"
+)
+
+// Estimated cost in YouTube API units of each method we call
+var quotaCosts = map[string]int{
+	"search.list":         100,
+	"videos.list":         1,
+	"channels.list":       1,
+	"playlists.list":      1,
+	"playlistItems.list":  1,
+	"commentThreads.list": 1,
+}
+
+// Units spent in a day with each key after which searches only use cached
+// results, set by YOUTUBE_QUOTA_LIMIT. YouTube's default quota is 10,000
+// units a day.
+var quotaSoftLimit = 9000
+
+// YouTube's quota day starts at midnight Pacific time
+var quotaZone = mustLoadLocation("America/Los_Angeles")
+
+func mustLoadLocation(name string) *time.Location {
+	zone, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return zone
+}
+
+// The quota day a time falls in, like 2024-03-09
+func quotaDay(t time.Time) string {
+	return t.In(quotaZone).Format(time.DateOnly)
+}
+
+// Units spent on the current quota day, loaded from the database when the
+// day changes or the server starts
+var quotaUsage struct {
+	sync.Mutex
+	day   string
+	units int
+}
+
+// Count a call to the API method against today's quota
+func spendQuota(ctx context.Context, method string) {
+	cost := quotaCosts[method]
+	day := quotaDay(time.Now())
+
+	total, err := store.AddQuotaUsage(ctx, day, cost)
+	quotaUsage.Lock()
+	defer quotaUsage.Unlock()
+	if quotaUsage.day != day {
+		quotaUsage.day, quotaUsage.units = day, 0
+	}
+	if err != nil {
+		log.Println("Error recording API quota usage:", err)
+		quotaUsage.units += cost
+		return
+	}
+	// The database counts calls from every instance
+	quotaUsage.units = total
+}
+
+// Units estimated to have been spent today
+func quotaSpent(ctx context.Context) int {
+	day := quotaDay(time.Now())
+	quotaUsage.Lock()
+	defer quotaUsage.Unlock()
+	if quotaUsage.day != day {
+		units, err := store.QuotaUsage(ctx, day)
+		if err != nil {
+			log.Println("Error loading API quota usage:", err)
+		}
+		quotaUsage.day, quotaUsage.units = day, units
+	}
+	return quotaUsage.units
+}
+
+// The soft limit for all the keys together
+func quotaLimit(keys *keyring) int {
+	return quotaSoftLimit * keys.Len()
+}
+
+// Report whether today's usage has reached the soft limit, or every key has
+// run out, when only cached searches are made
+func quotaLow(ctx context.Context, keys *keyring) bool {
+	return keys.Left() == 0 || quotaSpent(ctx) >= quotaLimit(keys)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Forget the quota usage kept in memory, as on a restart, now and after the
+// test
+func resetQuotaUsage(t *testing.T) {
+	quotaUsage.day, quotaUsage.units = "", 0
+	t.Cleanup(func() { quotaUsage.day, quotaUsage.units = "", 0 })
+}
+
+func TestQuotaDayRollsOverAtPacificMidnight(t *testing.T) {
+	tests := []struct {
+		utc  string
+		want string
+	}{
+		// Winter, UTC-8
+		{"2024-01-15T07:59:59Z", "2024-01-14"},
+		{"2024-01-15T08:00:00Z", "2024-01-15"},
+		// Summer, UTC-7
+		{"2024-07-15T06:59:59Z", "2024-07-14"},
+		{"2024-07-15T07:00:00Z", "2024-07-15"},
+		// Either side of the clocks going forward on 10 March
+		{"2024-03-10T07:59:59Z", "2024-03-09"},
+		{"2024-03-11T06:59:59Z", "2024-03-10"},
+		{"2024-03-11T07:00:00Z", "2024-03-11"},
+		// Midnight UTC is still the day before in California
+		{"2024-12-31T23:59:59Z", "2024-12-31"},
+		{"2025-01-01T00:00:00Z", "2024-12-31"},
+	}
+	for _, test := range tests {
+		at, err := time.Parse(time.RFC3339, test.utc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := quotaDay(at); got != test.want {
+			t.Errorf("quotaDay(%s) = %s, want %s", test.utc, got, test.want)
+		}
+	}
+}
+
+func TestQuotaUsagePersists(t *testing.T) {
+	useTestStore(t)
+	resetQuotaUsage(t)
+	ctx := context.Background()
+
+	spendQuota(ctx, "search.list")
+	spendQuota(ctx, "videos.list")
+	if got := quotaSpent(ctx); got != 101 {
+		t.Fatalf("spent %d, want 101", got)
+	}
+	// A restart loads the day's usage back
+	quotaUsage.day, quotaUsage.units = "", 0
+	if got := quotaSpent(ctx); got != 101 {
+		t.Errorf("spent %d after restarting, want 101", got)
+	}
+}
+
+func TestQuotaUsageResetsWithTheDay(t *testing.T) {
+	useTestStore(t)
+	resetQuotaUsage(t)
+	ctx := context.Background()
+	yesterday := quotaDay(time.Now().Add(-24 * time.Hour))
+	if _, err := store.AddQuotaUsage(ctx, yesterday, 9500); err != nil {
+		t.Fatal(err)
+	}
+	// Still holding yesterday's total when the day turns
+	quotaUsage.day, quotaUsage.units = yesterday, 9500
+
+	if got := quotaSpent(ctx); got != 0 {
+		t.Errorf("spent %d on a new day, want 0", got)
+	}
+	spendQuota(ctx, "videos.list")
+	if got := quotaSpent(ctx); got != 1 {
+		t.Errorf("spent %d, want 1", got)
+	}
+}
+
+func TestQuotaSoftLimit(t *testing.T) {
+	useTestStore(t)
+	resetQuotaUsage(t)
+	previous := quotaSoftLimit
+	quotaSoftLimit = 200
+	t.Cleanup(func() { quotaSoftLimit = previous })
+	ctx := context.Background()
+
+	keys := testKeyring(t, searchStub("dQw4w9WgXcQ"))
+	opts := searchOptions{Query: "cats", Type: "videos", Duration: "any"}
+	if _, err := cachedSearch(ctx, keys, opts, ""); err != nil {
+		t.Fatal(err)
+	}
+	spendQuota(ctx, "search.list")
+	if !quotaLow(ctx, keys) {
+		t.Fatalf("quota not low after spending %d of %d", quotaSpent(ctx), quotaSoftLimit)
+	}
+
+	// Cached searches are still answered, new ones aren't made
+	if _, err := cachedSearch(ctx, keys, opts, ""); err != nil {
+		t.Errorf("cached search: %v", err)
+	}
+	opts.Query = "dogs"
+	if _, err := cachedSearch(ctx, keys, opts, ""); !errors.Is(err, errSearchLimited) {
+		t.Errorf("new search: err = %v, want errSearchLimited", err)
+	}
+
+	router := testRouter(http.MethodGet, "/search", handleSearch(keys))
+	w := serve(router, requestAs(http.MethodGet, "/search?query=dogs", ""))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "running low on its daily YouTube allowance") {
+		t.Errorf("limited search: status %d without the banner", w.Code)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+	"github.com/TanishkBansode/right-to-comment/identity"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Longest quoted excerpt, in characters, set from QUOTE_MAX_LENGTH
+var quoteMaxLength = 280
+
+// Prefill the comment form on the page as a reply quoting the comment in the
+// "quote" parameter. Comments the viewer can't see aren't quoted.
+func addQuote(c *gin.Context, page gin.H, videoID string) {
+	id, err := strconv.ParseInt(c.Query("quote"), 10, 64)
+	if err != nil {
+		return
+	}
+	comment, err := store.GetComment(c.Request.Context(), id)
+	if errors.Is(err, database.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		log.Println("Error loading quoted comment:", err)
+		return
+	}
+	if comment.VideoID != videoID || comment.DeletedAt != nil || comment.Hidden ||
+		comment.VisibleToAuthorOnly && comment.AuthorHash != identity.Hash(identity.FromContext(c)) {
+		return
+	}
+
+	page["ParentID"] = strconv.FormatInt(comment.ID, 10)
+	page["FormText"] = quoteText(comment)
+}
+
+// Markdown blockquote of the comment with a line saying who wrote it. The
+// excerpt never takes more than half the comment length limit, leaving room
+// for the reply.
+func quoteText(comment database.Comment) string {
+	excerpt := []rune(strings.TrimSpace(comment.Text))
+	if limit := min(quoteMaxLength, maxCommentLength/2); len(excerpt) > limit {
+		excerpt = append([]rune(strings.TrimSpace(string(excerpt[:limit]))), '…')
+	}
+
+	var b strings.Builder
+	b.WriteString(cmp.Or(comment.Author, "Anonymous"))
+	b.WriteString(" wrote:\n")
+	for _, line := range strings.Split(string(excerpt), "\n") {
+		b.WriteString("> ")
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	return b.String()
+}
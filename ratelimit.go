@@ -0,0 +1,32 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/TanishkBansode/right-to-comment/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Reject requests from clients that exceeded the limiter's rate
+func rateLimit(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ok, wait := limiter.Allow(clientIP(c))
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.String(http.StatusTooManyRequests, "You're commenting too fast, please wait a moment.")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Address of the client, which is only taken from X-Forwarded-For when the
+// request came through a proxy listed in TRUSTED_PROXIES. Rate limits, socket
+// limits and IP hashes all key on it. Never log or store it as it is.
+func clientIP(c *gin.Context) string {
+	return c.ClientIP()
+}
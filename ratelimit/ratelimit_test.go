@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// A limiter whose clock only moves when the test moves it
+func fakeClock(l *Limiter) *time.Time {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.Now = func() time.Time { return now }
+	return &now
+}
+
+func TestAllowBurstThenRefill(t *testing.T) {
+	l := New(5, 2)
+	now := fakeClock(l)
+
+	for i := range 2 {
+		if ok, _ := l.Allow("1.2.3.4"); !ok {
+			t.Fatalf("event %d of the burst refused", i+1)
+		}
+	}
+	ok, wait := l.Allow("1.2.3.4")
+	if ok {
+		t.Fatal("event beyond the burst allowed")
+	}
+	if wait != 12*time.Second {
+		t.Errorf("wait = %s, want 12s at 5 a minute", wait)
+	}
+
+	*now = now.Add(6 * time.Second)
+	if ok, wait := l.Allow("1.2.3.4"); ok || wait != 6*time.Second {
+		t.Errorf("half refilled: ok = %v, wait = %s, want refused for 6s", ok, wait)
+	}
+	*now = now.Add(6 * time.Second)
+	if ok, _ := l.Allow("1.2.3.4"); !ok {
+		t.Error("event refused after refilling")
+	}
+}
+
+func TestKeysAreSeparate(t *testing.T) {
+	l := New(5, 1)
+	fakeClock(l)
+	if ok, _ := l.Allow("1.2.3.4"); !ok {
+		t.Fatal("first event refused")
+	}
+	if ok, _ := l.Allow("5.6.7.8"); !ok {
+		t.Error("another key limited by the first")
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	l := New(60, 2)
+	now := fakeClock(l)
+	l.Allow("full")
+	l.Allow("full")
+	l.Allow("busy")
+	l.Allow("busy")
+
+	*now = now.Add(time.Second)
+	l.Allow("busy")
+	*now = now.Add(time.Second)
+	l.Cleanup()
+	if _, ok := l.buckets["full"]; ok {
+		t.Error("refilled bucket kept")
+	}
+	if _, ok := l.buckets["busy"]; !ok {
+		t.Error("bucket still refilling dropped")
+	}
+}
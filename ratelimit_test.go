@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitComments(t *testing.T) {
+	limiter := ratelimit.New(5, 2)
+	now := time.Now()
+	limiter.Now = func() time.Time { return now }
+	router := testRouter(http.MethodPost, "/video/:id/comments", rateLimit(limiter), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+	post := func(ip string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/video/dQw4w9WgXcQ/comments", nil)
+		r.RemoteAddr = ip + ":1234"
+		return serve(router, r)
+	}
+
+	for i := range 2 {
+		if w := post("192.0.2.1"); w.Code != http.StatusCreated {
+			t.Fatalf("comment %d: status = %d", i+1, w.Code)
+		}
+	}
+	w := post("192.0.2.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "12" {
+		t.Errorf("Retry-After = %q, want 12", got)
+	}
+	if w := post("192.0.2.2"); w.Code != http.StatusCreated {
+		t.Errorf("another client: status = %d", w.Code)
+	}
+
+	now = now.Add(12 * time.Second)
+	if w := post("192.0.2.1"); w.Code != http.StatusCreated {
+		t.Errorf("after waiting: status = %d", w.Code)
+	}
+}
+
+func TestRateLimitForwardedFor(t *testing.T) {
+	limiter := ratelimit.New(5, 1)
+	router := testRouter(http.MethodPost, "/video/:id/comments", rateLimit(limiter), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+	post := func(remote, forwarded string) int {
+		r := httptest.NewRequest(http.MethodPost, "/video/dQw4w9WgXcQ/comments", nil)
+		r.RemoteAddr = remote + ":1234"
+		r.Header.Set("X-Forwarded-For", forwarded)
+		return serve(router, r).Code
+	}
+
+	// Untrusted clients can't dodge the limit by making up addresses
+	if err := router.SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	post("192.0.2.1", "198.51.100.1")
+	if code := post("192.0.2.1", "198.51.100.2"); code != http.StatusTooManyRequests {
+		t.Errorf("forged X-Forwarded-For: status = %d, want 429", code)
+	}
+
+	// Behind a trusted proxy each client has its own limit
+	if err := router.SetTrustedProxies([]string{"10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	post("10.0.0.1", "198.51.100.3")
+	if code := post("10.0.0.1", "198.51.100.4"); code != http.StatusCreated {
+		t.Errorf("second client behind the proxy: status = %d, want 201", code)
+	}
+	if code := post("10.0.0.1", "198.51.100.3"); code != http.StatusTooManyRequests {
+		t.Errorf("first client again: status = %d, want 429", code)
+	}
+}
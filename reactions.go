@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+	"github.com/TanishkBansode/right-to-comment/identity"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The only reactions accepted on comments, in display order
+var reactionEmoji = []string{"👍", "❤️", "😂", "😮"}
+
+type reactionCount struct {
+	Emoji string
+	Count int
+}
+
+// Toggle the caller's emoji reaction on a comment and return the new counts
+func reactComment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusNotFound, "Comment not found.")
+		return
+	}
+
+	emoji := c.PostForm("emoji")
+	if !slices.Contains(reactionEmoji, emoji) {
+		c.String(http.StatusBadRequest, "Unsupported reaction.")
+		return
+	}
+
+	token := identity.FromContext(c)
+
+	_, err = store.ToggleReaction(c.Request.Context(), id, token, emoji)
+	if errors.Is(err, database.ErrNotFound) {
+		c.String(http.StatusNotFound, "Comment not found.")
+		return
+	}
+	if err != nil {
+		log.Println("Error recording reaction:", err)
+		c.String(http.StatusInternalServerError, "Failed to record reaction.")
+		return
+	}
+
+	view, err := loadCommentView(c, id)
+	if err != nil {
+		log.Println("Error loading comment:", err)
+		c.String(http.StatusInternalServerError, "Failed to load comment.")
+		return
+	}
+
+	if c.GetHeader("HX-Request") == "" {
+		c.Redirect(http.StatusSeeOther, "/video/"+view.VideoID)
+		return
+	}
+	c.HTML(http.StatusOK, "reactions", view)
+}
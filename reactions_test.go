@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+	"github.com/TanishkBansode/right-to-comment/identity"
+)
+
+// A form post from the browser whose identity is id, made by htmx
+func formAs(target, id string, form url.Values) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	if id != "" {
+		r.AddCookie(&http.Cookie{Name: "voter", Value: identity.Sign(secretKey, "voter", id)})
+	}
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("HX-Request", "true")
+	return r
+}
+
+func TestReactToggles(t *testing.T) {
+	useTestStore(t)
+	router := testRouter(http.MethodPost, "/comments/:id/react", identity.Middleware(secretKey), reactComment)
+	id := postTestComment(t, "author", database.NewComment{Text: "Funny"})
+	target := "/comments/" + strconv.FormatInt(id, 10) + "/react"
+
+	for i, want := range []int{1, 0} {
+		if w := serve(router, formAs(target, "reader", url.Values{"emoji": {"😂"}})); w.Code != http.StatusOK {
+			t.Fatalf("reaction %d: status = %d", i+1, w.Code)
+		}
+		counts, err := store.GetReactionCounts(context.Background(), []int64{id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if counts[id]["😂"] != want {
+			t.Errorf("after reaction %d: count = %d, want %d", i+1, counts[id]["😂"], want)
+		}
+	}
+}
+
+func TestReactRejectsOtherEmoji(t *testing.T) {
+	useTestStore(t)
+	router := testRouter(http.MethodPost, "/comments/:id/react", identity.Middleware(secretKey), reactComment)
+	id := postTestComment(t, "author", database.NewComment{Text: "Funny"})
+	target := "/comments/" + strconv.FormatInt(id, 10) + "/react"
+
+	for _, emoji := range []string{"💩", "", "<script>"} {
+		if w := serve(router, formAs(target, "reader", url.Values{"emoji": {emoji}})); w.Code != http.StatusBadRequest {
+			t.Errorf("emoji %q: status = %d, want 400", emoji, w.Code)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Number of comments on each page of /recent
+	recentPageSize = 30
+
+	// Longest comment excerpt shown on /recent
+	recentExcerptLength = 200
+)
+
+// A comment on /recent along with the video it was left on
+type recentComment struct {
+	database.Comment
+	Video videoInfo
+}
+
+type recentCommentJSON struct {
+	ID         int64     `json:"id"`
+	VideoID    string    `json:"video_id"`
+	VideoTitle string    `json:"video_title,omitempty"`
+	Thumbnail  string    `json:"thumbnail,omitempty"`
+	Author     string    `json:"author,omitempty"`
+	Text       string    `json:"text"`
+	CreatedAt  time.Time `json:"created_at"`
+	Permalink  string    `json:"permalink"`
+	// Whether YouTube takes comments on the video, "enabled", "disabled" or
+	// "unknown"
+	YouTubeComments string `json:"youtube_comments"`
+}
+
+// Show the newest comments across every video
+func showRecent(keys *keyring) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		comments, nextBefore, ok := loadRecent(c, keys)
+		if !ok {
+			return
+		}
+		c.HTML(http.StatusOK, "recent.html", gin.H{
+			"Comments":      comments,
+			"NextBefore":    nextBefore,
+			"Paged":         c.Query("before") != "",
+			"ExcerptLength": recentExcerptLength,
+			"Zone":          viewerZone(c),
+		})
+	}
+}
+
+// The newest comments across every video as JSON
+func recentJSON(keys *keyring) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		comments, nextBefore, ok := loadRecent(c, keys)
+		if !ok {
+			return
+		}
+
+		out := make([]recentCommentJSON, 0, len(comments))
+		for _, comment := range comments {
+			out = append(out, recentCommentJSON{
+				ID:         comment.ID,
+				VideoID:    comment.VideoID,
+				VideoTitle: comment.Video.Title,
+				Thumbnail:  comment.Video.Thumbnail,
+				Author:     comment.Author,
+				Text:       comment.Text,
+				CreatedAt:  comment.CreatedAt,
+				Permalink:  absoluteURL(c, permalinkPath(comment.VideoID, comment.ID)),
+
+				YouTubeComments: youtubeCommentsStatus(comment.Video),
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{"comments": out, "next_before": nextBefore})
+	}
+}
+
+// Load a page of recent comments with their videos, writing the error
+// response itself when that fails
+func loadRecent(c *gin.Context, keys *keyring) ([]recentComment, string, bool) {
+	ctx := c.Request.Context()
+	page, err := store.RecentComments(ctx, recentPageSize, c.Query("before"))
+	if err != nil {
+		log.Println("Error loading recent comments:", err)
+		c.String(http.StatusInternalServerError, "Failed to load comments.")
+		return nil, "", false
+	}
+	comments := page.Items
+
+	// One lookup for the whole page. Without video details the comments are
+	// still worth showing, so a failure is only logged.
+	videoIDs := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		videoIDs = append(videoIDs, comment.VideoID)
+	}
+	videos, err := GetVideoMeta(ctx, keys, videoIDs)
+	if err != nil {
+		log.Println("Error fetching video details:", err)
+	}
+
+	recent := make([]recentComment, 0, len(comments))
+	for _, comment := range comments {
+		recent = append(recent, recentComment{Comment: comment, Video: videos[comment.VideoID]})
+	}
+	return recent, page.NextCursor, true
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Most related videos shown under a video
+	relatedLimit = 6
+	// How long a video's related videos are reused before searching again
+	relatedTTL = 6 * time.Hour
+	// How long finding related videos may take before they are left out
+	// of the page
+	relatedTimeout = 3 * time.Second
+	// Tags added to the title when searching for related videos
+	relatedTags = 3
+	// Most videos whose related videos are kept
+	relatedCacheSize = 1000
+)
+
+// Whether video pages show related videos, set by RELATED_VIDEOS. Each
+// video's costs a 100 unit search every relatedTTL.
+var relatedEnabled = true
+
+// Related videos found lately, by the video they are related to
+var relatedCache = newLRU[[]Video](relatedCacheSize)
+
+// Videos like the given one, found by searching for its title and first
+// few tags since YouTube no longer offers related videos directly. Results
+// are cached for relatedTTL.
+func relatedVideos(ctx context.Context, keys *keyring, videoID string) ([]Video, error) {
+	if videos, fetched, ok := relatedCache.Get(videoID); ok && time.Since(fetched) < relatedTTL {
+		return videos, nil
+	}
+
+	// A search costs 100 units, which visitors searching need more
+	if quotaLow(ctx, keys) {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, relatedTimeout)
+	defer cancel()
+
+	video, err := lookupVideo(ctx, keys, videoID)
+	if err != nil {
+		return nil, err
+	}
+	terms := []string{video.Title}
+	terms = append(terms, video.Tags[:min(len(video.Tags), relatedTags)]...)
+	results, err := searchYouTube(ctx, keys, searchOptions{
+		Query:      strings.Join(terms, " "),
+		Type:       searchTypes[0].Value,
+		Order:      searchOrders[0].Value,
+		Duration:   searchDurations[0].Value,
+		Uploaded:   searchUploads[0].Value,
+		Region:     searchRegion,
+		Language:   searchLanguage,
+		SafeSearch: safeSearch,
+	}, "")
+	if err != nil {
+		return nil, err
+	}
+
+	related := make([]Video, 0, relatedLimit)
+	for _, v := range results.Videos {
+		if v.ID != videoID && len(related) < relatedLimit {
+			related = append(related, v)
+		}
+	}
+	relatedCache.Put(videoID, related)
+	return related, nil
+}
+
+// The related videos section of a video page, loaded after the page so the
+// search doesn't hold it up. Nothing is shown when there are none or they
+// can't be found.
+func showRelated(keys *keyring) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		videoID := c.Param("id")
+		if !relatedEnabled || !videoIDPattern.MatchString(videoID) {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		related, err := relatedVideos(c.Request.Context(), keys, videoID)
+		if err != nil {
+			log.Println("Error finding related videos:", err)
+		}
+		if len(related) == 0 {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		c.HTML(http.StatusOK, "related.html", gin.H{"Related": related})
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+	"github.com/TanishkBansode/right-to-comment/identity"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Reasons a visitor can give when reporting a comment
+var reportReasons = []string{"spam", "harassment", "off-topic", "other"}
+
+// Number of reports after which a comment is hidden pending review, set from REPORT_THRESHOLD
+var reportThreshold = 3
+
+// File a report against a comment, once per browser
+func reportComment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusNotFound, "Comment not found.")
+		return
+	}
+
+	reason := c.DefaultPostForm("reason", "other")
+	if !slices.Contains(reportReasons, reason) {
+		c.String(http.StatusBadRequest, "Invalid report reason.")
+		return
+	}
+
+	token := identity.FromContext(c)
+
+	hidden, err := store.ReportComment(c.Request.Context(), id, token, reason, reportThreshold)
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		c.String(http.StatusNotFound, "Comment not found.")
+		return
+	case errors.Is(err, database.ErrAlreadyReported):
+		c.String(http.StatusConflict, "You have already reported this comment.")
+		return
+	case err != nil:
+		log.Println("Error reporting comment:", err)
+		c.String(http.StatusInternalServerError, "Failed to report comment.")
+		return
+	}
+
+	if hidden {
+		log.Printf("Comment %d hidden after reaching %d reports", id, reportThreshold)
+	}
+	c.String(http.StatusOK, "Thanks, the comment has been reported.")
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	// Most times a YouTube API call is made before its error is returned
+	youtubeAttempts = 3
+	// Longest wait before the first retry, doubling after each
+	youtubeBackoff = 250 * time.Millisecond
+)
+
+// Make a YouTube API call, counting it against the quota as the method,
+// and make it again after a while when it fails in a way that may pass: a
+// server error, a network error, or 429 Too Many Requests, when YouTube's
+// Retry-After is respected. Retries stop early rather than outlast ctx.
+// Errors have the API key taken out, see redactAPIKey.
+func callYouTube[T any](ctx context.Context, method string, do func(...googleapi.CallOption) (T, error)) (T, error) {
+	for attempt := 1; ; attempt++ {
+		spendQuota(ctx, method)
+		result, err := do()
+		err = redactAPIKey(err)
+		if err == nil || attempt == youtubeAttempts {
+			return result, err
+		}
+		wait, ok := retryDelay(err, attempt)
+		if !ok {
+			return result, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			return result, err
+		}
+		log.Printf("YouTube %s failed, retrying in %s: %v", method, wait.Round(time.Millisecond), err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+	}
+}
+
+// Network errors give the URL requested, which carries the API key as its
+// key parameter. Returns the error with the key replaced, so it can be
+// logged.
+func redactAPIKey(err error) error {
+	urlErr, ok := err.(*url.Error)
+	if !ok {
+		return err
+	}
+	redacted := &url.Error{Op: urlErr.Op, URL: "(unparsable URL)", Err: urlErr.Err}
+	if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+		if query := u.Query(); query.Has("key") {
+			query.Set("key", "REDACTED")
+			u.RawQuery = query.Encode()
+		}
+		redacted.URL = u.String()
+	}
+	return redacted
+}
+
+// How long to wait before retrying after the given failed attempt, false
+// when the error won't go away by retrying
+func retryDelay(err error, attempt int) (time.Duration, bool) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return 0, false
+	}
+	// Full jitter, so callers failing together don't retry together
+	backoff := rand.N(youtubeBackoff << (attempt - 1))
+
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		// Couldn't reach YouTube
+		return backoff, true
+	}
+	switch {
+	case apiErr.Code == http.StatusTooManyRequests:
+		if wait, ok := retryAfter(apiErr.Header); ok {
+			return wait, true
+		}
+		return backoff, true
+	case apiErr.Code >= http.StatusInternalServerError:
+		return backoff, true
+	}
+	return 0, false
+}
+
+// The wait a Retry-After header asks for, in seconds or until a date
+func retryAfter(header http.Header) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
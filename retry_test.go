@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// Call videos.list through a stub that gives the responses in turn, the last
+// one again for any further requests, counting the requests
+func callWithResponses(t *testing.T, ctx context.Context, responses ...func() (*http.Response, error)) (int, error) {
+	t.Helper()
+	useTestStore(t)
+	resetQuotaUsage(t)
+	var requests atomic.Int32
+	keys := testKeyring(t, func(*http.Request) (*http.Response, error) {
+		n := int(requests.Add(1))
+		return responses[min(n, len(responses))-1]()
+	})
+	err := withYouTube(ctx, keys, func(service *youtube.Service) error {
+		_, err := callYouTube(ctx, "videos.list", service.Videos.List([]string{"id"}).Id("dQw4w9WgXcQ").Context(ctx).Do)
+		return err
+	})
+	return int(requests.Load()), err
+}
+
+func status(code int, retryAfter string) func() (*http.Response, error) {
+	return func() (*http.Response, error) {
+		resp := youtubeError(code, "backendError")
+		if retryAfter != "" {
+			resp.Header.Set("Retry-After", retryAfter)
+		}
+		return resp, nil
+	}
+}
+
+func networkError() (*http.Response, error) {
+	return nil, errors.New("connection reset")
+}
+
+func success() (*http.Response, error) {
+	return jsonResponse(http.StatusOK, map[string]any{"items": []any{}}), nil
+}
+
+func TestCallYouTubeRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []func() (*http.Response, error)
+		requests  int
+		ok        bool
+	}{
+		{"fails twice then succeeds", []func() (*http.Response, error){status(503, ""), networkError, success}, 3, true},
+		{"gives up after three", []func() (*http.Response, error){status(500, "")}, youtubeAttempts, false},
+		{"429 with Retry-After", []func() (*http.Response, error){status(429, "0"), success}, 2, true},
+		{"no retry on 4xx", []func() (*http.Response, error){status(400, ""), success}, 1, false},
+		{"no retry on 404", []func() (*http.Response, error){status(404, ""), success}, 1, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests, err := callWithResponses(t, context.Background(), test.responses...)
+			if requests != test.requests {
+				t.Errorf("made %d requests, want %d", requests, test.requests)
+			}
+			if (err == nil) != test.ok {
+				t.Errorf("err = %v", err)
+			}
+		})
+	}
+}
+
+func TestCallYouTubeRetriesWithinDeadline(t *testing.T) {
+	// Waiting a minute as asked would outlast the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	requests, err := callWithResponses(t, ctx, status(429, "60"), success)
+	if err == nil || requests != 1 {
+		t.Errorf("err = %v after %d requests, want the 429 after 1", err, requests)
+	}
+	if took := time.Since(start); took > 500*time.Millisecond {
+		t.Errorf("took %s, want no wait", took)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+	}
+	for _, test := range tests {
+		got, ok := retryAfter(http.Header{"Retry-After": {test.value}})
+		if got != test.want || ok != test.ok {
+			t.Errorf("retryAfter(%q) = %s, %v, want %s, %v", test.value, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestRedactAPIKey(t *testing.T) {
+	_, err := callWithResponses(t, context.Background(), networkError)
+	if err == nil || strings.Contains(err.Error(), "test-key") || !strings.Contains(err.Error(), "key=REDACTED") {
+		t.Errorf("err = %v, want the key redacted", err)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"html"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+const searchPageSize = 20
+
+// Find comments mentioning the query on any video
+func searchComments(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	var results []database.SearchResult
+	if query != "" {
+		// Ask for one extra row to find out whether another page exists
+		results, err = store.SearchComments(c.Request.Context(), query, searchPageSize+1, (page-1)*searchPageSize)
+		if err != nil {
+			log.Println("Error searching comments:", err)
+			c.String(http.StatusInternalServerError, "Failed to search comments.")
+			return
+		}
+	}
+
+	hasMore := len(results) > searchPageSize
+	if hasMore {
+		results = results[:searchPageSize]
+	}
+
+	c.HTML(http.StatusOK, "search.html", gin.H{
+		"Query":    query,
+		"Results":  results,
+		"Page":     page,
+		"PrevPage": page - 1,
+		"NextPage": page + 1,
+		"HasMore":  hasMore,
+		"Zone":     viewerZone(c),
+	})
+}
+
+// Escape a search snippet and mark the matched terms
+func snippet(s string) template.HTML {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, database.MatchStart, "<mark>")
+	s = strings.ReplaceAll(s, database.MatchEnd, "</mark>")
+	return template.HTML(s)
+}
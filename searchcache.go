@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/singleflight"
+)
+
+// Most search result pages kept, the least recently used going first
+const searchCacheSize = 500
+
+// How long a page of search results is reused, set by SEARCH_CACHE_TTL.
+// Each search costs 100 units of the daily API quota.
+var searchCacheTTL = 10 * time.Minute
+
+// Returned instead of searching once the day's quota is nearly spent, see
+// quotaLow
+var errSearchLimited = errors.New("search limited to cached results")
+
+// Hits, misses and flushes of the search cache, under /admin/metrics
+var searchCacheStats = expvar.NewMap("search_cache")
+
+// Pages of search results by searchCacheKey
+var searchCache = struct {
+	pages *lru[searchPage]
+	// Identical searches made at once wait for the first one
+	flight singleflight.Group
+}{
+	pages: newLRU[searchPage](searchCacheSize),
+}
+
+// The same search, however its query is spaced or capitalised, always has
+// the same key
+func searchCacheKey(opts searchOptions, pageToken string) string {
+	query := strings.ToLower(strings.Join(strings.Fields(opts.Query), " "))
+	return strings.Join([]string{
+		query, opts.Type, opts.Order, opts.Duration, opts.Uploaded,
+		opts.Region, opts.Language, opts.SafeSearch, strconv.FormatBool(opts.NoShorts), pageToken,
+	}, "\x00")
+}
+
+// searchYouTube, reusing results from the last searchCacheTTL. Errors
+// aren't cached. Once the day's quota is nearly spent, older results are
+// reused too and searches that were never cached fail with errSearchLimited.
+func cachedSearch(ctx context.Context, keys *keyring, opts searchOptions, pageToken string) (searchPage, error) {
+	key := searchCacheKey(opts, pageToken)
+	limited := quotaLow(ctx, keys)
+	if page, ok := cachedSearchPage(key, limited); ok {
+		searchCacheStats.Add("hits", 1)
+		return page, nil
+	}
+	searchCacheStats.Add("misses", 1)
+	if limited {
+		return searchPage{}, errSearchLimited
+	}
+
+	// The search carries on for the others waiting if the first visitor
+	// gives up
+	v, err, _ := searchCache.flight.Do(key, func() (any, error) {
+		page, err := searchYouTube(context.WithoutCancel(ctx), keys, opts, pageToken)
+		if err != nil {
+			return searchPage{}, err
+		}
+		storeSearchPage(key, page)
+		return page, nil
+	})
+	if err != nil {
+		return searchPage{}, err
+	}
+	// Every caller gets its own copy to add comment counts to
+	return v.(searchPage).clone(), nil
+}
+
+// A cached page of results, only from the last searchCacheTTL unless stale
+// ones will do
+func cachedSearchPage(key string, stale bool) (searchPage, bool) {
+	page, stored, ok := searchCache.pages.Get(key)
+	if !ok {
+		return searchPage{}, false
+	}
+	if !stale && time.Since(stored) >= searchCacheTTL {
+		searchCache.pages.Remove(key)
+		return searchPage{}, false
+	}
+	return page.clone(), true
+}
+
+func storeSearchPage(key string, page searchPage) {
+	searchCache.pages.Put(key, page.clone())
+}
+
+// Forget every cached search, returning how many pages there were
+func flushSearchPages() int {
+	searchCacheStats.Add("flushes", 1)
+	return searchCache.pages.Clear()
+}
+
+// A copy of the page whose results can be changed without touching the
+// original's
+func (p searchPage) clone() searchPage {
+	cloneAll := func(results []map[string]string) []map[string]string {
+		if results == nil {
+			return nil
+		}
+		out := make([]map[string]string, len(results))
+		for i, result := range results {
+			out[i] = make(map[string]string, len(result))
+			for k, v := range result {
+				out[i][k] = v
+			}
+		}
+		return out
+	}
+	p.Videos = slices.Clone(p.Videos)
+	p.Channels, p.Playlists = cloneAll(p.Channels), cloneAll(p.Playlists)
+	return p
+}
+
+// Empty the search cache, e.g. after changing SafeSearch or the API key
+func flushSearchCache(c *gin.Context) {
+	n := flushSearchPages()
+	audit(c, "flush-search-cache", "site", "search-cache", c.PostForm("note"))
+	c.String(http.StatusOK, "Search cache flushed, "+strconv.Itoa(n)+" pages dropped.")
+}
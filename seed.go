@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+)
+
+// Command line flags for filling a development database with fixtures
+var (
+	seedFlag     = flag.Bool("seed", false, "add fixture comments to the database and exit")
+	seedValue    = flag.Int64("seed-value", 1, "value the fixtures are generated from, the same one gives the same comments")
+	seedVideos   = flag.Int("seed-videos", 0, "videos to comment on, default 8")
+	seedComments = flag.Int("seed-comments", 0, "top-level comments per video, default 40")
+	seedForce    = flag.Bool("force", false, "seed even when the database already has comments")
+)
+
+// Add the fixture comments the flags ask for
+func seed() {
+	n, err := database.Seed(context.Background(), store, database.SeedOptions{
+		Videos:           *seedVideos,
+		CommentsPerVideo: *seedComments,
+		Seed:             *seedValue,
+		Force:            *seedForce,
+	})
+	if errors.Is(err, database.ErrNotEmpty) {
+		log.Fatal("Not seeding: the database already has comments, pass -force to add more anyway")
+	}
+	if err != nil {
+		log.Fatal("Error seeding database: ", err)
+	}
+	log.Printf("Seeded %d comments", n)
+}
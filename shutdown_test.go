@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+	"github.com/TanishkBansode/right-to-comment/ratelimit"
+	"github.com/TanishkBansode/right-to-comment/webhook"
+)
+
+// Wait for the number of goroutines to drop back to n
+func waitForGoroutines(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > n {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("%d goroutines left running, want %d:\n%s", runtime.NumGoroutine(), n, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestShutdownStopsEverything(t *testing.T) {
+	previous := store
+	t.Cleanup(func() {
+		store = previous
+		previews = nil
+		mailer = nil
+	})
+	t.Setenv("SMTP_HOST", "127.0.0.1")
+	t.Setenv("SMTP_PORT", "1")
+	t.Setenv("SMTP_FROM", "comments@example.com")
+	var err error
+	if store, err = database.Open(":memory:"); err != nil {
+		t.Fatal(err)
+	}
+	before := runtime.NumGoroutine()
+
+	// The jobs main starts, as it starts them
+	stopJobs := []func(){
+		ratelimit.New(5, 2).StartCleanup(time.Minute),
+		startMaintenance(),
+		startOptimizing(),
+		startPreviews(),
+		webhook.New([]string{"http://127.0.0.1:1/hook"}, "secret").Close,
+		startBackups(time.Hour),
+		loadMailer(),
+	}
+	// Queued notifications are handled before the mailer stops
+	mailer.queue <- replyNotice{replyID: 12345, baseURL: "http://localhost"}
+	if !drain(time.Second, stopJobs) {
+		t.Fatal("jobs didn't stop in time")
+	}
+	if n := len(mailer.queue); n != 0 {
+		t.Errorf("%d notifications left in the mail queue", n)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	waitForGoroutines(t, before)
+
+	if err := store.Ping(context.Background()); err == nil {
+		t.Error("database still open after shutdown")
+	}
+}
+
+func TestDrainGivesUp(t *testing.T) {
+	stuck := make(chan struct{})
+	defer close(stuck)
+	var stopped atomic.Bool
+	start := time.Now()
+	if drain(50*time.Millisecond, []func(){func() { stopped.Store(true) }, func() { <-stuck }}) {
+		t.Error("drain reported a stuck job as stopped")
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("drain took %s", took)
+	}
+	if !stopped.Load() {
+		t.Error("jobs before the stuck one weren't stopped")
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"crypto/rand"
+	"log"
+	"os"
+)
+
+// Key used to sign cookie values, set from SECRET_KEY
+var secretKey []byte
+
+// Load the signing key, generating a throwaway one when none is configured
+func loadSecretKey() {
+	if key := os.Getenv("SECRET_KEY"); key != "" {
+		secretKey = []byte(key)
+		return
+	}
+
+	secretKey = make([]byte, 32)
+	if _, err := rand.Read(secretKey); err != nil {
+		log.Fatal("Error generating secret key:", err)
+	}
+	log.Println("SECRET_KEY not set, signed cookies will not survive a restart")
+}
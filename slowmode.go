@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/identity"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Longest slow mode interval an admin can set
+const maxSlowMode = time.Hour
+
+// Refuse a comment when the author's last one on the video is more recent
+// than the video's slow mode interval allows. Admins aren't held to it.
+func enforceSlowMode(c *gin.Context) {
+	if isAdmin(c) {
+		c.Next()
+		return
+	}
+	ctx := c.Request.Context()
+	videoID := c.Param("id")
+	interval, err := store.GetSlowMode(ctx, videoID)
+	if err != nil {
+		log.Println("Error checking slow mode:", err)
+		c.String(http.StatusInternalServerError, "Something went wrong, please try again.")
+		c.Abort()
+		return
+	}
+	if interval <= 0 {
+		c.Next()
+		return
+	}
+
+	last, ok, err := store.LastCommentAt(ctx, videoID, identity.Hash(identity.FromContext(c)))
+	if err != nil {
+		log.Println("Error checking slow mode:", err)
+		c.String(http.StatusInternalServerError, "Something went wrong, please try again.")
+		c.Abort()
+		return
+	}
+	if wait := time.Until(last.Add(interval)); ok && wait > 0 {
+		seconds := int(math.Ceil(wait.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.String(http.StatusTooManyRequests, fmt.Sprintf("Slow mode is on, you can comment again in %ds.", seconds))
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// Set the "interval" form field, like "30s", "2m" or plain seconds, as the
+// video's slow mode. 0 turns it off.
+func setSlowMode(c *gin.Context) {
+	videoID := c.Param("id")
+	value := strings.TrimSpace(c.PostForm("interval"))
+	interval, err := time.ParseDuration(value)
+	if seconds, convErr := strconv.Atoi(value); convErr == nil {
+		interval, err = time.Duration(seconds)*time.Second, nil
+	}
+	if err != nil || interval < 0 || interval > maxSlowMode {
+		c.String(http.StatusBadRequest, fmt.Sprintf("The interval must be between 0 and %s, like 30s.", slowModeLabel(maxSlowMode)))
+		return
+	}
+
+	if err := store.SetSlowMode(c.Request.Context(), videoID, interval.Truncate(time.Second), adminAudit(c)); err != nil {
+		log.Println("Error setting slow mode:", err)
+		c.String(http.StatusInternalServerError, "Failed to update the video.")
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/video/"+videoID)
+}
+
+// Show an interval the way admins type it, "2m" rather than "2m0s"
+func slowModeLabel(interval time.Duration) string {
+	label := interval.String()
+	if strings.HasSuffix(label, "m0s") {
+		label = strings.TrimSuffix(label, "0s")
+	}
+	if strings.HasSuffix(label, "h0m") {
+		label = strings.TrimSuffix(label, "0m")
+	}
+	return label
+}
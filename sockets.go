@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// Largest message a client may send, in bytes
+	maxSocketMessage = 512
+	// Clients have to send something, a ping if nothing else, this often
+	socketReadTimeout = 60 * time.Second
+	// How long one message to a client may take to write
+	socketWriteTimeout = 10 * time.Second
+	// Open sockets allowed from one IP address
+	maxSocketsPerIP = 5
+)
+
+// Set by WEBSOCKETS_ENABLED to serve /ws/video/:id
+var socketsEnabled = false
+
+// YouTube video IDs, for checking subscribe messages
+var videoIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// Open sockets per client IP
+var (
+	socketsMu   sync.Mutex
+	socketsByIP = make(map[string]int)
+)
+
+// A message from a client: "subscribe" with a video ID to switch videos, or
+// "ping" to keep the connection open
+type socketRequest struct {
+	Type    string `json:"type"`
+	VideoID string `json:"video_id"`
+}
+
+// A message to a client. Type is a liveEvent type, "subscribed", "pong" or
+// "error".
+type socketMessage struct {
+	Type      string       `json:"type"`
+	VideoID   string       `json:"video_id,omitempty"`
+	CommentID int64        `json:"comment_id,omitempty"`
+	Score     *int         `json:"score,omitempty"`
+	Comment   *commentJSON `json:"comment,omitempty"`
+	Error     string       `json:"error,omitempty"`
+}
+
+// Send the video's comment events over a WebSocket as JSON messages
+func serveSocket(c *gin.Context) {
+	if !videoIDPattern.MatchString(c.Param("id")) {
+		c.String(http.StatusNotFound, "There's no YouTube video with that ID.")
+		return
+	}
+	ip := clientIP(c)
+	if !acquireSocket(ip) {
+		c.String(http.StatusTooManyRequests, "Too many open connections.")
+		return
+	}
+	defer releaseSocket(ip)
+
+	server := websocket.Server{
+		// Pages embedding the comments live on other sites, and events
+		// carry nothing a page couldn't fetch itself, so any origin is fine
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			ws.MaxPayloadBytes = maxSocketMessage
+			runSocket(c, ws, c.Param("id"))
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+func acquireSocket(ip string) bool {
+	socketsMu.Lock()
+	defer socketsMu.Unlock()
+	if socketsByIP[ip] >= maxSocketsPerIP {
+		return false
+	}
+	socketsByIP[ip]++
+	return true
+}
+
+func releaseSocket(ip string) {
+	socketsMu.Lock()
+	defer socketsMu.Unlock()
+	if socketsByIP[ip]--; socketsByIP[ip] <= 0 {
+		delete(socketsByIP, ip)
+	}
+}
+
+// Relay events for the subscribed video until the client goes away, stops
+// pinging or the server shuts down. Only this goroutine writes to the socket.
+func runSocket(c *gin.Context, ws *websocket.Conn, videoID string) {
+	defer ws.Close()
+
+	requests := make(chan socketRequest)
+	done := make(chan struct{})
+	defer close(done)
+	go readSocket(ws, requests, done)
+
+	sub := liveComments.Subscribe(videoID)
+	defer func() { sub.Close() }()
+	if sendSocket(ws, socketMessage{Type: "subscribed", VideoID: videoID}) != nil {
+		return
+	}
+
+	for {
+		var msg socketMessage
+		select {
+		case <-liveClosing:
+			return
+		case req, ok := <-requests:
+			if !ok {
+				return
+			}
+			switch {
+			case req.Type == "ping":
+				msg = socketMessage{Type: "pong"}
+			case req.Type == "subscribe" && videoIDPattern.MatchString(req.VideoID):
+				sub.Close()
+				videoID, sub = req.VideoID, liveComments.Subscribe(req.VideoID)
+				msg = socketMessage{Type: "subscribed", VideoID: videoID}
+			case req.Type == "subscribe":
+				msg = socketMessage{Type: "error", Error: "invalid video ID"}
+			default:
+				msg = socketMessage{Type: "error", Error: "unknown message type, use subscribe or ping"}
+			}
+		case event, ok := <-sub.Messages():
+			if !ok {
+				return
+			}
+			var send bool
+			if msg, send = socketEvent(c, videoID, event); !send {
+				continue
+			}
+		}
+		if sendSocket(ws, msg) != nil {
+			return
+		}
+	}
+}
+
+// Read client messages until the connection fails, a message is too large
+// or none arrives in time
+func readSocket(ws *websocket.Conn, requests chan<- socketRequest, done <-chan struct{}) {
+	defer close(requests)
+	for {
+		if err := ws.SetReadDeadline(time.Now().Add(socketReadTimeout)); err != nil {
+			return
+		}
+		var req socketRequest
+		if err := websocket.JSON.Receive(ws, &req); err != nil {
+			return
+		}
+		select {
+		case requests <- req:
+		case <-done:
+			return
+		}
+	}
+}
+
+func sendSocket(ws *websocket.Conn, msg socketMessage) error {
+	if err := ws.SetWriteDeadline(time.Now().Add(socketWriteTimeout)); err != nil {
+		return err
+	}
+	return websocket.JSON.Send(ws, msg)
+}
+
+// The message announcing an event, with the comment itself for new ones.
+// Reports false for comments that are gone or hidden by the time it's sent.
+func socketEvent(c *gin.Context, videoID string, event liveEvent) (socketMessage, bool) {
+	msg := socketMessage{Type: event.Type, VideoID: videoID, CommentID: event.CommentID}
+	switch event.Type {
+	case eventScoreChanged:
+		msg.Score = &event.Score
+	case eventCommentCreated:
+		view, err := loadCommentView(c, event.CommentID)
+		if err != nil {
+			if !errors.Is(err, database.ErrNotFound) {
+				log.Println("Error loading live comment:", err)
+			}
+			return msg, false
+		}
+		if view.DeletedAt != nil || view.Hidden {
+			return msg, false
+		}
+		comment := newCommentJSON(view)
+		msg.Comment = &comment
+	}
+	return msg, true
+}
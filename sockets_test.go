@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// Open a socket to the video's events, read the "subscribed" greeting and
+// wait until the hub has the subscriber. The socket is closed after the
+// test, once the hub has dropped it.
+func dialSocket(t *testing.T, videoID string) *websocket.Conn {
+	t.Helper()
+	srv := httptest.NewServer(testRouter(http.MethodGet, "/ws/video/:id", serveSocket))
+	t.Cleanup(srv.Close)
+
+	before := liveComments.Subscribers()
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws/video/"+videoID, "", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ws.Close()
+		waitForViewers(t, before)
+	})
+	if msg := receiveSocket(t, ws); msg.Type != "subscribed" || msg.VideoID != videoID {
+		t.Fatalf("greeted with %+v", msg)
+	}
+	waitForViewers(t, before+1)
+	return ws
+}
+
+func receiveSocket(t *testing.T, ws *websocket.Conn) socketMessage {
+	t.Helper()
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg socketMessage
+	if err := websocket.JSON.Receive(ws, &msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestSocketClientMessages(t *testing.T) {
+	ws := dialSocket(t, "dQw4w9WgXcQ")
+	tests := []struct {
+		request socketRequest
+		want    socketMessage
+	}{
+		{socketRequest{Type: "ping"}, socketMessage{Type: "pong"}},
+		{socketRequest{Type: "subscribe", VideoID: "nope"}, socketMessage{Type: "error", Error: "invalid video ID"}},
+		{socketRequest{Type: "shout"}, socketMessage{Type: "error", Error: "unknown message type, use subscribe or ping"}},
+		{socketRequest{Type: "subscribe", VideoID: "9bZkp7q19f0"}, socketMessage{Type: "subscribed", VideoID: "9bZkp7q19f0"}},
+	}
+	for _, test := range tests {
+		if err := websocket.JSON.Send(ws, test.request); err != nil {
+			t.Fatal(err)
+		}
+		if got := receiveSocket(t, ws); got != test.want {
+			t.Errorf("%+v: got %+v, want %+v", test.request, got, test.want)
+		}
+	}
+}
+
+func TestSocketBroadcast(t *testing.T) {
+	watching, switching := dialSocket(t, "dQw4w9WgXcQ"), dialSocket(t, "dQw4w9WgXcQ")
+
+	// Subscribing to another video drops the first one
+	websocket.JSON.Send(switching, socketRequest{Type: "subscribe", VideoID: "9bZkp7q19f0"})
+	receiveSocket(t, switching)
+	if n := liveComments.Publish("dQw4w9WgXcQ", liveEvent{Type: eventScoreChanged, CommentID: 3, Score: 7}); n != 1 {
+		t.Errorf("delivered to %d sockets, want 1", n)
+	}
+	msg := receiveSocket(t, watching)
+	if msg.Type != eventScoreChanged || msg.CommentID != 3 || msg.Score == nil || *msg.Score != 7 {
+		t.Errorf("got %+v", msg)
+	}
+
+	liveComments.Publish("9bZkp7q19f0", liveEvent{Type: eventCommentDeleted, CommentID: 4})
+	if msg := receiveSocket(t, switching); msg.Type != eventCommentDeleted || msg.VideoID != "9bZkp7q19f0" || msg.CommentID != 4 {
+		t.Errorf("got %+v", msg)
+	}
+}
+
+func TestSocketUnsubscribesOnClose(t *testing.T) {
+	before := liveComments.Subscribers()
+	ws := dialSocket(t, "dQw4w9WgXcQ")
+	ws.Close()
+	waitForViewers(t, before)
+}
+
+func TestSocketsPerIPCapped(t *testing.T) {
+	for range maxSocketsPerIP {
+		if !acquireSocket("192.0.2.1") {
+			t.Fatal("socket refused under the limit")
+		}
+	}
+	if acquireSocket("192.0.2.1") {
+		t.Error("socket allowed over the limit")
+	}
+	if !acquireSocket("192.0.2.2") {
+		t.Error("another IP refused")
+	}
+	releaseSocket("192.0.2.2")
+	for range maxSocketsPerIP {
+		releaseSocket("192.0.2.1")
+	}
+	if len(socketsByIP) != 0 {
+		t.Errorf("counts left over: %v", socketsByIP)
+	}
+}
@@ -0,0 +1,96 @@
+// Package spam implements content heuristics that flag likely spam comments.
+package spam
+
+import (
+	"regexp"
+	"unicode"
+)
+
+// Name of the heuristic that flagged a comment
+type Rule string
+
+const (
+	TooManyLinks Rule = "too many links"
+	Duplicate    Rule = "duplicate of a recent comment"
+	Shouting     Rule = "mostly uppercase"
+	Repetitive   Rule = "mostly repeated characters"
+)
+
+// Texts shorter than this are too short for the ratio based rules to be meaningful
+const minRatioLength = 10
+
+// Share of the text above which the ratio based rules fire
+const maxRatio = 0.8
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)`)
+
+// Rules to apply, each of which can be switched off
+type Config struct {
+	Links bool
+	// Most links a comment may contain when Links is on
+	MaxLinks int
+	// Duplicate detection needs stored comments, so it is left to the caller
+	Duplicates bool
+	Shouting   bool
+	Repetitive bool
+}
+
+// Return the first enabled content rule the text breaks, or "" if none
+func (c Config) Check(text string) Rule {
+	if c.Links && len(linkPattern.FindAllStringIndex(text, -1)) > c.MaxLinks {
+		return TooManyLinks
+	}
+	if c.Shouting && uppercaseRatio(text) > maxRatio {
+		return Shouting
+	}
+	if c.Repetitive && repeatedRatio(text) > maxRatio {
+		return Repetitive
+	}
+	return ""
+}
+
+// Share of letters that are uppercase
+func uppercaseRatio(text string) float64 {
+	var letters, upper int
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	if letters < minRatioLength {
+		return 0
+	}
+	return float64(upper) / float64(letters)
+}
+
+// Share of non-space characters that belong to a run of three or more of
+// the same character, as in "aaaaaa!!!!!!"
+func repeatedRatio(text string) float64 {
+	var total, repeated, run int
+	var prev rune
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			prev, run = 0, 0
+			continue
+		}
+		total++
+		if r == prev {
+			run++
+		} else {
+			prev, run = r, 1
+		}
+		switch {
+		case run == 3:
+			repeated += 3
+		case run > 3:
+			repeated++
+		}
+	}
+	if total < minRatioLength {
+		return 0
+	}
+	return float64(repeated) / float64(total)
+}
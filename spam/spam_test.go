@@ -0,0 +1,53 @@
+package spam
+
+import "testing"
+
+var allRules = Config{Links: true, MaxLinks: 2, Duplicates: true, Shouting: true, Repetitive: true}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want Rule
+	}{
+		{"ordinary", "Great video, the part about bridges was really interesting.", ""},
+		{"two links", "See https://example.com and www.example.org for more", ""},
+		{"three links", "BUY NOW http://a.example http://b.example https://c.example", TooManyLinks},
+		{"links without spaces", "http://a.example,http://b.example,http://c.example", TooManyLinks},
+		{"shouting", "THIS IS THE BEST VIDEO EVER MADE", Shouting},
+		{"short shouting", "LOL", ""},
+		{"some capitals", "I LOVE this video so much, thanks", ""},
+		{"repeated", "aaaaaaaaaaaaaaaa!!!!!!!!!!!", Repetitive},
+		{"repeated words", "no no no no no no no no no", ""},
+		{"short repeated", "hmmmm", ""},
+		{"emphasis", "Sooooo good, really well made video", ""},
+	}
+	for _, test := range tests {
+		if got := allRules.Check(test.text); got != test.want {
+			t.Errorf("%s: Check(%q) = %q, want %q", test.name, test.text, got, test.want)
+		}
+	}
+}
+
+func TestRulesSwitchOff(t *testing.T) {
+	tests := []struct {
+		text   string
+		config Config
+	}{
+		{"http://a.example http://b.example http://c.example", Config{Links: false, Shouting: true, Repetitive: true}},
+		{"THIS IS THE BEST VIDEO EVER MADE", Config{Links: true, MaxLinks: 2, Shouting: false, Repetitive: true}},
+		{"aaaaaaaaaaaaaaaa!!!!!!!!!!!", Config{Links: true, MaxLinks: 2, Shouting: true, Repetitive: false}},
+	}
+	for _, test := range tests {
+		if got := test.config.Check(test.text); got != "" {
+			t.Errorf("Check(%q) with %+v = %q, want no rule", test.text, test.config, got)
+		}
+	}
+}
+
+func TestMaxLinks(t *testing.T) {
+	config := Config{Links: true, MaxLinks: 0}
+	if got := config.Check("see www.example.com"); got != TooManyLinks {
+		t.Errorf("Check = %q, want %q", got, TooManyLinks)
+	}
+}
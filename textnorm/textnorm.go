@@ -0,0 +1,81 @@
+// Package textnorm puts text into one canonical form, so the word filter,
+// duplicate checks and the page all see the same characters no matter how
+// the text was encoded or decorated.
+package textnorm
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Combining marks kept on one base character by default. Enough for accents
+// and the vowel signs of scripts like Devanagari, not for "zalgo" text.
+const DefaultMaxMarks = 3
+
+const zeroWidthJoiner = '\u200d'
+
+// Convert text to NFC, drop invisible characters and keep at most maxMarks
+// combining marks after each base character. Newlines and tabs are kept; a
+// zero-width joiner is kept only inside emoji sequences like 👩‍💻.
+func Normalize(text string, maxMarks int) string {
+	runes := []rune(norm.NFC.String(text))
+
+	var b strings.Builder
+	b.Grow(len(text))
+	var prev rune
+	marks := 0
+	for i, r := range runes {
+		switch {
+		case r == '\n' || r == '\t':
+		case r == zeroWidthJoiner:
+			if i+1 >= len(runes) || !isEmoji(prev) || !isEmoji(runes[i+1]) {
+				continue
+			}
+		case invisible(r):
+			continue
+		case isMark(r):
+			if marks >= maxMarks {
+				continue
+			}
+			marks++
+			b.WriteRune(r)
+			continue
+		}
+		marks = 0
+		prev = r
+		b.WriteRune(r)
+	}
+	// Dropping marks can leave text that composes further
+	return norm.NFC.String(b.String())
+}
+
+// Control and format characters, which include zero-width spaces, the
+// byte order mark, soft hyphens and bidirectional overrides
+func invisible(r rune) bool {
+	return unicode.IsControl(r) || unicode.Is(unicode.Cf, r)
+}
+
+// Emoji and the skin tone modifiers that follow them
+func isEmoji(r rune) bool {
+	return unicode.In(r, unicode.So, unicode.Sk)
+}
+
+// Text as filters should match it: invisible characters dropped and accents
+// and other marks removed, so "bàd", "bad" and "b\u200bad" all read "bad"
+func Fold(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	for _, r := range norm.NFD.String(text) {
+		if (invisible(r) && r != '\n' && r != '\t') || isMark(r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return norm.NFC.String(b.String())
+}
+
+func isMark(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me)
+}
@@ -0,0 +1,47 @@
+package textnorm
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name, text, want string
+	}{
+		{"decomposed to NFC", "cafe\u0301", "caf\u00e9"},
+		{"zero-width space", "spa\u200bm", "spam"},
+		{"byte order mark", "\ufeffhello", "hello"},
+		{"RTL override", "file\u202egnp.exe", "filegnp.exe"},
+		{"control characters", "bell\a and\x00 null", "bell and null"},
+		{"newlines and tabs kept", "one\n\ttwo", "one\n\ttwo"},
+		{"zalgo capped", "z" + strings.Repeat("\u0336\u0317", 10) + "a", "z\u0336\u0336\u0336a"},
+		{"accents kept", "n\u0303o", "\u00f1o"},
+		{"Devanagari vowel signs kept", "\u0939\u093f\u0928\u094d\u0926\u0940", "\u0939\u093f\u0928\u094d\u0926\u0940"},
+		{"joiner kept in emoji", "\U0001f469\u200d\U0001f4bb", "\U0001f469\u200d\U0001f4bb"},
+		{"joiner dropped between letters", "sp\u200dam", "spam"},
+	}
+	for _, test := range tests {
+		if got := Normalize(test.text, DefaultMaxMarks); got != test.want {
+			t.Errorf("%s: Normalize(%q) = %q, want %q", test.name, test.text, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeMarkCap(t *testing.T) {
+	zalgo := "a" + strings.Repeat("\u0336", 20)
+	for _, max := range []int{0, 1, 5} {
+		if got := utf8.RuneCountInString(Normalize(zalgo, max)); got != max+1 {
+			t.Errorf("cap %d: kept %d runes, want %d", max, got, max+1)
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	for _, text := range []string{"bad", "b\u00e0d", "b\u200bad", "b\u202ead", "ba\u0301\u0301\u0301d"} {
+		if got := Fold(text); got != "bad" {
+			t.Errorf("Fold(%q) = %q, want bad", text, got)
+		}
+	}
+}
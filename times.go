@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cookie holding the viewer's offset from UTC in minutes, set by the pages'
+// script from the browser's clock. Without it absolute times are in UTC.
+const tzCookie = "tz"
+
+// Largest offset any zone uses, 14 hours east
+const maxZoneOffset = 14 * 60
+
+// Fixed zone of the viewer's tz cookie, UTC when it is missing or invalid
+func viewerZone(c *gin.Context) *time.Location {
+	value, err := c.Cookie(tzCookie)
+	if err != nil {
+		return time.UTC
+	}
+	minutes, err := strconv.Atoi(value)
+	if err != nil || minutes < -maxZoneOffset || minutes > maxZoneOffset {
+		return time.UTC
+	}
+	return time.FixedZone("", minutes*60)
+}
+
+// A time as how long ago it was, with the exact time in the viewer's zone
+// on hover and in UTC for scripts
+func timeAgo(t time.Time, zone *time.Location) template.HTML {
+	if zone == nil {
+		zone = time.UTC
+	}
+	local := t.In(zone)
+	return template.HTML(fmt.Sprintf(
+		`<time datetime="%s" title="%s">%s</time>`,
+		t.UTC().Format(time.RFC3339),
+		local.Format(time.RFC3339),
+		relativeTime(local, time.Now()),
+	))
+}
+
+// How long before now t was, in the largest whole unit: seconds under a
+// minute, minutes under an hour, hours under a day, days under a month and
+// the date in t's zone after that. Times in the future, from clock skew,
+// are "just now".
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < time.Second:
+		return "just now"
+	case d < time.Minute:
+		return ago(int(d/time.Second), "second")
+	case d < time.Hour:
+		return ago(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return ago(int(d/time.Hour), "hour")
+	case d < 30*24*time.Hour:
+		return ago(int(d/(24*time.Hour)), "day")
+	default:
+		return "on " + t.Format("2 Jan 2006")
+	}
+}
+
+// Like relativeTime, but in months and years rather than a date after the
+// first month, for things as old as videos
+func longAgo(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < 30*24*time.Hour:
+		return relativeTime(t, now)
+	case d < 365*24*time.Hour:
+		return ago(int(d/(30*24*time.Hour)), "month")
+	default:
+		return ago(int(d/(365*24*time.Hour)), "year")
+	}
+}
+
+func ago(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit + " ago"
+	}
+	return strconv.Itoa(n) + " " + unit + "s ago"
+}
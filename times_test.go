@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ago  time.Duration
+		want string
+	}{
+		{-time.Minute, "just now"},
+		{0, "just now"},
+		{time.Second, "1 second ago"},
+		{59 * time.Second, "59 seconds ago"},
+		{time.Minute, "1 minute ago"},
+		{59*time.Minute + 59*time.Second, "59 minutes ago"},
+		{time.Hour, "1 hour ago"},
+		{23 * time.Hour, "23 hours ago"},
+		{24 * time.Hour, "1 day ago"},
+		{29 * 24 * time.Hour, "29 days ago"},
+		{30 * 24 * time.Hour, "on 14 Feb 2024"},
+	}
+	for _, test := range tests {
+		if got := relativeTime(now.Add(-test.ago), now); got != test.want {
+			t.Errorf("relativeTime(%s ago) = %q, want %q", test.ago, got, test.want)
+		}
+	}
+}
+
+func TestLongAgo(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ago  time.Duration
+		want string
+	}{
+		{2 * 24 * time.Hour, "2 days ago"},
+		{30 * 24 * time.Hour, "1 month ago"},
+		{364 * 24 * time.Hour, "12 months ago"},
+		{365 * 24 * time.Hour, "1 year ago"},
+		{3 * 365 * 24 * time.Hour, "3 years ago"},
+	}
+	for _, test := range tests {
+		if got := longAgo(now.Add(-test.ago), now); got != test.want {
+			t.Errorf("longAgo(%s ago) = %q, want %q", test.ago, got, test.want)
+		}
+	}
+}
+
+func TestViewerZone(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		cookie string
+		want   int
+	}{
+		{"", 0},
+		{"330", 330 * 60},
+		{"-480", -480 * 60},
+		{"840", 840 * 60},
+		{"841", 0},
+		{"east", 0},
+	}
+	for _, test := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		if test.cookie != "" {
+			c.Request.AddCookie(&http.Cookie{Name: tzCookie, Value: test.cookie})
+		}
+		if _, offset := time.Now().In(viewerZone(c)).Zone(); offset != test.want {
+			t.Errorf("tz=%q: offset %d, want %d", test.cookie, offset, test.want)
+		}
+	}
+}
+
+func TestTimeAgo(t *testing.T) {
+	posted := time.Now().Add(-90 * time.Second).UTC().Truncate(time.Second)
+	got := string(timeAgo(posted, time.FixedZone("", 330*60)))
+	for _, want := range []string{
+		`datetime="` + posted.Format(time.RFC3339) + `"`,
+		`title="` + posted.In(time.FixedZone("", 330*60)).Format(time.RFC3339) + `"`,
+		"+05:30",
+		">1 minute ago<",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("%s doesn't contain %s", got, want)
+		}
+	}
+}
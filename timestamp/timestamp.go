@@ -0,0 +1,146 @@
+// Package timestamp finds video timestamps such as "2:35" or "1:02:10" in
+// text and turns them into seek links.
+package timestamp
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"strconv"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+var pattern = regexp.MustCompile(`(?:\d{1,2}:)?\d{1,2}:\d{2}`)
+
+// Parse "M:SS", "MM:SS" or "H:MM:SS" into seconds
+func Parse(s string) (int, bool) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+
+	values := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || part == "" {
+			return 0, false
+		}
+		values[i] = n
+	}
+
+	seconds := values[len(values)-1]
+	minutes := values[len(values)-2]
+	if len(parts[len(parts)-1]) != 2 || seconds > 59 || minutes > 59 {
+		return 0, false
+	}
+	hours := 0
+	if len(values) == 3 {
+		if len(parts[1]) != 2 {
+			return 0, false
+		}
+		hours = values[0]
+	}
+	return hours*3600 + minutes*60 + seconds, true
+}
+
+// Parse a position given either as whole seconds or as a timestamp
+func ParseOffset(s string) (int, bool) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, true
+	}
+	return Parse(s)
+}
+
+// Format seconds as "M:SS", or "H:MM:SS" from an hour on
+func Format(seconds int) string {
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// A timestamp found in text, with its byte offsets
+type Match struct {
+	Start, End int
+	Seconds    int
+}
+
+// Find standalone timestamps in text. Matches that are part of a longer run
+// of digits and colons, like "10:30:45:12" or "12:300", are skipped.
+func Find(text string) []Match {
+	var matches []Match
+	for _, loc := range pattern.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		if start > 0 && isTimeChar(text[start-1]) || end < len(text) && isTimeChar(text[end]) {
+			continue
+		}
+		if seconds, ok := Parse(text[start:end]); ok {
+			matches = append(matches, Match{Start: start, End: end, Seconds: seconds})
+		}
+	}
+	return matches
+}
+
+func isTimeChar(b byte) bool {
+	return b == ':' || b >= '0' && b <= '9'
+}
+
+// Turn timestamps in the text of an HTML fragment into links to baseURL
+// with a "t" parameter. Text inside links and code is left alone, as are
+// timestamps past maxSeconds when it is positive.
+func Link(fragment template.HTML, baseURL string, maxSeconds int) template.HTML {
+	var out bytes.Buffer
+	tokenizer := xhtml.NewTokenizer(strings.NewReader(string(fragment)))
+	skipDepth := 0
+
+	for {
+		tt := tokenizer.Next()
+		if tt == xhtml.ErrorToken {
+			break
+		}
+		token := tokenizer.Token()
+
+		switch tt {
+		case xhtml.StartTagToken:
+			if skipsTimestamps(token.Data) {
+				skipDepth++
+			}
+		case xhtml.EndTagToken:
+			if skipsTimestamps(token.Data) && skipDepth > 0 {
+				skipDepth--
+			}
+		case xhtml.TextToken:
+			if skipDepth == 0 {
+				out.WriteString(linkText(token.Data, baseURL, maxSeconds))
+				continue
+			}
+		}
+		out.WriteString(token.String())
+	}
+	return template.HTML(out.String())
+}
+
+func skipsTimestamps(tag string) bool {
+	return tag == "a" || tag == "code" || tag == "pre"
+}
+
+// Escape text, linking the timestamps in it
+func linkText(text, baseURL string, maxSeconds int) string {
+	var out strings.Builder
+	last := 0
+	for _, m := range Find(text) {
+		if maxSeconds > 0 && m.Seconds > maxSeconds {
+			continue
+		}
+		out.WriteString(html.EscapeString(text[last:m.Start]))
+		fmt.Fprintf(&out, `<a href="%s?t=%d" class="timestamp">%s</a>`,
+			html.EscapeString(baseURL), m.Seconds, html.EscapeString(text[m.Start:m.End]))
+		last = m.End
+	}
+	out.WriteString(html.EscapeString(text[last:]))
+	return out.String()
+}
@@ -0,0 +1,91 @@
+package timestamp
+
+import (
+	"html/template"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+		ok   bool
+	}{
+		{"2:35", 155, true},
+		{"02:35", 155, true},
+		{"1:02:10", 3730, true},
+		{"0:00", 0, true},
+		{"3:1", 0, false},
+		{"2:60", 0, false},
+		{"60:00", 0, false},
+		{"1:2:10", 0, false},
+		{"1:02:10:00", 0, false},
+		{"abc", 0, false},
+		{":35", 0, false},
+	}
+	for _, test := range tests {
+		got, ok := Parse(test.in)
+		if got != test.want || ok != test.ok {
+			t.Errorf("Parse(%q) = %d, %v, want %d, %v", test.in, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		text string
+		want []int
+	}{
+		{"the drop at 2:35 is great", []int{155}},
+		{"from 1:02:10 to 1:05:00", []int{3730, 3900}},
+		{"won 3:1 last night", nil},
+		{"call 10:30:45:12", nil},
+		{"ratio 12:300", nil},
+		{"at 12:30pm", []int{750}},
+		{"(4:20)", []int{260}},
+	}
+	for _, test := range tests {
+		matches := Find(test.text)
+		var got []int
+		for _, m := range matches {
+			got = append(got, m.Seconds)
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("Find(%q) = %v, want %v", test.text, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("Find(%q) = %v, want %v", test.text, got, test.want)
+			}
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := map[int]string{0: "0:00", 155: "2:35", 3730: "1:02:10"}
+	for seconds, want := range tests {
+		if got := Format(seconds); got != want {
+			t.Errorf("Format(%d) = %q, want %q", seconds, got, want)
+		}
+	}
+}
+
+func TestLink(t *testing.T) {
+	tests := []struct {
+		in         template.HTML
+		maxSeconds int
+		want       template.HTML
+	}{
+		{"<p>see 2:35</p>", 0, `<p>see <a href="/video/x?t=155" class="timestamp">2:35</a></p>`},
+		{"<p>see 2:35 &amp; 9:00</p>", 300, `<p>see <a href="/video/x?t=155" class="timestamp">2:35</a> &amp; 9:00</p>`},
+		{"<p><code>12:00</code></p>", 0, "<p><code>12:00</code></p>"},
+		{`<p><a href="https://example.com">at 1:00</a></p>`, 0, `<p><a href="https://example.com">at 1:00</a></p>`},
+		{"<p>&lt;b&gt; 1:00</p>", 0, `<p>&lt;b&gt; <a href="/video/x?t=60" class="timestamp">1:00</a></p>`},
+	}
+	for _, test := range tests {
+		if got := Link(test.in, "/video/x", test.maxSeconds); got != test.want {
+			t.Errorf("Link(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// How long a video's caption tracks and transcripts are reused before
+	// YouTube is asked again
+	transcriptTTL = 24 * time.Hour
+	// How long fetching a transcript may take before the page says it
+	// couldn't be loaded
+	transcriptTimeout = 5 * time.Second
+	// Most videos, and video and language pairs, whose caption tracks and
+	// transcripts are kept
+	transcriptCacheSize = 500
+	// Largest caption track or list read, well above any real one
+	maxTranscriptBytes = 4 << 20
+)
+
+// Where caption tracks are listed and fetched. YouTube's timedtext endpoint
+// needs no API key or quota.
+var timedTextURL = "https://www.youtube.com/api/timedtext"
+
+var timedTextClient = &http.Client{Timeout: transcriptTimeout}
+
+// A caption track uploaded for a video
+type captionTrack struct {
+	Name     string `xml:"name,attr"`
+	Language string `xml:"lang_code,attr"`
+	// The language's name, like "English"
+	Label   string `xml:"lang_translated,attr"`
+	Default bool   `xml:"lang_default,attr"`
+}
+
+// One line of a transcript
+type transcriptSegment struct {
+	Start    time.Duration
+	Duration time.Duration
+	Text     string
+}
+
+// Whole seconds into the video the line starts at, to seek the player to
+func (s transcriptSegment) Seconds() int {
+	return int(s.Start / time.Second)
+}
+
+// Caption tracks by video ID, and parsed transcripts by video ID and language
+var (
+	captionTracksCache = newLRU[[]captionTrack](transcriptCacheSize)
+	transcriptCache    = newLRU[[]transcriptSegment](transcriptCacheSize)
+)
+
+// The caption tracks uploaded for a video, none when it has no captions
+func captionTracks(ctx context.Context, videoID string) ([]captionTrack, error) {
+	if tracks, fetched, ok := captionTracksCache.Get(videoID); ok && time.Since(fetched) < transcriptTTL {
+		return tracks, nil
+	}
+	// YouTube already said whether it has any
+	if video, ok := cachedVideo(videoID); ok && !video.Captions {
+		return nil, nil
+	}
+
+	var list struct {
+		Tracks []captionTrack `xml:"track"`
+	}
+	if err := fetchTimedText(ctx, url.Values{"type": {"list"}, "v": {videoID}}, &list); err != nil {
+		return nil, fmt.Errorf("listing caption tracks: %w", err)
+	}
+	captionTracksCache.Put(videoID, list.Tracks)
+	return list.Tracks, nil
+}
+
+// The track in the language asked for, or else the video's default track or
+// its first one
+func pickCaptionTrack(tracks []captionTrack, language string) (captionTrack, bool) {
+	if len(tracks) == 0 {
+		return captionTrack{}, false
+	}
+	if i := slices.IndexFunc(tracks, func(t captionTrack) bool { return t.Language == language }); language != "" && i >= 0 {
+		return tracks[i], true
+	}
+	if i := slices.IndexFunc(tracks, func(t captionTrack) bool { return t.Default }); i >= 0 {
+		return tracks[i], true
+	}
+	return tracks[0], true
+}
+
+// The lines of a video's caption track, in order
+func fetchTranscript(ctx context.Context, videoID string, track captionTrack) ([]transcriptSegment, error) {
+	// Tracks in one language are told apart by name
+	key := videoID + " " + track.Language + " " + track.Name
+	if segments, fetched, ok := transcriptCache.Get(key); ok && time.Since(fetched) < transcriptTTL {
+		return segments, nil
+	}
+
+	var doc struct {
+		Lines []struct {
+			Start    string `xml:"start,attr"`
+			Duration string `xml:"dur,attr"`
+			Text     string `xml:",chardata"`
+		} `xml:"text"`
+	}
+	query := url.Values{"v": {videoID}, "lang": {track.Language}}
+	if track.Name != "" {
+		query.Set("name", track.Name)
+	}
+	if err := fetchTimedText(ctx, query, &doc); err != nil {
+		return nil, fmt.Errorf("fetching %s transcript: %w", track.Language, err)
+	}
+
+	segments := make([]transcriptSegment, 0, len(doc.Lines))
+	for _, line := range doc.Lines {
+		// The text is escaped HTML inside the XML, like &amp;#39;
+		text := strings.Join(strings.Fields(html.UnescapeString(line.Text)), " ")
+		if text == "" {
+			continue
+		}
+		segments = append(segments, transcriptSegment{
+			Start:    parseSeconds(line.Start),
+			Duration: parseSeconds(line.Duration),
+			Text:     text,
+		})
+	}
+	transcriptCache.Put(key, segments)
+	return segments, nil
+}
+
+// A time given in seconds like "12.34", 0 when it isn't one
+func parseSeconds(value string) time.Duration {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// Ask the timedtext endpoint and decode its XML answer into v. An empty
+// answer, which YouTube gives for videos without captions, leaves v as is.
+func fetchTimedText(ctx context.Context, query url.Values, v any) error {
+	ctx, cancel := context.WithTimeout(ctx, transcriptTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, timedTextURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := timedTextClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("timedtext: %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTranscriptBytes))
+	if err != nil {
+		return err
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return nil
+	}
+	return xml.Unmarshal(body, v)
+}
+
+// The transcript section of a video page, loaded after the page so fetching
+// it doesn't hold the page up, in the track picked by the "lang" parameter.
+// Failures are shown in the section rather than breaking the page.
+func showTranscript(c *gin.Context) {
+	videoID := c.Param("id")
+	if !videoIDPattern.MatchString(videoID) {
+		c.String(http.StatusNotFound, "There's no YouTube video with that ID.")
+		return
+	}
+	data := gin.H{"VideoID": videoID}
+	tracks, err := captionTracks(c.Request.Context(), videoID)
+	if err != nil {
+		log.Println("Error loading transcript:", err)
+		data["Failed"] = true
+		c.HTML(http.StatusOK, "transcript.html", data)
+		return
+	}
+	track, ok := pickCaptionTrack(tracks, c.Query("lang"))
+	if !ok {
+		c.HTML(http.StatusOK, "transcript.html", data)
+		return
+	}
+	segments, err := fetchTranscript(c.Request.Context(), videoID, track)
+	if err != nil {
+		log.Println("Error loading transcript:", err)
+		data["Failed"] = true
+	}
+	data["Tracks"] = tracks
+	data["Language"] = track.Language
+	data["Segments"] = segments
+	c.HTML(http.StatusOK, "transcript.html", data)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Serve timedtext answers from the handler for the test, with empty caches
+func useTimedText(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	previous := timedTextURL
+	timedTextURL = srv.URL
+	captionTracksCache.Clear()
+	transcriptCache.Clear()
+	t.Cleanup(func() {
+		srv.Close()
+		timedTextURL = previous
+		captionTracksCache.Clear()
+		transcriptCache.Clear()
+	})
+}
+
+// A timedtext stub with English and French tracks for every video, counting
+// the requests
+func captionsStub(t *testing.T) *atomic.Int32 {
+	var requests atomic.Int32
+	useTimedText(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Query().Get("type") == "list" {
+			w.Write([]byte(`<transcript_list docid="1">
+				<track id="0" name="" lang_code="fr" lang_translated="French"/>
+				<track id="1" name="" lang_code="en" lang_translated="English" lang_default="true"/>
+			</transcript_list>`))
+			return
+		}
+		w.Write([]byte(`<?xml version="1.0" encoding="utf-8" ?><transcript>
+			<text start="0.5" dur="2.1">` + r.URL.Query().Get("lang") + ` line</text>
+			<text start="62.9" dur="1">It&amp;#39;s  a
+			test</text>
+			<text start="64" dur="1"> </text>
+		</transcript>`))
+	})
+	return &requests
+}
+
+func TestTranscriptParsing(t *testing.T) {
+	captionsStub(t)
+	ctx := context.Background()
+
+	tracks, err := captionTracks(ctx, "dQw4w9WgXcQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	track, ok := pickCaptionTrack(tracks, "")
+	if !ok || track.Language != "en" {
+		t.Fatalf("picked %+v, want the default English track", track)
+	}
+	segments, err := fetchTranscript(ctx, "dQw4w9WgXcQ", track)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []transcriptSegment{
+		{Start: 500 * time.Millisecond, Duration: 2100 * time.Millisecond, Text: "en line"},
+		{Start: 62900 * time.Millisecond, Duration: time.Second, Text: "It's a test"},
+	}
+	if len(segments) != len(want) {
+		t.Fatalf("segments = %+v, want %+v", segments, want)
+	}
+	for i := range want {
+		if segments[i] != want[i] {
+			t.Errorf("segment %d = %+v, want %+v", i, segments[i], want[i])
+		}
+	}
+	if segments[1].Seconds() != 62 {
+		t.Errorf("seconds = %d, want 62", segments[1].Seconds())
+	}
+}
+
+func TestPickCaptionTrack(t *testing.T) {
+	tracks := []captionTrack{{Language: "fr"}, {Language: "en", Default: true}}
+	for _, test := range []struct{ language, want string }{
+		{"fr", "fr"},
+		{"", "en"},
+		{"de", "en"},
+	} {
+		if track, _ := pickCaptionTrack(tracks, test.language); track.Language != test.want {
+			t.Errorf("pickCaptionTrack(%q) = %q, want %q", test.language, track.Language, test.want)
+		}
+	}
+	if _, ok := pickCaptionTrack(nil, "en"); ok {
+		t.Error("picked a track from none")
+	}
+}
+
+func TestTranscriptCachedPerLanguage(t *testing.T) {
+	requests := captionsStub(t)
+	router := testRouter(http.MethodGet, "/video/:id/transcript", showTranscript)
+	get := func(path string) string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Body.String()
+	}
+
+	get("/video/dQw4w9WgXcQ/transcript")
+	get("/video/dQw4w9WgXcQ/transcript")
+	if requests.Load() != 2 {
+		t.Errorf("made %d requests, want 2 for the list and English", requests.Load())
+	}
+	body := get("/video/dQw4w9WgXcQ/transcript?lang=fr")
+	if requests.Load() != 3 {
+		t.Errorf("made %d requests, want 3 after French", requests.Load())
+	}
+	if !strings.Contains(body, "fr line") || !strings.Contains(body, `href="/video/dQw4w9WgXcQ?t=62" class="timestamp`) {
+		t.Errorf("French transcript not shown with seek links:\n%s", body)
+	}
+}
+
+func TestTranscriptCachedPerTrack(t *testing.T) {
+	useTimedText(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<transcript><text start="0" dur="1">` + r.URL.Query().Get("name") + ` line</text></transcript>`))
+	})
+	ctx := context.Background()
+
+	for _, name := range []string{"", "Director's commentary", ""} {
+		segments, err := fetchTranscript(ctx, "dQw4w9WgXcQ", captionTrack{Language: "en", Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := strings.TrimSpace(name + " line"); len(segments) != 1 || segments[0].Text != want {
+			t.Errorf("track %q: segments = %+v, want %q", name, segments, want)
+		}
+	}
+}
+
+func TestTranscriptUnavailable(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{"no captions", func(w http.ResponseWriter, r *http.Request) {}, "No transcript available"},
+		{"failure", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "down", http.StatusInternalServerError)
+		}, "transcript couldn't be loaded"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useTimedText(t, test.handler)
+			router := testRouter(http.MethodGet, "/video/:id/transcript", showTranscript)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/video/dQw4w9WgXcQ/transcript", nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("status = %d, want 200 so the page keeps working", w.Code)
+			}
+			if !strings.Contains(w.Body.String(), test.want) {
+				t.Errorf("body doesn't contain %q:\n%s", test.want, w.Body.String())
+			}
+		})
+	}
+}
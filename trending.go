@@ -0,0 +1,81 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Number of videos on each page of /trending-comments
+const trendingPageSize = 20
+
+// How far back /trending-comments counts comments
+var trendingWindow = 7 * 24 * time.Hour
+
+// A video on /trending-comments with its recent comment count
+type trendingVideo struct {
+	database.VideoCommentCount
+	Video videoInfo
+}
+
+// Show the videos with the most comments lately
+func showTrending(keys *keyring) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page := 1
+		if p := c.Query("page"); p != "" {
+			n, err := strconv.Atoi(p)
+			if err != nil || n < 1 {
+				c.String(http.StatusBadRequest, "Invalid page parameter.")
+				return
+			}
+			page = n
+		}
+
+		// Ask for one extra row to find out whether another page exists
+		ctx := c.Request.Context()
+		counts, err := store.MostDiscussedVideos(ctx, time.Now().Add(-trendingWindow), trendingPageSize+1, (page-1)*trendingPageSize)
+		if err != nil {
+			log.Println("Error loading most discussed videos:", err)
+			c.String(http.StatusInternalServerError, "Failed to load videos.")
+			return
+		}
+		hasNext := len(counts) > trendingPageSize
+		if hasNext {
+			counts = counts[:trendingPageSize]
+		}
+
+		// Videos YouTube can't tell us about are still listed by their ID
+		videoIDs := make([]string, 0, len(counts))
+		for _, count := range counts {
+			videoIDs = append(videoIDs, count.VideoID)
+		}
+		videos, err := GetVideoMeta(ctx, keys, videoIDs)
+		if err != nil {
+			log.Println("Error fetching video details:", err)
+		}
+
+		trending := make([]trendingVideo, 0, len(counts))
+		for _, count := range counts {
+			trending = append(trending, trendingVideo{VideoCommentCount: count, Video: videos[count.VideoID]})
+		}
+
+		var nextPage, prevPage int
+		if hasNext {
+			nextPage = page + 1
+		}
+		if page > 1 {
+			prevPage = page - 1
+		}
+		c.HTML(http.StatusOK, "trending.html", gin.H{
+			"Videos":   trending,
+			"Days":     int(trendingWindow.Hours() / 24),
+			"NextPage": nextPage,
+			"PrevPage": prevPage,
+		})
+	}
+}
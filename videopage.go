@@ -0,0 +1,164 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/timestamp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Links in video descriptions, which are plain text
+var descriptionLink = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// A video's metadata as the top of its page shows it
+type videoDetailsView struct {
+	Title        string
+	ChannelID    string
+	ChannelTitle string
+	Description  template.HTML
+	Published    string
+	Views        string
+	Tags         []string
+	HD           bool
+	Captions     bool
+	// Badge for a live or upcoming video, see liveBadge
+	Live string
+	// One of the youtubeComments values
+	YouTubeComments string
+}
+
+func newVideoDetailsView(videoID string, video videoInfo) videoDetailsView {
+	view := videoDetailsView{
+		Title:        video.Title,
+		ChannelID:    video.ChannelID,
+		ChannelTitle: video.ChannelTitle,
+		Description:  linkDescription(video.Description, videoID, video.Duration, video.Live == ""),
+		Published:    missingStat,
+		Views:        missingStat,
+		Tags:         video.Tags,
+		HD:           video.Definition == "hd",
+		Captions:     video.Captions,
+
+		YouTubeComments: youtubeCommentsStatus(video),
+		Live:            liveBadge(video),
+	}
+	if !video.PublishedAt.IsZero() {
+		view.Published = video.PublishedAt.Format("2 Jan 2006")
+	}
+	if video.HasViews {
+		view.Views = compactCount(video.Views)
+	}
+	return view
+}
+
+// Escape a description, linking the URLs in it and, unless the video is live
+// or yet to start, turning its timestamps into links that seek the player
+func linkDescription(text, videoID string, duration time.Duration, timestamps bool) template.HTML {
+	var out strings.Builder
+	last := 0
+	for _, loc := range descriptionLink.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		// Punctuation ending a sentence isn't part of the link
+		for end > start && strings.ContainsRune(".,;:!?)'", rune(text[end-1])) {
+			end--
+		}
+		link := html.EscapeString(text[start:end])
+		out.WriteString(html.EscapeString(text[last:start]))
+		fmt.Fprintf(&out, `<a href="%s" rel="nofollow ugc noopener" target="_blank" class="text-blue-600 hover:underline">%s</a>`, link, link)
+		last = end
+	}
+	out.WriteString(html.EscapeString(text[last:]))
+	if !timestamps {
+		return template.HTML(out.String())
+	}
+	return timestamp.Link(template.HTML(out.String()), "/video/"+url.PathEscape(videoID), int(duration/time.Second))
+}
+
+// A video's metadata for other sites embedding its comments
+type videoJSON struct {
+	ID              string `json:"id"`
+	Title           string `json:"title"`
+	ChannelID       string `json:"channel_id,omitempty"`
+	ChannelTitle    string `json:"channel_title,omitempty"`
+	DurationSeconds int    `json:"duration_seconds"`
+	Thumbnail       string `json:"thumbnail,omitempty"`
+	ThumbnailWidth  int64  `json:"thumbnail_width,omitempty"`
+	ThumbnailHeight int64  `json:"thumbnail_height,omitempty"`
+	// Comments left here
+	Comments int `json:"comments"`
+	// Whether YouTube takes comments on it, "enabled", "disabled" or
+	// "unknown"
+	YouTubeComments  string `json:"youtube_comments"`
+	CommentsDisabled bool   `json:"comments_disabled"`
+	// "live", "upcoming" or "none", with when an upcoming video starts
+	LiveBroadcast  string     `json:"live_broadcast"`
+	ScheduledStart *time.Time `json:"scheduled_start,omitempty"`
+	URL            string     `json:"url"`
+}
+
+// A video's metadata as JSON, from the cache where it's there. Browsers and
+// proxies may keep it for what's left of its videoCacheTTL.
+func videoMetadataJSON(keys *keyring) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		videoID := c.Param("id")
+		if !videoIDPattern.MatchString(videoID) {
+			c.String(http.StatusNotFound, "Video not found.")
+			return
+		}
+		ctx := c.Request.Context()
+		video, err := lookupVideo(ctx, keys, videoID)
+		if errors.Is(err, errVideoNotFound) {
+			c.String(http.StatusNotFound, "Video not found.")
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching video details:", err)
+			c.String(http.StatusBadGateway, "Failed to look up the video.")
+			return
+		}
+		counts, err := store.CountCommentsByVideoIDs(ctx, []string{videoID})
+		if err != nil {
+			log.Println("Error counting comments:", err)
+			c.String(http.StatusInternalServerError, "Failed to count comments.")
+			return
+		}
+
+		maxAge := time.Duration(0)
+		if fetched, ok := videoFetchedAt(videoID); ok {
+			maxAge = max(videoCacheTTL-time.Since(fetched), 0)
+		}
+		// Like the comment count, fetched from other sites
+		c.Header("Access-Control-Allow-Origin", "*")
+		c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge/time.Second)))
+		out := videoJSON{
+			ID:               videoID,
+			Title:            video.Title,
+			ChannelID:        video.ChannelID,
+			ChannelTitle:     video.ChannelTitle,
+			DurationSeconds:  int(video.Duration / time.Second),
+			Thumbnail:        video.Thumbnail,
+			ThumbnailWidth:   video.ThumbnailWidth,
+			ThumbnailHeight:  video.ThumbnailHeight,
+			Comments:         counts[videoID],
+			YouTubeComments:  youtubeCommentsStatus(video),
+			CommentsDisabled: video.YouTubeComments == youtubeCommentsDisabled,
+			LiveBroadcast:    cmp.Or(video.Live, "none"),
+			URL:              absoluteURL(c, "/video/"+videoID),
+		}
+		if !video.ScheduledStart.IsZero() {
+			out.ScheduledStart = &video.ScheduledStart
+		}
+		c.JSON(http.StatusOK, out)
+	}
+}
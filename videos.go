@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// Most video IDs YouTube accepts in one Videos.List call
+const maxVideosPerRequest = 50
+
+var errVideoNotFound = errors.New("video not found")
+
+// What we keep about a YouTube video
+type videoInfo struct {
+	Title string
+	// Medium sized thumbnail where YouTube has one, with its size in pixels
+	// so pages can reserve the space before it loads. Width and height are 0
+	// when unknown.
+	Thumbnail       string
+	ThumbnailWidth  int64
+	ThumbnailHeight int64
+	// Keywords the uploader gave, used to find related videos
+	Tags []string
+	// Used to decide which timestamps in comments can be linked
+	Duration time.Duration
+
+	Description  string
+	ChannelID    string
+	ChannelTitle string
+	PublishedAt  time.Time
+	// Views when the video was looked up, unknown when HasViews is false
+	Views    uint64
+	HasViews bool
+	// "hd" or "sd"
+	Definition string
+	Captions   bool
+	// One of the youtubeComments values, or empty when not yet checked, see
+	// checkYouTubeComments
+	YouTubeComments string
+	// "live" while streaming and "upcoming" before a stream or premiere
+	// starts, when ScheduledStart says when. Empty for other videos.
+	Live           string
+	ScheduledStart time.Time
+}
+
+// Longest a Short can be
+const maxShortLength = time.Minute
+
+// Whether a video is a YouTube Short, going by its length or a #shorts tag
+// in its title or description. Streams yet to start have no length.
+func isShort(video videoInfo) bool {
+	if video.Duration > 0 && video.Duration <= maxShortLength {
+		return true
+	}
+	return strings.Contains(strings.ToLower(video.Title+" "+video.Description), "#shorts")
+}
+
+// Whether the video is streaming or yet to start, as far as the cache knows
+func videoIsLive(videoID string) bool {
+	video, ok := cachedVideo(videoID)
+	return ok && video.Live != ""
+}
+
+// Parts of a video Videos.List asks for, all for the same quota
+var videoParts = []string{"snippet", "contentDetails", "statistics", "liveStreamingDetails"}
+
+// Whether the video is streaming or yet to start, as videoInfo.Live, and
+// when it's due to
+func liveBroadcast(item *youtube.Video) (string, time.Time) {
+	live := item.Snippet.LiveBroadcastContent
+	if live != "live" && live != "upcoming" {
+		return "", time.Time{}
+	}
+	var scheduled time.Time
+	if details := item.LiveStreamingDetails; details != nil {
+		scheduled, _ = time.Parse(time.RFC3339, details.ScheduledStartTime)
+	}
+	return live, scheduled
+}
+
+// What result lists show in place of the length of a live or upcoming
+// video, like "LIVE" or "PREMIERE 3 Nov 2026 18:00 UTC". Premieres have a
+// length, streams yet to start don't.
+func liveBadge(video videoInfo) string {
+	switch video.Live {
+	case "live":
+		return "LIVE"
+	case "upcoming":
+		label := "UPCOMING"
+		if video.Duration > 0 {
+			label = "PREMIERE"
+		}
+		if video.ScheduledStart.IsZero() {
+			return label
+		}
+		return label + " " + video.ScheduledStart.UTC().Format("2 Jan 2006 15:04 UTC")
+	}
+	return ""
+}
+
+// How long video details are reused before YouTube is asked again, set by
+// VIDEO_CACHE_TTL
+var videoCacheTTL = 24 * time.Hour
+
+// Most videos kept in memory. The database keeps the rest.
+const videoCacheSize = 10000
+
+type cachedVideoEntry struct {
+	video   videoInfo
+	fetched time.Time
+}
+
+// Videos seen in search results or looked up lately, by ID. Those looked up
+// are also kept in the database, see GetVideoMeta.
+var videoCache = newLRU[cachedVideoEntry](videoCacheSize)
+
+// What we know about a video, however old
+func cachedVideo(videoID string) (videoInfo, bool) {
+	entry, _, ok := videoCache.Get(videoID)
+	return entry.video, ok
+}
+
+// When the video's details in memory were fetched from YouTube
+func videoFetchedAt(videoID string) (time.Time, bool) {
+	entry, _, ok := videoCache.Get(videoID)
+	return entry.fetched, ok
+}
+
+// Details of a video fetched less than videoCacheTTL ago, from memory
+func freshVideo(videoID string) (videoInfo, bool) {
+	entry, _, ok := videoCache.Get(videoID)
+	if !ok || time.Since(entry.fetched) >= videoCacheTTL {
+		return videoInfo{}, false
+	}
+	return entry.video, true
+}
+
+func cacheVideo(item *youtube.Video) videoInfo {
+	video := videoInfo{
+		Title:    item.Snippet.Title,
+		Duration: parseDuration(item.ContentDetails.Duration),
+		Tags:     item.Snippet.Tags,
+
+		Description:  item.Snippet.Description,
+		ChannelID:    item.Snippet.ChannelId,
+		ChannelTitle: item.Snippet.ChannelTitle,
+		Definition:   item.ContentDetails.Definition,
+		Captions:     item.ContentDetails.Caption == "true",
+	}
+	video.PublishedAt, _ = time.Parse(time.RFC3339, item.Snippet.PublishedAt)
+	video.Live, video.ScheduledStart = liveBroadcast(item)
+	if item.Statistics != nil {
+		video.Views, video.HasViews = item.Statistics.ViewCount, true
+		// No count can mean none yet or comments turned off
+		if item.Statistics.CommentCount > 0 {
+			video.YouTubeComments = youtubeCommentsEnabled
+		}
+	}
+	if thumbnail := pickThumbnail(item.Snippet.Thumbnails); thumbnail != nil {
+		video.Thumbnail, video.ThumbnailWidth, video.ThumbnailHeight = thumbnail.Url, thumbnail.Width, thumbnail.Height
+	}
+	videoCache.Put(item.Id, cachedVideoEntry{video: video, fetched: time.Now()})
+	return video
+}
+
+// The medium thumbnail, or the nearest size YouTube has, or nil when there
+// are none
+func pickThumbnail(thumbnails *youtube.ThumbnailDetails) *youtube.Thumbnail {
+	if thumbnails == nil {
+		return nil
+	}
+	for _, thumbnail := range []*youtube.Thumbnail{thumbnails.Medium, thumbnails.High, thumbnails.Default, thumbnails.Standard, thumbnails.Maxres} {
+		if thumbnail != nil && thumbnail.Url != "" {
+			return thumbnail
+		}
+	}
+	return nil
+}
+
+// Look up videos by ID, asking YouTube only about the ones not cached in
+// memory or in the database within videoCacheTTL, in as few calls as
+// possible. When YouTube can't be reached, older details are used for the
+// videos that have them. Videos YouTube doesn't know are left out.
+//
+// Every consumer of video details goes through here, or lookupVideo for one.
+func GetVideoMeta(ctx context.Context, keys *keyring, videoIDs []string) (map[string]videoInfo, error) {
+	videos := make(map[string]videoInfo, len(videoIDs))
+	var missing []string
+	for _, id := range videoIDs {
+		if _, seen := videos[id]; seen {
+			continue
+		}
+		if video, ok := freshVideo(id); ok {
+			videos[id] = video
+		} else if !slices.Contains(missing, id) {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return videos, nil
+	}
+
+	// The database outlives restarts and is shared between instances
+	cached, err := store.GetCachedVideos(ctx, missing)
+	if err != nil {
+		log.Println("Error loading cached videos:", err)
+	}
+	stale := make(map[string]videoInfo)
+	var fetch []string
+	for _, id := range missing {
+		var video videoInfo
+		if entry, ok := cached[id]; ok && json.Unmarshal(entry.Data, &video) == nil {
+			videoCache.Put(id, cachedVideoEntry{video: video, fetched: entry.FetchedAt})
+			if time.Since(entry.FetchedAt) < videoCacheTTL {
+				videos[id] = video
+				continue
+			}
+			stale[id] = video
+		}
+		fetch = append(fetch, id)
+	}
+	if len(fetch) == 0 {
+		return videos, nil
+	}
+
+	fetched, err := fetchVideos(ctx, keys, fetch)
+	for id, video := range fetched {
+		videos[id] = video
+	}
+	saveVideos(ctx, fetched)
+	if err != nil {
+		// Old details beat none
+		var unknown bool
+		for _, id := range fetch {
+			if _, ok := videos[id]; ok {
+				continue
+			}
+			if video, ok := stale[id]; ok {
+				videos[id] = video
+			} else {
+				unknown = true
+			}
+		}
+		if unknown {
+			return videos, err
+		}
+		log.Println("Error refreshing video details, using older ones:", err)
+	}
+	return videos, nil
+}
+
+// Ask YouTube about the videos, in batches of maxVideosPerRequest, within
+// detailsTimeout. Returns those fetched before any error.
+func fetchVideos(ctx context.Context, keys *keyring, videoIDs []string) (map[string]videoInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, detailsTimeout)
+	defer cancel()
+
+	videos := make(map[string]videoInfo, len(videoIDs))
+	for start := 0; start < len(videoIDs); start += maxVideosPerRequest {
+		batch := videoIDs[start:min(start+maxVideosPerRequest, len(videoIDs))]
+		var response *youtube.VideoListResponse
+		err := withYouTube(ctx, keys, func(service *youtube.Service) (err error) {
+			response, err = callYouTube(ctx, "videos.list", service.Videos.List(videoParts).Id(batch...).Context(ctx).Do)
+			return err
+		})
+		if err != nil {
+			return videos, err
+		}
+		for _, item := range response.Items {
+			videos[item.Id] = cacheVideo(item)
+		}
+	}
+	return videos, nil
+}
+
+// Keep freshly fetched details in the database. They are still in memory,
+// so a failure is only logged.
+func saveVideos(ctx context.Context, videos map[string]videoInfo) {
+	data := make(map[string][]byte, len(videos))
+	for id, video := range videos {
+		encoded, err := json.Marshal(video)
+		if err != nil {
+			log.Println("Error encoding video details:", err)
+			continue
+		}
+		data[id] = encoded
+	}
+	if err := store.SaveCachedVideos(ctx, data); err != nil {
+		log.Println("Error caching video details:", err)
+	}
+}
+
+// Look up one video, asking YouTube only the first time
+func lookupVideo(ctx context.Context, keys *keyring, videoID string) (videoInfo, error) {
+	videos, err := GetVideoMeta(ctx, keys, []string{videoID})
+	if err != nil {
+		return videoInfo{}, err
+	}
+	video, ok := videos[videoID]
+	if !ok {
+		return videoInfo{}, fmt.Errorf("video %s: %w", videoID, errVideoNotFound)
+	}
+	return video, nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+)
+
+// An HTTP transport answering with the function
+type fakeTransport func(*http.Request) (*http.Response, error)
+
+func (f fakeTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// A JSON response with the status
+func jsonResponse(status int, body any) *http.Response {
+	encoded, _ := json.Marshal(body)
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(string(encoded))),
+	}
+}
+
+// Use a fresh in-memory database as the store for the test, and start it
+// with empty caches
+func useTestStore(t *testing.T) {
+	t.Helper()
+	db, err := database.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := store
+	store = db
+	videoCache.Clear()
+	flushSearchPages()
+	t.Cleanup(func() {
+		db.Close()
+		store = previous
+		videoCache.Clear()
+		flushSearchPages()
+	})
+}
+
+// A keyring whose requests are answered by the transport
+func testKeyring(t *testing.T, transport fakeTransport) *keyring {
+	t.Helper()
+	keys, err := newKeyring(context.Background(), transport, "test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+// A YouTube stub answering Videos.List with a video for every ID asked about,
+// counting the calls
+func countingVideos(t *testing.T) (*keyring, *atomic.Int32) {
+	var calls atomic.Int32
+	keys := testKeyring(t, func(r *http.Request) (*http.Response, error) {
+		if !strings.HasSuffix(r.URL.Path, "/videos") {
+			return jsonResponse(http.StatusNotFound, map[string]any{}), nil
+		}
+		calls.Add(1)
+		var items []map[string]any
+		for _, id := range strings.Split(strings.Join(r.URL.Query()["id"], ","), ",") {
+			items = append(items, map[string]any{
+				"id":             id,
+				"snippet":        map[string]any{"title": "Video " + id},
+				"contentDetails": map[string]any{"duration": "PT2M"},
+			})
+		}
+		return jsonResponse(http.StatusOK, map[string]any{"items": items}), nil
+	})
+	return keys, &calls
+}
+
+func TestGetVideoMetaCacheMiss(t *testing.T) {
+	useTestStore(t)
+	keys, calls := countingVideos(t)
+
+	videos, err := GetVideoMeta(context.Background(), keys, []string{"aaaaaaaaaaa", "bbbbbbbbbbb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("made %d calls, want 1", calls.Load())
+	}
+	if got := videos["bbbbbbbbbbb"].Title; got != "Video bbbbbbbbbbb" {
+		t.Errorf("title = %q", got)
+	}
+	if videos["aaaaaaaaaaa"].Duration != 2*time.Minute {
+		t.Errorf("duration = %s", videos["aaaaaaaaaaa"].Duration)
+	}
+	cached, err := store.GetCachedVideos(context.Background(), []string{"aaaaaaaaaaa", "bbbbbbbbbbb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cached) != 2 {
+		t.Errorf("saved %d videos to the database, want 2", len(cached))
+	}
+}
+
+func TestGetVideoMetaCacheHit(t *testing.T) {
+	useTestStore(t)
+	keys, calls := countingVideos(t)
+	ctx := context.Background()
+
+	if _, err := GetVideoMeta(ctx, keys, []string{"aaaaaaaaaaa"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetVideoMeta(ctx, keys, []string{"aaaaaaaaaaa", "aaaaaaaaaaa"}); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("made %d calls, want 1 for the memory hit", calls.Load())
+	}
+
+	// After a restart the database still has it
+	videoCache.Clear()
+	videos, err := GetVideoMeta(ctx, keys, []string{"aaaaaaaaaaa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("made %d calls, want 1 for the database hit", calls.Load())
+	}
+	if videos["aaaaaaaaaaa"].Title != "Video aaaaaaaaaaa" {
+		t.Errorf("title = %q", videos["aaaaaaaaaaa"].Title)
+	}
+}
+
+func TestGetVideoMetaBatches(t *testing.T) {
+	useTestStore(t)
+	keys, calls := countingVideos(t)
+
+	var ids []string
+	for i := range maxVideosPerRequest + 1 {
+		ids = append(ids, fmt.Sprintf("video%06d", i))
+	}
+	videos, err := GetVideoMeta(context.Background(), keys, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(videos) != len(ids) {
+		t.Errorf("got %d videos, want %d", len(videos), len(ids))
+	}
+	if calls.Load() != 2 {
+		t.Errorf("made %d calls, want 2", calls.Load())
+	}
+}
+
+func TestGetVideoMetaUsesStaleOnError(t *testing.T) {
+	useTestStore(t)
+	keys := testKeyring(t, func(*http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusBadRequest, map[string]any{"error": map[string]any{"code": 400}}), nil
+	})
+	if err := store.SaveCachedVideos(context.Background(), map[string][]byte{"aaaaaaaaaaa": []byte(`{"Title":"Old"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	// Everything cached is out of date
+	ttl := videoCacheTTL
+	videoCacheTTL = 0
+	t.Cleanup(func() { videoCacheTTL = ttl })
+
+	video, err := lookupVideo(context.Background(), keys, "aaaaaaaaaaa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if video.Title != "Old" {
+		t.Errorf("title = %q, want the stale one", video.Title)
+	}
+	if _, err := lookupVideo(context.Background(), keys, "bbbbbbbbbbb"); err == nil {
+		t.Error("unknown video looked up without error")
+	}
+}
+
+func TestVideoCacheBounded(t *testing.T) {
+	cache := newLRU[cachedVideoEntry](2)
+	for _, id := range []string{"a", "b", "c"} {
+		cache.Put(id, cachedVideoEntry{})
+	}
+	if cache.Len() != 2 {
+		t.Errorf("len = %d, want 2", cache.Len())
+	}
+	if _, _, ok := cache.Get("a"); ok {
+		t.Error("least recently used entry kept")
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+	"github.com/TanishkBansode/right-to-comment/identity"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Record the caller's up- or downvote on a comment and return the new score
+func voteComment(value int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.String(http.StatusNotFound, "Comment not found.")
+			return
+		}
+
+		token := identity.FromContext(c)
+
+		ctx := c.Request.Context()
+		err = store.Vote(ctx, id, token, value)
+		if errors.Is(err, database.ErrNotFound) {
+			c.String(http.StatusNotFound, "Comment not found.")
+			return
+		}
+		if err != nil {
+			log.Println("Error recording vote:", err)
+			c.String(http.StatusInternalServerError, "Failed to record vote.")
+			return
+		}
+
+		view, err := loadCommentView(c, id)
+		if err != nil {
+			log.Println("Error loading comment:", err)
+			c.String(http.StatusInternalServerError, "Failed to load comment.")
+			return
+		}
+		publishChange(eventScoreChanged, view.Comment)
+
+		if c.GetHeader("HX-Request") == "" {
+			c.Redirect(http.StatusSeeOther, "/video/"+view.VideoID)
+			return
+		}
+		c.HTML(http.StatusOK, "votes", view)
+	}
+}
@@ -0,0 +1,147 @@
+// Package webhook delivers JSON events to configured URLs in the background,
+// retrying failed deliveries a few times.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Header carrying the hex HMAC-SHA256 of the body, prefixed with "sha256="
+const SignatureHeader = "X-Signature-256"
+
+const (
+	// Deliveries waiting for a worker, beyond which new events are dropped
+	queueSize = 100
+	workers   = 4
+	timeout   = 10 * time.Second
+	// Attempts per URL, the first one included
+	maxAttempts = 4
+	// Wait before the first retry, doubled for each one after it
+	firstBackoff = time.Second
+)
+
+type delivery struct {
+	url  string
+	body []byte
+}
+
+// Dispatcher posts events to every URL it was created with. Sending never
+// blocks, and when the queue is full the event is dropped.
+type Dispatcher struct {
+	urls   []string
+	secret []byte
+	client *http.Client
+	// Counts of attempts, deliveries, failures and drops
+	Stats *expvar.Map
+
+	queue chan delivery
+	wg    sync.WaitGroup
+}
+
+// Create a dispatcher and start its workers. Payloads are signed with the
+// secret when it isn't empty.
+func New(urls []string, secret string) *Dispatcher {
+	d := &Dispatcher{
+		urls:   urls,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: timeout},
+		Stats:  new(expvar.Map).Init(),
+		queue:  make(chan delivery, queueSize),
+	}
+	for range workers {
+		d.wg.Add(1)
+		go d.work()
+	}
+	return d
+}
+
+// Queue an event for delivery to every URL
+func (d *Dispatcher) Send(event any) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Println("Error encoding webhook payload:", err)
+		return
+	}
+	for _, url := range d.urls {
+		select {
+		case d.queue <- delivery{url: url, body: body}:
+		default:
+			d.Stats.Add("dropped", 1)
+			log.Println("Webhook queue full, dropping event for", url)
+		}
+	}
+}
+
+// Stop accepting events and wait for queued ones to be delivered
+func (d *Dispatcher) Close() {
+	close(d.queue)
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for job := range d.queue {
+		d.deliver(job)
+	}
+}
+
+// Post the body, backing off between attempts
+func (d *Dispatcher) deliver(job delivery) {
+	backoff := firstBackoff
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		d.Stats.Add("attempts", 1)
+		err := d.post(job)
+		if err == nil {
+			d.Stats.Add("delivered", 1)
+			return
+		}
+		log.Printf("Webhook delivery to %s failed (attempt %d of %d): %v", job.url, attempt, maxAttempts, err)
+		if attempt < maxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	d.Stats.Add("failures", 1)
+}
+
+func (d *Dispatcher) post(job delivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.url, bytes.NewReader(job.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(d.secret) > 0 {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(d.secret, job.body))
+	}
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return nil
+}
+
+// Hex HMAC-SHA256 of the body, as sent in SignatureHeader
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
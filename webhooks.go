@@ -0,0 +1,63 @@
+package main
+
+import (
+	"expvar"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TanishkBansode/right-to-comment/database"
+	"github.com/TanishkBansode/right-to-comment/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Set when WEBHOOK_URLS lists somewhere to announce new comments
+var webhooks *webhook.Dispatcher
+
+// Payload posted to webhooks for each new comment
+type commentEvent struct {
+	VideoID   string    `json:"video_id"`
+	CommentID int64     `json:"comment_id"`
+	Author    string    `json:"author"`
+	Text      string    `json:"text"`
+	Permalink string    `json:"permalink"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Start the webhook dispatcher when URLs are configured
+func loadWebhooks() {
+	var urls []string
+	for _, url := range strings.Split(os.Getenv("WEBHOOK_URLS"), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	if len(urls) == 0 {
+		return
+	}
+
+	secret := os.Getenv("WEBHOOK_SECRET")
+	if secret == "" {
+		log.Println("WEBHOOK_SECRET not set, webhook payloads will not be signed")
+	}
+	webhooks = webhook.New(urls, secret)
+	expvar.Publish("webhook_deliveries", webhooks.Stats)
+}
+
+// Announce a comment that was just posted
+func notifyWebhooks(c *gin.Context, id int64, comment database.NewComment) {
+	// Shadowbanned comments stay invisible to everyone else
+	if webhooks == nil || comment.VisibleToAuthorOnly {
+		return
+	}
+	webhooks.Send(commentEvent{
+		VideoID:   comment.VideoID,
+		CommentID: id,
+		Author:    comment.Author,
+		Text:      comment.Text,
+		Permalink: absoluteURL(c, permalinkPath(comment.VideoID, id)),
+		CreatedAt: time.Now().UTC(),
+	})
+}
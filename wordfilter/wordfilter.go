@@ -0,0 +1,149 @@
+// Package wordfilter blocks or masks a configurable list of words in text.
+package wordfilter
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/TanishkBansode/right-to-comment/textnorm"
+)
+
+var ErrBlocked = errors.New("text contains a blocked word")
+
+type Mode int
+
+const (
+	// Reject text containing a listed word
+	Block Mode = iota
+	// Replace listed words with asterisks
+	Mask
+)
+
+// Parse a mode name, defaulting to Block
+func ParseMode(name string) Mode {
+	if strings.EqualFold(name, "mask") {
+		return Mask
+	}
+	return Block
+}
+
+// Filter matches whole words case-insensitively, so "ass" does not match
+// "class". Text is matched in its textnorm.Fold form, so decorating a word
+// with accents or invisible characters doesn't get it past the filter. It is safe for concurrent use and can be reloaded while in use.
+type Filter struct {
+	mode   Mode
+	path   string
+	inline string
+
+	mu      sync.RWMutex
+	pattern *regexp.Regexp
+}
+
+// Create a filter from a word list file and/or an inline list, either of
+// which may be empty. Lists are comma or newline separated.
+func New(mode Mode, path, inline string) (*Filter, error) {
+	f := &Filter{mode: mode, path: path, inline: inline}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Re-read the word list file, keeping the old list if that fails
+func (f *Filter) Reload() error {
+	words := Parse(f.inline)
+	if f.path != "" {
+		data, err := os.ReadFile(f.path)
+		if err != nil {
+			return err
+		}
+		words = append(words, Parse(string(data))...)
+	}
+
+	var pattern *regexp.Regexp
+	if len(words) > 0 {
+		quoted := make([]string, len(words))
+		for i, word := range words {
+			quoted[i] = regexp.QuoteMeta(textnorm.Fold(word))
+		}
+		pattern = regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)
+	}
+
+	f.mu.Lock()
+	f.pattern = pattern
+	f.mu.Unlock()
+	return nil
+}
+
+// Split a comma or newline separated list, skipping blanks and # comments
+func Parse(list string) []string {
+	var words []string
+	for _, line := range strings.Split(list, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for _, word := range strings.Split(line, ",") {
+			if word = strings.TrimSpace(word); word != "" {
+				words = append(words, word)
+			}
+		}
+	}
+	return words
+}
+
+// Report whether the text contains a listed word
+func (f *Filter) Match(text string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.pattern != nil && f.pattern.MatchString(textnorm.Fold(text))
+}
+
+// Replace each listed word, along with any marks on its letters, with as many
+// asterisks as it has letters
+func (f *Filter) Mask(text string) string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.pattern == nil {
+		return text
+	}
+
+	// Match the folded text, remembering where each of its bytes came from so
+	// the original span can be masked
+	var folded strings.Builder
+	offsets := make([]int, 0, len(text)+1)
+	for i, r := range text {
+		part := textnorm.Fold(string(r))
+		for range len(part) {
+			offsets = append(offsets, i)
+		}
+		folded.WriteString(part)
+	}
+	offsets = append(offsets, len(text))
+
+	matched := folded.String()
+	var b strings.Builder
+	last := 0
+	for _, m := range f.pattern.FindAllStringIndex(matched, -1) {
+		b.WriteString(text[last:offsets[m[0]]])
+		b.WriteString(strings.Repeat("*", utf8.RuneCountInString(matched[m[0]:m[1]])))
+		last = offsets[m[1]]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// Filter text according to the mode: masked text in Mask mode, or ErrBlocked
+// in Block mode when a listed word is found
+func (f *Filter) Apply(text string) (string, error) {
+	if f.mode == Mask {
+		return f.Mask(text), nil
+	}
+	if f.Match(text) {
+		return "", ErrBlocked
+	}
+	return text, nil
+}
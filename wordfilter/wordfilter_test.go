@@ -0,0 +1,127 @@
+package wordfilter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	got := Parse("darn, heck\n# a comment\n\n  drat  \nfoo,,bar")
+	want := []string{"darn", "heck", "drat", "foo", "bar"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Parse = %q, want %q", got, want)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	f, err := New(Block, "", "ass,heck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"what the heck", true},
+		{"What the HECK!", true},
+		{"heck, no", true},
+		{"don't be an ass.", true},
+		{"a class act", false},
+		{"passing the assessment", false},
+		{"hecked", false},
+		{"what the h\u200beck", true},
+		{"what the he\u0301ck", true},
+		{"", false},
+	}
+	for _, test := range tests {
+		if got := f.Match(test.text); got != test.want {
+			t.Errorf("Match(%q) = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestApplyBlock(t *testing.T) {
+	f, err := New(Block, "", "heck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Apply("Oh heck"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("err = %v, want ErrBlocked", err)
+	}
+	if text, err := f.Apply("Oh hello"); err != nil || text != "Oh hello" {
+		t.Errorf("Apply = %q, %v, want the text unchanged", text, err)
+	}
+}
+
+func TestApplyMask(t *testing.T) {
+	f, err := New(Mask, "", "heck,darn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"Oh HECK, darn it all": "Oh ****, **** it all",
+		"a class act":          "a class act",
+		"heckle":               "heckle",
+	}
+	for text, want := range tests {
+		got, err := f.Apply(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Apply(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestEmptyFilter(t *testing.T) {
+	f, err := New(Block, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, err := f.Apply("anything at all"); err != nil || text != "anything at all" {
+		t.Errorf("Apply = %q, %v", text, err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte("heck\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := New(Block, path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Match("heck") {
+		t.Fatal("word from the file not matched")
+	}
+
+	if err := os.WriteFile(path, []byte("drat\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if f.Match("heck") || !f.Match("drat") {
+		t.Error("reloaded list not used")
+	}
+
+	// A failed reload keeps the list
+	os.Remove(path)
+	if err := f.Reload(); err == nil {
+		t.Error("reload of a missing file succeeded")
+	}
+	if !f.Match("drat") {
+		t.Error("list lost after a failed reload")
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	if ParseMode("MASK") != Mask || ParseMode("block") != Block || ParseMode("") != Block {
+		t.Error("modes parsed wrongly")
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/api/googleapi"
+)
+
+// Why a YouTube API call failed, as far as the visitor needs to know
+var (
+	errYouTubeQuota       = errors.New("YouTube API quota exceeded")
+	errYouTubeConfig      = errors.New("YouTube API key rejected")
+	errYouTubeUnreachable = errors.New("YouTube API unreachable")
+	errYouTubePageToken   = errors.New("YouTube API rejected the page token")
+	errYouTubeTimeout     = errors.New("YouTube API took too long")
+)
+
+// How long a search, and a lookup of video, channel or playlist details, may
+// take before giving up, set by SEARCH_TIMEOUT and DETAILS_TIMEOUT. Each
+// covers every call made for it, retries included.
+var (
+	searchTimeout  = 5 * time.Second
+	detailsTimeout = 3 * time.Second
+)
+
+// One option of a search form select, with the first of each list below the
+// default
+type searchChoice struct{ Value, Label string }
+
+// What a search looks for
+var searchTypes = []searchChoice{
+	{"videos", "Videos"},
+	{"channels", "Channels"},
+	{"playlists", "Playlists"},
+}
+
+// Orders search results can be sorted in, by their YouTube API names
+var searchOrders = []searchChoice{
+	{"relevance", "Relevance"},
+	{"date", "Upload date"},
+	{"viewCount", "View count"},
+	{"rating", "Rating"},
+}
+
+// Video lengths to search for, by their YouTube API names
+var searchDurations = []searchChoice{
+	{"any", "Any length"},
+	{"short", "Under 4 minutes"},
+	{"medium", "4 to 20 minutes"},
+	{"long", "Over 20 minutes"},
+}
+
+// How recently the videos searched for were uploaded, see uploadWindows
+var searchUploads = []searchChoice{
+	{"any", "Any time"},
+	{"hour", "Last hour"},
+	{"today", "Today"},
+	{"week", "This week"},
+	{"month", "This month"},
+	{"year", "This year"},
+}
+
+// How far back each of searchUploads reaches
+var uploadWindows = map[string]time.Duration{
+	"hour":  time.Hour,
+	"today": 24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+	"year":  365 * 24 * time.Hour,
+}
+
+// SafeSearch levels, least filtered first
+var safeSearchModes = []searchChoice{
+	{"none", "Off"},
+	{"moderate", "Moderate"},
+	{"strict", "Strict"},
+}
+
+// The least filtering any search gets, set by SAFE_SEARCH. Searches can ask
+// for stricter filtering but never for less.
+var safeSearch = "moderate"
+
+// How strict a SafeSearch level is, -1 when it isn't one
+func safeSearchRank(mode string) int {
+	return slices.IndexFunc(safeSearchModes, func(c searchChoice) bool { return c.Value == mode })
+}
+
+// The requested SafeSearch level when it's at least as strict as the
+// configured one, otherwise the configured one
+func stricterSafeSearch(requested string) string {
+	if safeSearchRank(requested) > safeSearchRank(safeSearch) {
+		return requested
+	}
+	return safeSearch
+}
+
+// Region and language searches are made for unless they ask for others, set
+// by SEARCH_REGION and SEARCH_LANGUAGE. Empty leaves it to YouTube.
+var searchRegion, searchLanguage string
+
+// An ISO 3166-1 alpha-2 country code, upper-cased first
+var regionPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// A language tag like en, pt-BR or zh-Hant, near enough BCP 47 for YouTube
+var languagePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(?:-[a-zA-Z0-9]{2,8})*$`)
+
+// Read the default search region and language and the SafeSearch level,
+// ignoring invalid ones
+func loadSearchDefaults() {
+	searchRegion = validRegion(os.Getenv("SEARCH_REGION"), "")
+	searchLanguage = validLanguage(os.Getenv("SEARCH_LANGUAGE"), "")
+	if mode := os.Getenv("SAFE_SEARCH"); mode != "" {
+		if safeSearchRank(mode) < 0 {
+			log.Printf("Invalid SAFE_SEARCH %q, using %q", mode, safeSearch)
+		} else {
+			safeSearch = mode
+		}
+	}
+}
+
+// The region code given, or the fallback when it's empty or not a region code
+func validRegion(region, fallback string) string {
+	region = strings.ToUpper(strings.TrimSpace(region))
+	if region == "" {
+		return fallback
+	}
+	if !regionPattern.MatchString(region) {
+		log.Printf("Invalid search region %q, using %q", region, fallback)
+		return fallback
+	}
+	return region
+}
+
+// The language tag given, or the fallback when it's empty or not a language
+// tag
+func validLanguage(lang, fallback string) string {
+	lang = strings.TrimSpace(lang)
+	if lang == "" {
+		return fallback
+	}
+	if !languagePattern.MatchString(lang) {
+		log.Printf("Invalid search language %q, using %q", lang, fallback)
+		return fallback
+	}
+	return lang
+}
+
+// What a search form asked for, each choice one of the lists above
+type searchOptions struct {
+	Query    string
+	Type     string
+	Order    string
+	Duration string
+	Uploaded string
+	// Country the results are for and the language they should be relevant
+	// to, empty for YouTube's defaults
+	Region   string
+	Language string
+	// SafeSearch level, never less strict than safeSearch
+	SafeSearch string
+	// Leave Shorts out of video results, see isShort
+	NoShorts bool
+}
+
+// Video results asked for instead of 10 when Shorts are left out, since some
+// of them will be
+const shortsOverFetch = 25
+
+// Read the search options from a form or query string, through get. Choices
+// that aren't in their list, and invalid regions and languages, fall back to
+// the default, e.g. from an old or hand-edited link, and SafeSearch can only
+// be made stricter.
+func parseSearchOptions(get func(string) string) searchOptions {
+	return searchOptions{
+		Query:      get("query"),
+		Type:       pickChoice(searchTypes, "type", get("type")),
+		Order:      pickChoice(searchOrders, "order", get("order")),
+		Duration:   pickChoice(searchDurations, "duration", get("duration")),
+		Uploaded:   pickChoice(searchUploads, "uploaded", get("uploaded")),
+		Region:     validRegion(get("region"), searchRegion),
+		Language:   validLanguage(get("lang"), searchLanguage),
+		SafeSearch: stricterSafeSearch(get("safe")),
+		NoShorts:   get("noshorts") != "",
+	}
+}
+
+func pickChoice(choices []searchChoice, name, value string) string {
+	for _, choice := range choices {
+		if choice.Value == value {
+			return value
+		}
+	}
+	if value != "" {
+		log.Printf("Invalid search %s %q, using %q", name, value, choices[0].Value)
+	}
+	return choices[0].Value
+}
+
+// Query string for the search, on the page the token points to
+func (o searchOptions) values(pageToken string, page int) url.Values {
+	values := url.Values{
+		"query":    {o.Query},
+		"type":     {o.Type},
+		"order":    {o.Order},
+		"duration": {o.Duration},
+		"uploaded": {o.Uploaded},
+	}
+	if o.Region != "" {
+		values.Set("region", o.Region)
+	}
+	if o.Language != "" {
+		values.Set("lang", o.Language)
+	}
+	if o.SafeSearch != safeSearch {
+		values.Set("safe", o.SafeSearch)
+	}
+	if o.NoShorts {
+		values.Set("noshorts", "1")
+	}
+	if pageToken != "" {
+		values.Set("pageToken", pageToken)
+		values.Set("page", strconv.Itoa(page))
+	}
+	return values
+}
+
+// Shown in place of statistics YouTube didn't give
+const missingStat = "—"
+
+// A count shortened the way YouTube shows them, e.g. 999, 1.2K, 34K or 5M.
+// Digits past the first decimal are dropped rather than rounded, so a count
+// never shows as more than it is.
+func compactCount(n uint64) string {
+	if n < 1000 {
+		return strconv.FormatUint(n, 10)
+	}
+	units := []string{"K", "M", "B", "T"}
+	v := float64(n)
+	for i, unit := range units {
+		v /= 1000
+		if v >= 1000 && i < len(units)-1 {
+			continue
+		}
+		if v < 10 {
+			return strconv.FormatFloat(math.Floor(v*10)/10, 'f', -1, 64) + unit
+		}
+		return strconv.FormatFloat(math.Floor(v), 'f', 0, 64) + unit
+	}
+	return strconv.FormatUint(n, 10)
+}
+
+// Log a failed YouTube API call and show the visitor what went wrong, as far
+// as they need to know
+func renderYouTubeError(c *gin.Context, what string, err error) {
+	switch {
+	case errors.Is(err, errYouTubeQuota):
+		log.Println(what+":", err)
+		renderError(c, http.StatusServiceUnavailable, "Search is unavailable right now, please try again later.")
+	case errors.Is(err, errYouTubeTimeout):
+		log.Println(what+":", err)
+		renderError(c, http.StatusGatewayTimeout, "YouTube took too long to answer, please try again.")
+	case errors.Is(err, errYouTubeConfig):
+		log.Println(what+", check YOUTUBE_API_KEY:", err)
+		renderError(c, http.StatusInternalServerError, "Search isn't set up correctly on this server.")
+	default:
+		log.Println(what+":", err)
+		renderError(c, http.StatusBadGateway, "Couldn't reach YouTube, please try again.")
+	}
+}
+
+// Wrap an error from the YouTube API in the matching error above, keeping
+// the original for the logs
+func classifyYouTubeError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", errYouTubeTimeout, err)
+	}
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return fmt.Errorf("%w: %w", errYouTubeUnreachable, err)
+	}
+
+	reasons := make([]string, 0, len(apiErr.Errors))
+	for _, item := range apiErr.Errors {
+		reasons = append(reasons, item.Reason)
+	}
+	hasReason := func(want ...string) bool {
+		return slices.ContainsFunc(reasons, func(r string) bool { return slices.Contains(want, r) })
+	}
+	switch {
+	case hasReason("invalidPageToken"):
+		return fmt.Errorf("%w: %w", errYouTubePageToken, err)
+	case apiErr.Code == http.StatusTooManyRequests,
+		hasReason("quotaExceeded", "dailyLimitExceeded", "rateLimitExceeded", "userRateLimitExceeded"):
+		return fmt.Errorf("%w: %w", errYouTubeQuota, err)
+	case hasReason("keyInvalid", "keyExpired", "accessNotConfigured", "ipRefererBlocked", "forbidden"),
+		apiErr.Code == http.StatusBadRequest && strings.Contains(apiErr.Message, "API key"):
+		return fmt.Errorf("%w: %w", errYouTubeConfig, err)
+	case apiErr.Code >= http.StatusInternalServerError:
+		return fmt.Errorf("%w: %w", errYouTubeUnreachable, err)
+	}
+	return err
+}
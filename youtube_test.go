@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompactCount(t *testing.T) {
+	tests := []struct {
+		n    uint64
+		want string
+	}{
+		{0, "0"},
+		{999, "999"},
+		{1000, "1K"},
+		{1250, "1.2K"},
+		{1299, "1.2K"},
+		{9999, "9.9K"},
+		{34_000, "34K"},
+		{999_999, "999K"},
+		{1_000_000, "1M"},
+		{1_290_000, "1.2M"},
+		{5_000_000_000, "5B"},
+		{2_500_000_000_000_000, "2500T"},
+	}
+	for _, test := range tests {
+		if got := compactCount(test.n); got != test.want {
+			t.Errorf("compactCount(%d) = %q, want %q", test.n, got, test.want)
+		}
+	}
+}
+
+func TestVideoDetailsStatistics(t *testing.T) {
+	useTestStore(t)
+	published := time.Now().AddDate(-3, 0, -10).UTC().Format(time.RFC3339)
+	var parts string
+	keys := testKeyring(t, func(r *http.Request) (*http.Response, error) {
+		parts = strings.Join(r.URL.Query()["part"], ",")
+		return jsonResponse(http.StatusOK, map[string]any{"items": []map[string]any{
+			{
+				"id":             "dQw4w9WgXcQ",
+				"snippet":        map[string]any{"title": "Popular", "publishedAt": published},
+				"contentDetails": map[string]any{"duration": "PT3M33S"},
+				"statistics":     map[string]any{"viewCount": "1600000000", "likeCount": "18000000"},
+			},
+			{
+				// Likes hidden and no statistics or date given at all
+				"id":             "9bZkp7q19f0",
+				"snippet":        map[string]any{"title": "Private stats"},
+				"contentDetails": map[string]any{"duration": "PT4M13S"},
+			},
+		}}), nil
+	})
+	_, service, _ := keys.Service()
+
+	videos, err := videoDetails(context.Background(), service, []string{"dQw4w9WgXcQ", "9bZkp7q19f0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(parts, "statistics") {
+		t.Errorf("parts requested = %q, want statistics among them", parts)
+	}
+	if len(videos) != 2 {
+		t.Fatalf("videos = %+v", videos)
+	}
+	if v := videos[0]; v.Views != "1.6B" || v.Likes != "18M" || v.Published != "3 years ago" {
+		t.Errorf("stats = %s views, %s likes, published %s", v.Views, v.Likes, v.Published)
+	}
+	if v := videos[1]; v.Views != missingStat || v.Likes != missingStat || v.Published != missingStat {
+		t.Errorf("missing stats shown as %q, %q, %q", v.Views, v.Likes, v.Published)
+	}
+}
+
+func TestSafeSearchOnlyStricter(t *testing.T) {
+	tests := []struct {
+		baseline, requested, want string
+	}{
+		{"moderate", "", "moderate"},
+		{"moderate", "strict", "strict"},
+		{"moderate", "none", "moderate"},
+		{"moderate", "bogus", "moderate"},
+		{"strict", "none", "strict"},
+		{"strict", "moderate", "strict"},
+		{"none", "", "none"},
+		{"none", "moderate", "moderate"},
+	}
+	previous := safeSearch
+	t.Cleanup(func() { safeSearch = previous })
+	for _, test := range tests {
+		t.Run(test.baseline+"/"+test.requested, func(t *testing.T) {
+			useTestStore(t)
+			safeSearch = test.baseline
+			var sent string
+			stub := searchStub("dQw4w9WgXcQ")
+			keys := testKeyring(t, func(r *http.Request) (*http.Response, error) {
+				if strings.HasSuffix(r.URL.Path, "/search") {
+					sent = r.URL.Query().Get("safeSearch")
+				}
+				return stub(r)
+			})
+			router := testRouter(http.MethodGet, "/search", handleSearch(keys))
+			w := serve(router, requestAs(http.MethodGet, "/search?query=cats&safe="+test.requested, ""))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d", w.Code)
+			}
+			if sent != test.want {
+				t.Errorf("sent safeSearch=%q, want %q", sent, test.want)
+			}
+			// The page shows the mode in use and offers nothing looser than
+			// the baseline
+			label := safeSearchModes[safeSearchRank(test.want)].Label
+			if !strings.Contains(w.Body.String(), `selected>SafeSearch: `+label) {
+				t.Errorf("page doesn't show SafeSearch: %s as selected", label)
+			}
+			for _, mode := range safeSearchModes[:safeSearchRank(test.baseline)] {
+				if strings.Contains(w.Body.String(), "SafeSearch: "+mode.Label+"<") {
+					t.Errorf("page offers SafeSearch: %s below the %s baseline", mode.Label, test.baseline)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/youtube/v3"
+)
+
+// Whether a video takes comments on YouTube, as videoInfo.YouTubeComments
+// keeps it. Empty means it hasn't been checked.
+const (
+	youtubeCommentsEnabled  = "enabled"
+	youtubeCommentsDisabled = "disabled"
+	youtubeCommentsUnknown  = "unknown"
+)
+
+// Whether YouTube takes comments on the video, unknown when not checked
+func youtubeCommentsStatus(video videoInfo) string {
+	if video.YouTubeComments == "" {
+		return youtubeCommentsUnknown
+	}
+	return video.YouTubeComments
+}
+
+// Whether video pages check if YouTube takes comments on the video when its
+// statistics don't already show it does, at 1 unit of quota a video a day,
+// set by YOUTUBE_COMMENT_CHECK
+var youtubeCommentCheck = true
+
+// The video with whether YouTube takes comments on it, asking YouTube when
+// that isn't known yet. What YouTube says is cached with the video's other
+// details.
+func checkYouTubeComments(ctx context.Context, keys *keyring, videoID string, video videoInfo) videoInfo {
+	if video.YouTubeComments != "" || !youtubeCommentCheck || quotaLow(ctx, keys) {
+		return video
+	}
+	status := fetchYouTubeComments(ctx, keys, videoID)
+	if status == "" {
+		return video
+	}
+	video.YouTubeComments = status
+	fetched, ok := videoFetchedAt(videoID)
+	if !ok {
+		fetched = time.Now()
+	}
+	videoCache.Put(videoID, cachedVideoEntry{video: video, fetched: fetched})
+	saveVideos(ctx, map[string]videoInfo{videoID: video})
+	return video
+}
+
+// Ask YouTube for one comment thread on the video, which it refuses when
+// comments are turned off. Empty when YouTube couldn't be asked, so it is
+// asked again next time.
+func fetchYouTubeComments(ctx context.Context, keys *keyring, videoID string) string {
+	ctx, cancel := context.WithTimeout(ctx, detailsTimeout)
+	defer cancel()
+
+	err := withYouTube(ctx, keys, func(service *youtube.Service) error {
+		_, err := callYouTube(ctx, "commentThreads.list", service.CommentThreads.List([]string{"id"}).VideoId(videoID).MaxResults(1).Context(ctx).Do)
+		return err
+	})
+	var apiErr *googleapi.Error
+	switch {
+	case err == nil:
+		return youtubeCommentsEnabled
+	case !errors.As(err, &apiErr) || apiErr.Code >= http.StatusInternalServerError || apiErr.Code == http.StatusTooManyRequests:
+		log.Println("Error checking YouTube comments:", err)
+		return ""
+	case slices.ContainsFunc(apiErr.Errors, func(item googleapi.ErrorItem) bool { return item.Reason == "commentsDisabled" }):
+		return youtubeCommentsDisabled
+	}
+	// Refused for some other reason, like the video being private
+	return youtubeCommentsUnknown
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Hosts serving YouTube's own pages, besides youtu.be
+var youtubeHosts = []string{"youtube.com", "www.youtube.com", "m.youtube.com", "music.youtube.com", "youtube-nocookie.com", "www.youtube-nocookie.com"}
+
+// Paths whose next segment is the video ID, like /shorts/ID
+var videoPathPrefixes = []string{"/shorts/", "/live/", "/embed/", "/v/"}
+
+// A start time in a link, as seconds or like 1h2m3s
+var linkTimePattern = regexp.MustCompile(`^(?:(\d+)h)?(?:(\d+)m)?(?:(\d+)s?)?$`)
+
+// The video a search box query points to, when it is a link to one on
+// YouTube or a bare video ID, with the start time in seconds the link gives,
+// or 0. A bare ID has to mix upper and lower case with a digit, - or _, so
+// that 11 letter words are still searched for.
+func parseVideoLink(query string) (string, int, bool) {
+	query = strings.TrimSpace(query)
+	if videoIDPattern.MatchString(query) && looksLikeVideoID(query) {
+		return query, 0, true
+	}
+
+	if !strings.Contains(query, "://") {
+		query = "https://" + query
+	}
+	link, err := url.Parse(query)
+	if err != nil || link.Scheme != "http" && link.Scheme != "https" {
+		return "", 0, false
+	}
+	host := strings.ToLower(link.Hostname())
+
+	var videoID string
+	switch {
+	case host == "youtu.be" || host == "www.youtu.be":
+		videoID = firstSegment(link.Path, "/")
+	case containsFold(youtubeHosts, host):
+		if link.Path == "/watch" || link.Path == "/watch/" {
+			videoID = link.Query().Get("v")
+			break
+		}
+		for _, prefix := range videoPathPrefixes {
+			if strings.HasPrefix(link.Path, prefix) {
+				videoID = firstSegment(link.Path, prefix)
+				break
+			}
+		}
+	}
+	if !videoIDPattern.MatchString(videoID) {
+		return "", 0, false
+	}
+
+	// t in the query string or the fragment, or start on embed links
+	start := linkTime(link.Query().Get("t"))
+	if start == 0 {
+		start = linkTime(link.Query().Get("start"))
+	}
+	if fragment, err := url.ParseQuery(link.Fragment); start == 0 && err == nil {
+		start = linkTime(fragment.Get("t"))
+	}
+	return videoID, start, true
+}
+
+// Whether an 11 character string is more likely a video ID than a word
+func looksLikeVideoID(s string) bool {
+	upper := strings.ContainsFunc(s, func(r rune) bool { return r >= 'A' && r <= 'Z' })
+	lower := strings.ContainsFunc(s, func(r rune) bool { return r >= 'a' && r <= 'z' })
+	other := strings.ContainsAny(s, "0123456789-_")
+	return upper && lower && other
+}
+
+// The path segment after the prefix, without any that follow
+func firstSegment(path, prefix string) string {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, prefix), "/")
+	return segment
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Seconds from a link's start time like "42", "42s" or "1h2m3s", 0 when it
+// isn't one
+func linkTime(value string) int {
+	match := linkTimePattern.FindStringSubmatch(value)
+	if value == "" || match == nil {
+		return 0
+	}
+	seconds := 0
+	for i, unit := range []int{3600, 60, 1} {
+		if n, err := strconv.Atoi(match[i+1]); err == nil {
+			seconds += n * unit
+		}
+	}
+	return seconds
+}
+
+// Send a search for a YouTube link or video ID straight to the video's page,
+// reporting whether it was one
+func redirectVideoLink(c *gin.Context, query string) bool {
+	videoID, start, ok := parseVideoLink(query)
+	if !ok {
+		return false
+	}
+	target := "/video/" + videoID
+	if start > 0 {
+		target += "?t=" + strconv.Itoa(start)
+	}
+	c.Redirect(http.StatusSeeOther, target)
+	return true
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestParseVideoLink(t *testing.T) {
+	tests := []struct {
+		query   string
+		videoID string
+		start   int
+		ok      bool
+	}{
+		// Bare video IDs
+		{"dQw4w9WgXcQ", "dQw4w9WgXcQ", 0, true},
+		{"  dQw4w9WgXcQ\n", "dQw4w9WgXcQ", 0, true},
+		{"9bZkp7q19f0", "9bZkp7q19f0", 0, true},
+		{"a-B_cDeFgHi", "a-B_cDeFgHi", 0, true},
+
+		// youtu.be
+		{"https://youtu.be/dQw4w9WgXcQ", "dQw4w9WgXcQ", 0, true},
+		{"https://youtu.be/dQw4w9WgXcQ?t=42", "dQw4w9WgXcQ", 42, true},
+		{"https://youtu.be/dQw4w9WgXcQ?si=abc123&t=42s", "dQw4w9WgXcQ", 42, true},
+		{"http://www.youtu.be/dQw4w9WgXcQ/", "dQw4w9WgXcQ", 0, true},
+		{"youtu.be/dQw4w9WgXcQ", "dQw4w9WgXcQ", 0, true},
+
+		// /watch
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", "dQw4w9WgXcQ", 0, true},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&list=PL123&index=2", "dQw4w9WgXcQ", 0, true},
+		{"https://www.youtube.com/watch?feature=share&v=dQw4w9WgXcQ&t=1m30s", "dQw4w9WgXcQ", 90, true},
+		{"https://youtube.com/watch/?v=dQw4w9WgXcQ", "dQw4w9WgXcQ", 0, true},
+		{"https://m.youtube.com/watch?v=dQw4w9WgXcQ&t=1h2m3s", "dQw4w9WgXcQ", 3723, true},
+		{"https://music.youtube.com/watch?v=dQw4w9WgXcQ", "dQw4w9WgXcQ", 0, true},
+		{"HTTPS://WWW.YouTube.com/watch?v=dQw4w9WgXcQ", "dQw4w9WgXcQ", 0, true},
+		{"www.youtube.com/watch?v=dQw4w9WgXcQ", "dQw4w9WgXcQ", 0, true},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ#t=2m", "dQw4w9WgXcQ", 120, true},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=soon", "dQw4w9WgXcQ", 0, true},
+
+		// Other paths with the ID in them
+		{"https://www.youtube.com/shorts/dQw4w9WgXcQ", "dQw4w9WgXcQ", 0, true},
+		{"https://youtube.com/shorts/dQw4w9WgXcQ?feature=share", "dQw4w9WgXcQ", 0, true},
+		{"https://www.youtube.com/live/dQw4w9WgXcQ?si=abc&t=300", "dQw4w9WgXcQ", 300, true},
+		{"https://www.youtube.com/embed/dQw4w9WgXcQ?start=30", "dQw4w9WgXcQ", 30, true},
+		{"https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ", "dQw4w9WgXcQ", 0, true},
+		{"https://www.youtube.com/v/dQw4w9WgXcQ", "dQw4w9WgXcQ", 0, true},
+
+		// Searched for as they are
+		{"", "", 0, false},
+		{"cats", "", 0, false},
+		{"rick astley never gonna give you up", "", 0, false},
+		{"programming", "", 0, false},
+		{"Programming", "", 0, false},
+		{"PROGRAMMING", "", 0, false},
+		{"dQw4w9WgXc", "", 0, false},
+		{"dQw4w9WgXcQQ", "", 0, false},
+		{"https://vimeo.com/76979871", "", 0, false},
+		{"https://example.com/watch?v=dQw4w9WgXcQ", "", 0, false},
+		{"https://youtube.com.example.com/watch?v=dQw4w9WgXcQ", "", 0, false},
+		{"https://notyoutube.com/watch?v=dQw4w9WgXcQ", "", 0, false},
+		{"ftp://youtu.be/dQw4w9WgXcQ", "", 0, false},
+		{"https://www.youtube.com/watch", "", 0, false},
+		{"https://www.youtube.com/watch?v=tooshort", "", 0, false},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ%3Cscript", "", 0, false},
+		{"https://www.youtube.com/@RickAstleyYT", "", 0, false},
+		{"https://www.youtube.com/channel/UCuAXFkgsw1L7xaCfnd5JJOw", "", 0, false},
+		{"https://www.youtube.com/playlist?list=PL123", "", 0, false},
+		{"https://youtu.be/", "", 0, false},
+		{"https://www.youtube.com/shorts/", "", 0, false},
+	}
+	for _, test := range tests {
+		videoID, start, ok := parseVideoLink(test.query)
+		if videoID != test.videoID || start != test.start || ok != test.ok {
+			t.Errorf("parseVideoLink(%q) = %q, %d, %v, want %q, %d, %v", test.query, videoID, start, ok, test.videoID, test.start, test.ok)
+		}
+	}
+}
+
+func TestLinkTime(t *testing.T) {
+	tests := map[string]int{
+		"":        0,
+		"42":      42,
+		"42s":     42,
+		"2m":      120,
+		"1m30s":   90,
+		"1h":      3600,
+		"1h2m3s":  3723,
+		"1h5s":    3605,
+		"soon":    0,
+		"-5":      0,
+		"1m30x":   0,
+		"1.5":     0,
+		"0":       0,
+		"1h2m3s4": 0,
+	}
+	for value, want := range tests {
+		if got := linkTime(value); got != want {
+			t.Errorf("linkTime(%q) = %d, want %d", value, got, want)
+		}
+	}
+}
+
+func TestSearchRedirectsVideoLinks(t *testing.T) {
+	useTestStore(t)
+	search := testRouter(http.MethodGet, "/search", handleSearch(testKeyring(t, searchStub("9bZkp7q19f0"))))
+	tests := []struct {
+		query    string
+		location string
+	}{
+		{"https://youtu.be/dQw4w9WgXcQ?t=42", "/video/dQw4w9WgXcQ?t=42"},
+		{"https://m.youtube.com/watch?v=dQw4w9WgXcQ&list=PL123", "/video/dQw4w9WgXcQ"},
+		{"dQw4w9WgXcQ", "/video/dQw4w9WgXcQ"},
+	}
+	for _, test := range tests {
+		w := serve(search, requestAs(http.MethodGet, "/search?query="+url.QueryEscape(test.query), ""))
+		if w.Code != http.StatusSeeOther || w.Header().Get("Location") != test.location {
+			t.Errorf("search for %q: status = %d, Location = %q, want 303 to %q", test.query, w.Code, w.Header().Get("Location"), test.location)
+		}
+	}
+
+	// Anything else is still searched for
+	w := serve(search, requestAs(http.MethodGet, "/search?query="+url.QueryEscape("https://vimeo.com/76979871"), ""))
+	if w.Code != http.StatusOK {
+		t.Errorf("search for another site's link: status = %d, want 200", w.Code)
+	}
+
+	// The form posting to /search goes straight there too
+	post := testRouter(http.MethodPost, "/search", redirectSearch)
+	w = serve(post, formAs("/search", "", url.Values{"query": {"https://www.youtube.com/shorts/dQw4w9WgXcQ"}}))
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/video/dQw4w9WgXcQ" {
+		t.Errorf("posted link: status = %d, Location = %q", w.Code, w.Header().Get("Location"))
+	}
+	w = serve(post, formAs("/search", "", url.Values{"query": {"cats"}}))
+	if location := w.Header().Get("Location"); w.Code != http.StatusSeeOther || location != "/search?"+parseSearchOptions(url.Values{"query": {"cats"}}.Get).values("", 0).Encode() {
+		t.Errorf("posted search: status = %d, Location = %q", w.Code, location)
+	}
+}